@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+var assetsBuildCmd = &cobra.Command{
+	Use:   "assets:build",
+	Short: "Fingerprint static assets",
+	Long: `Copy every file in internal/assets/static to internal/assets/dist under a name
+carrying its content hash, add gzip variants of text files and write the
+manifest that assets.Path reads. Run it before building for production; the
+output is embedded in the binary.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runProjectCLI("assets", "build"); err != nil {
+			log.Fatalf("Failed to build assets: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(assetsBuildCmd)
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"log"
+	"os"
+	"os/exec"
+
+	"github.com/spf13/cobra"
+)
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the application database",
+	Long:  `Commands that work with the data in the application database.`,
+}
+
+var dbSeedCmd = &cobra.Command{
+	Use:   "seed [name]",
+	Short: "Seed the database",
+	Long: `Run all seeders, or the named seeder and its dependencies, in dependency order
+inside a single transaction.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Println("Seeding database...")
+		if err := runSeeders(databaseName, args...); err != nil {
+			log.Fatalf("Seeding failed: %v", err)
+		}
+	},
+}
+
+// runSeeders runs the project's seeders for a database through its cmd/cli entrypoint
+func runSeeders(database string, names ...string) error {
+	args := []string{"seed"}
+	if database != "" {
+		args = append(args, "--database", database)
+	}
+	return runProjectCLI(append(args, names...)...)
+}
+
+// runProjectCLI runs the generated project's cmd/cli entrypoint, which has
+// access to the project's own packages
+func runProjectCLI(args ...string) error {
+	cliCmd := exec.Command("go", append([]string{"run", "./cmd/cli"}, args...)...)
+	cliCmd.Stdout = os.Stdout
+	cliCmd.Stderr = os.Stderr
+	cliCmd.Stdin = os.Stdin
+	return cliCmd.Run()
+}
+
+func init() {
+	rootCmd.AddCommand(dbCmd)
+	dbCmd.AddCommand(dbSeedCmd)
+
+	// Add flags
+	dbCmd.PersistentFlags().StringVar(&databaseName, "database", "", "Named database to use (default: primary)")
+}
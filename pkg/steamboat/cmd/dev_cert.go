@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/zulubit/steamboat/pkg/steamboat/generator"
+)
+
+var (
+	certDir   string
+	certHosts []string
+	certForce bool
+)
+
+var devCertCmd = &cobra.Command{
+	Use:   "dev:cert",
+	Short: "Generate a certificate for local HTTPS",
+	Long: `Generate a certificate and key for local development, valid for localhost
+and the loopback addresses by default. Point TLS_CERT_FILE and TLS_KEY_FILE at
+them, e.g. in .env.local, to serve the app over HTTPS.
+
+The certificate is signed by a local CA in dev-ca.crt, created on first use and
+reused after. Trust it once in your browser or system store and certificates
+generated later are trusted too. An existing dev.key is only replaced with --force.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(certHosts) == 0 {
+			log.Fatal("At least one --host is required")
+		}
+
+		cert, err := generator.GenerateDevCert(certDir, certHosts, certForce)
+		if err != nil {
+			log.Fatalf("Failed to generate certificate: %v", err)
+		}
+
+		fmt.Printf("✓ Development certificate created for %v\n", certHosts)
+		fmt.Printf("  Created: %s\n", cert.CertPath)
+		fmt.Printf("  Created: %s\n", cert.KeyPath)
+		if cert.CACreated {
+			fmt.Printf("  Created: %s\n", cert.CAPath)
+			fmt.Printf("\n⚠ Trust %s in your browser or system store to avoid certificate warnings\n", cert.CAPath)
+		}
+		fmt.Println("\nAdd to .env.local:")
+		fmt.Printf("  TLS_CERT_FILE=%s\n", cert.CertPath)
+		fmt.Printf("  TLS_KEY_FILE=%s\n", cert.KeyPath)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(devCertCmd)
+
+	// Add flags
+	devCertCmd.Flags().StringVar(&certDir, "dir", "certs", "Directory to write dev.crt and dev.key to")
+	devCertCmd.Flags().StringSliceVar(&certHosts, "host", []string{"localhost", "127.0.0.1", "::1"}, "Host names and IP addresses the certificate is valid for")
+	devCertCmd.Flags().BoolVar(&certForce, "force", false, "Replace an existing dev.crt and dev.key")
+}
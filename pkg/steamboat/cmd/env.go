@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/zulubit/steamboat/pkg/steamboat/env"
+)
+
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Inspect the environment configuration",
+	Long:  `Inspect the configuration loaded from .env files and the shell.`,
+}
+
+var envShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the effective configuration",
+	Long: `Show every variable loaded for the selected environment and where its value
+came from. Values of secrets such as SESSION_KEY are masked.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Printf("Environment: %s\n", environment.Name)
+		if len(environment.Files) > 0 {
+			fmt.Printf("Loaded:      %s\n", strings.Join(environment.Files, ", "))
+		}
+		if len(environment.Missing) > 0 {
+			fmt.Printf("Not found:   %s\n", strings.Join(environment.Missing, ", "))
+		}
+		fmt.Println()
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VARIABLE\tVALUE\tSOURCE")
+		for _, v := range environment.Vars {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", v.Key, env.Mask(v.Key, v.Value), v.Source)
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(envCmd)
+	envCmd.AddCommand(envShowCmd)
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	listStatus  string
+	purgeStatus string
+)
+
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "Manage background jobs",
+	Long:  `Commands that inspect and clean up the jobs table of the application database.`,
+}
+
+var jobsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List background jobs",
+	Long:  `List the jobs in the queue, newest first, optionally only those with the given status (pending, running, done or dead).`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runProjectCLI("jobs", "list", "--status", listStatus); err != nil {
+			log.Fatalf("Failed to list jobs: %v", err)
+		}
+	},
+}
+
+var jobsRetryCmd = &cobra.Command{
+	Use:   "retry [id...]",
+	Short: "Retry dead jobs",
+	Long:  `Queue the given dead jobs, or every dead job, again with a fresh set of attempts.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runProjectCLI(append([]string{"jobs", "retry"}, args...)...); err != nil {
+			log.Fatalf("Failed to retry jobs: %v", err)
+		}
+	},
+}
+
+var jobsPurgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Delete finished jobs",
+	Long:  `Delete the jobs with the given statuses, done by default. Use --status done,dead to also drop dead jobs.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runProjectCLI("jobs", "purge", "--status", purgeStatus); err != nil {
+			log.Fatalf("Failed to purge jobs: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(jobsCmd)
+	jobsCmd.AddCommand(jobsListCmd)
+	jobsCmd.AddCommand(jobsRetryCmd)
+	jobsCmd.AddCommand(jobsPurgeCmd)
+
+	// Add flags
+	jobsListCmd.Flags().StringVar(&listStatus, "status", "", "Only list jobs with this status")
+	jobsPurgeCmd.Flags().StringVar(&purgeStatus, "status", "done", "Comma-separated statuses to purge (done, dead)")
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/zulubit/steamboat/pkg/steamboat/generator"
+)
+
+var makeJobCmd = &cobra.Command{
+	Use:   "job [name]",
+	Short: "Generate a new background job",
+	Long:  `Generate a background job in internal/jobs with its payload type and a handler registered with the job queue.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		jobName := args[0]
+
+		log.Printf("Creating job: %s", jobName)
+
+		path, err := generator.GenerateJob(jobName)
+		if err != nil {
+			log.Fatalf("Failed to generate job: %v", err)
+		}
+
+		fmt.Printf("✓ Job '%s' created successfully\n", jobName)
+		fmt.Printf("  Created: %s\n", path)
+	},
+}
+
+func init() {
+	makeCmd.AddCommand(makeJobCmd)
+}
@@ -3,22 +3,61 @@ package cmd
 import (
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/zulubit/steamboat/pkg/steamboat/generator"
+	"github.com/zulubit/steamboat/pkg/steamboat/migrate"
+)
+
+var (
+	timestampVersion bool
+	autoMigration    bool
+	dropUnknown      bool
+	rebuildTable     string
 )
 
 var makeMigrationCmd = &cobra.Command{
 	Use:   "migration [name]",
 	Short: "Generate a new migration",
-	Long:  `Generate a new migration file with up and down functions.`,
+	Long: `Generate a new migration file with up and down functions.
+
+With --auto the SQL is generated by comparing the models in
+internal/database/models against the current database schema. Changes that
+SQLite cannot make with ALTER TABLE are written as a table rebuild. Tables
+without a model are left alone unless --drop-unknown is given; virtual tables
+such as FTS indexes and their shadow tables are never touched.
+
+With --rebuild TABLE the migration contains SQLite's table rebuild sequence
+for TABLE with its current definition, ready to be edited.`,
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		migrationName := args[0]
+		if autoMigration && rebuildTable != "" {
+			log.Fatalf("--auto and --rebuild cannot be combined")
+		}
+		if dropUnknown && !autoMigration {
+			log.Fatalf("--drop-unknown only applies to --auto")
+		}
 		
 		log.Printf("Creating migration: %s", migrationName)
 		
-		filename, err := generator.GenerateMigration(migrationName)
+		// The URL may be unset when generating, only the directory matters here
+		target, _ := migrate.LookupDatabase(databaseName)
+
+		var filename string
+		var err error
+		if rebuildTable != "" {
+			filename, err = generateRebuildMigration(migrationName, target.MigrationsPath)
+		} else if autoMigration {
+			filename, err = generateAutoMigration(migrationName, target.MigrationsPath)
+			if filename == "" && err == nil {
+				fmt.Printf("✓ Models match the database schema, no migration needed\n")
+				return
+			}
+		} else {
+			filename, err = generator.GenerateMigration(migrationName, target.MigrationsPath, timestampVersion)
+		}
 		if err != nil {
 			log.Fatalf("Failed to generate migration: %v", err)
 		}
@@ -28,6 +67,65 @@ var makeMigrationCmd = &cobra.Command{
 	},
 }
 
+// generateAutoMigration writes the migration that brings the schema in line
+// with the models, or returns "" when there is nothing to change
+func generateAutoMigration(name, migrationsDir string) (string, error) {
+	auto, err := migrate.Autogenerate(databaseName, dropUnknown)
+	if err != nil {
+		return "", err
+	}
+	if len(auto.Unknown) > 0 {
+		fmt.Printf("⚠ Tables without a model were left alone: %s (drop them with --drop-unknown)\n", strings.Join(auto.Unknown, ", "))
+	}
+	if len(auto.Changes) == 0 {
+		return "", nil
+	}
+
+	fmt.Printf("Detected changes:\n")
+	for _, change := range auto.Changes {
+		marker := "+"
+		switch {
+		case change.Destructive:
+			marker = "!"
+		case change.Rebuild:
+			marker = "~"
+		}
+		fmt.Printf("  %s %s\n", marker, change.Description)
+	}
+	for _, change := range auto.Changes {
+		if change.Rebuild {
+			fmt.Printf("\n⚠ Some changes need a table rebuild (~), review the generated SQL before migrating\n")
+			break
+		}
+	}
+	for _, change := range auto.Changes {
+		if change.Destructive {
+			fmt.Printf("⚠ Some changes drop data (!), review the generated SQL before migrating\n")
+			break
+		}
+	}
+	fmt.Println()
+
+	return generator.GenerateMigrationSQL(name, migrationsDir, timestampVersion, auto.Up, auto.Down)
+}
+
+// generateRebuildMigration writes a migration that rebuilds rebuildTable with its current definition
+func generateRebuildMigration(name, migrationsDir string) (string, error) {
+	up, down, err := migrate.RebuildSQL(databaseName, rebuildTable)
+	if err != nil {
+		return "", err
+	}
+
+	return generator.GenerateMigrationSQL(name, migrationsDir, timestampVersion, up, down)
+}
+
 func init() {
 	makeCmd.AddCommand(makeMigrationCmd)
+
+	// Add flags
+	makeMigrationCmd.Flags().BoolVarP(&timestampVersion, "timestamp", "t", false, "Use a timestamp version (e.g. 20261018153000) instead of the next sequence number")
+	makeMigrationCmd.Flags().BoolVar(&autoMigration, "auto", false, "Generate the SQL by diffing the models against the database schema")
+	makeMigrationCmd.Flags().BoolVar(&dropUnknown, "drop-unknown", false, "With --auto, also drop the tables that have no model")
+	makeMigrationCmd.Flags().StringVar(&rebuildTable, "rebuild", "", "Generate the table rebuild sequence for TABLE, for changes ALTER TABLE cannot make")
+	makeMigrationCmd.Flags().StringVar(&databaseName, "database", "", "Named database the migration belongs to (default: primary)")
 }
\ No newline at end of file
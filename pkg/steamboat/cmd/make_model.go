@@ -11,23 +11,27 @@ import (
 var makeModelCmd = &cobra.Command{
 	Use:   "model [name]",
 	Short: "Generate a new model",
-	Long:  `Generate a new model with database struct and query methods.`,
+	Long:  `Generate a new model with database struct, query methods, tests and a test factory.`,
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		modelName := args[0]
 		
 		log.Printf("Creating model: %s", modelName)
 		
-		if err := generator.GenerateModel(modelName); err != nil {
+		if err := generator.GenerateModel(modelName, databaseName); err != nil {
 			log.Fatalf("Failed to generate model: %v", err)
 		}
 		
 		fmt.Printf("✓ Model '%s' created successfully\n", modelName)
 		fmt.Printf("  Created: internal/database/models/%s.go\n", modelName)
 		fmt.Printf("  Created: internal/database/models/%s_test.go\n", modelName)
+		fmt.Printf("  Created: internal/database/factories/%s.go\n", modelName)
 	},
 }
 
 func init() {
 	makeCmd.AddCommand(makeModelCmd)
+
+	// Add flags
+	makeModelCmd.Flags().StringVar(&databaseName, "database", "", "Named database connection the model's queries use (default: primary)")
 }
\ No newline at end of file
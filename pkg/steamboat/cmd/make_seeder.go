@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/zulubit/steamboat/pkg/steamboat/generator"
+)
+
+var makeSeederCmd = &cobra.Command{
+	Use:   "seeder [name]",
+	Short: "Generate a new database seeder",
+	Long:  `Generate a new seeder in internal/database/seeders. If a model matching the name exists, the seeder uses its generated queries.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		seederName := args[0]
+
+		log.Printf("Creating seeder: %s", seederName)
+
+		path, err := generator.GenerateSeeder(seederName, databaseName)
+		if err != nil {
+			log.Fatalf("Failed to generate seeder: %v", err)
+		}
+
+		fmt.Printf("✓ Seeder '%s' created successfully\n", seederName)
+		fmt.Printf("  Created: %s\n", path)
+	},
+}
+
+func init() {
+	makeCmd.AddCommand(makeSeederCmd)
+
+	// Add flags
+	makeSeederCmd.Flags().StringVar(&databaseName, "database", "", "Named database the seeder targets (default: primary)")
+}
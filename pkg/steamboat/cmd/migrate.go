@@ -1,24 +1,46 @@
 package cmd
 
 import (
+	"fmt"
 	"log"
+	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/zulubit/steamboat/pkg/steamboat/migrate"
 )
 
 var (
-	rollback   bool
-	showStatus bool
+	rollback     bool
+	showStatus   bool
+	databaseName string
+	dryRun       bool
+	outputFile   string
 )
 
 var migrateCmd = &cobra.Command{
 	Use:   "migrate",
 	Short: "Run database migrations",
-	Long:  `Run all pending database migrations, rollback the last migration, or show status.`,
+	Long: `Run all pending database migrations, rollback the last migration, or show status.
+
+With --dry-run the SQL of every pending migration (or of the last one with
+--rollback) is printed in order without touching the database. --output writes
+it to a script that can be reviewed and applied by hand instead.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if dryRun || outputFile != "" {
+			runDryRun()
+			return
+		}
+
 		if showStatus {
-			version, dirty, err := migrate.Status()
+			issues, err := migrate.Check(databaseName)
+			if err != nil {
+				log.Fatalf("Failed to check migrations: %v", err)
+			}
+			for _, issue := range issues {
+				log.Printf("Warning: %s (fix: %s)", issue.Problem, issue.Fix)
+			}
+
+			version, dirty, err := migrate.Status(databaseName)
 			if err != nil {
 				log.Fatalf("Failed to get migration status: %v", err)
 			}
@@ -37,13 +59,13 @@ var migrateCmd = &cobra.Command{
 		
 		if rollback {
 			log.Println("Rolling back last migration...")
-			if err := migrate.Rollback(); err != nil {
+			if err := migrate.Rollback(databaseName); err != nil {
 				log.Fatalf("Rollback failed: %v", err)
 			}
 			log.Println("Rollback completed successfully")
 		} else {
 			log.Println("Running migrations...")
-			if err := migrate.Run(); err != nil {
+			if err := migrate.Run(databaseName); err != nil {
 				log.Fatalf("Migration failed: %v", err)
 			}
 			log.Println("All migrations completed successfully")
@@ -51,10 +73,39 @@ var migrateCmd = &cobra.Command{
 	},
 }
 
+// runDryRun prints or writes the SQL migrate would run
+func runDryRun() {
+	plan, err := migrate.DryRun(databaseName, rollback)
+	if err != nil {
+		log.Fatalf("Dry run failed: %v", err)
+	}
+
+	if outputFile == "" {
+		fmt.Print(plan.Script())
+		return
+	}
+
+	if err := os.WriteFile(outputFile, []byte(plan.Script()), 0644); err != nil {
+		log.Fatalf("Failed to write %s: %v", outputFile, err)
+	}
+
+	if len(plan.Steps) == 0 {
+		fmt.Printf("✓ Nothing to run, the database is at version %d\n", plan.Current)
+	} else {
+		fmt.Printf("✓ Wrote %d migration(s) to %s\n", len(plan.Steps), outputFile)
+	}
+	for _, step := range plan.Steps {
+		fmt.Printf("  %s: %d -> %d\n", step.Migration.ID, step.From, step.To)
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(migrateCmd)
 	
 	// Add flags
 	migrateCmd.Flags().BoolVarP(&rollback, "rollback", "r", false, "Rollback the last migration")
 	migrateCmd.Flags().BoolVarP(&showStatus, "status", "s", false, "Show current migration status")
+	migrateCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the SQL that would run without touching the database")
+	migrateCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Write the SQL that would run to a file instead of migrating (implies --dry-run)")
+	migrateCmd.PersistentFlags().StringVar(&databaseName, "database", "", "Named database to migrate (default: primary)")
 }
\ No newline at end of file
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/zulubit/steamboat/pkg/steamboat/migrate"
+)
+
+var (
+	freshSeed bool
+)
+
+var migrateFreshCmd = &cobra.Command{
+	Use:   "fresh",
+	Short: "Drop all tables and re-run every migration",
+	Long:  `Drop every table in the database, run all migrations from scratch and optionally seed the result.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Println("Dropping all tables and re-running migrations...")
+		if err := migrate.Fresh(databaseName); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		log.Println("All migrations completed successfully")
+
+		if freshSeed {
+			log.Println("Seeding database...")
+			if err := runSeeders(databaseName); err != nil {
+				log.Fatalf("Seeding failed: %v", err)
+			}
+		}
+	},
+}
+
+func init() {
+	migrateCmd.AddCommand(migrateFreshCmd)
+
+	// Add flags
+	migrateFreshCmd.Flags().BoolVar(&freshSeed, "seed", false, "Run all seeders after migrating")
+}
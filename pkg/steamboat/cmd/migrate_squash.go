@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/zulubit/steamboat/pkg/steamboat/migrate"
+)
+
+var (
+	squashUpTo uint
+)
+
+var migrateSquashCmd = &cobra.Command{
+	Use:   "squash",
+	Short: "Replace old migrations with a single baseline",
+	Long: `Apply every migration up to --up-to in a scratch database and replace their files with a
+single baseline migration generated from the resulting schema and rows. The baseline keeps the
+version it replaces, so databases that are already at or past it skip it.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if squashUpTo == 0 {
+			log.Fatalf("--up-to is required")
+		}
+
+		log.Printf("Squashing migrations up to version %d...", squashUpTo)
+
+		result, err := migrate.Squash(databaseName, squashUpTo)
+		if err != nil {
+			log.Fatalf("Squash failed: %v", err)
+		}
+
+		fmt.Printf("✓ Squashed %d migration files into a baseline\n", len(result.Removed))
+		fmt.Printf("  Created: %s\n", result.Baseline.UpPath)
+		fmt.Printf("  Created: %s\n", result.Baseline.DownPath)
+		for _, path := range result.Removed {
+			fmt.Printf("  Removed: %s\n", path)
+		}
+	},
+}
+
+func init() {
+	migrateCmd.AddCommand(migrateSquashCmd)
+
+	// Add flags
+	migrateSquashCmd.Flags().UintVar(&squashUpTo, "up-to", 0, "Last migration version to include in the baseline")
+}
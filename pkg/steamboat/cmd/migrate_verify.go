@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/zulubit/steamboat/pkg/steamboat/migrate"
+)
+
+var (
+	verifyEmpty bool
+)
+
+var migrateVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check pending migrations in a sandbox database",
+	Long: `Copy the current database (or start from an empty one) into a temporary file and
+apply each pending migration up, down and up again, comparing the schema at each step.
+Reports SQL errors, migrations whose down does not reverse their up, and destructive
+statements. The database at DB_URL is never modified.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		report, err := migrate.Verify(migrate.VerifyOptions{Database: databaseName, Empty: verifyEmpty})
+		if err != nil {
+			log.Fatalf("Verification failed: %v", err)
+		}
+
+		fmt.Printf("Verifying against %s (version %d)\n\n", report.Source, report.StartVersion)
+
+		if len(report.Results) == 0 {
+			fmt.Println("No pending migrations")
+			return
+		}
+
+		for _, result := range report.Results {
+			mark := "✓"
+			if !result.OK() {
+				mark = "✗"
+			}
+			fmt.Printf("%s %s\n", mark, result.Migration.ID)
+
+			for _, msg := range result.Errors {
+				fmt.Printf("    error: %s\n", msg)
+			}
+			for _, msg := range result.Problems {
+				fmt.Printf("    not reversible: %s\n", msg)
+			}
+			for _, statement := range result.Destructive {
+				fmt.Printf("    destructive: %s\n", statement)
+			}
+		}
+
+		if !report.OK() {
+			fmt.Println()
+			fmt.Println("Some migrations failed verification")
+			os.Exit(1)
+		}
+
+		fmt.Println()
+		fmt.Println("All pending migrations verified")
+	},
+}
+
+func init() {
+	migrateCmd.AddCommand(migrateVerifyCmd)
+
+	// Add flags
+	migrateVerifyCmd.Flags().BoolVar(&verifyEmpty, "empty", false, "Start from an empty database instead of a copy of DB_URL")
+}
@@ -5,7 +5,12 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
-	_ "github.com/joho/godotenv/autoload"
+	"github.com/zulubit/steamboat/pkg/steamboat/env"
+)
+
+var (
+	envName     string
+	environment *env.Environment
 )
 
 var rootCmd = &cobra.Command{
@@ -13,7 +18,19 @@ var rootCmd = &cobra.Command{
 	Short: "Steamboat CLI - Manage your Steamboat application",
 	Long: `Steamboat CLI is a command line tool for managing your Steamboat application.
 	
-It provides commands for database migrations, server management, and more.`,
+It provides commands for database migrations, server management, and more.
+
+Configuration is read from .env, then .env.{env}, then .env.local, later files
+overriding earlier ones. Variables already set in the shell override all files.
+The environment is chosen with --env, or APP_ENV, and defaults to development.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		loaded, err := env.Load(envName)
+		if err != nil {
+			return err
+		}
+		environment = loaded
+		return nil
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -25,4 +42,5 @@ func Execute() {
 }
 
 func init() {
+	rootCmd.PersistentFlags().StringVar(&envName, "env", "", "Environment whose .env.{env} file is loaded (default: $APP_ENV or development)")
 }
\ No newline at end of file
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+var scheduleListCmd = &cobra.Command{
+	Use:   "schedule:list",
+	Short: "List scheduled tasks",
+	Long:  `List the tasks registered in internal/schedule with their schedule, next run and the outcome of their last run.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runProjectCLI("schedule", "list"); err != nil {
+			log.Fatalf("Failed to list scheduled tasks: %v", err)
+		}
+	},
+}
+
+var scheduleRunCmd = &cobra.Command{
+	Use:   "schedule:run [name]",
+	Short: "Run a scheduled task now",
+	Long: `Run a scheduled task immediately and record its outcome. The task takes its
+lease like a scheduled run, so it does not overlap a run on a server.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runProjectCLI("schedule", "run", args[0]); err != nil {
+			log.Fatalf("Failed to run scheduled task: %v", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(scheduleListCmd)
+	rootCmd.AddCommand(scheduleRunCmd)
+}
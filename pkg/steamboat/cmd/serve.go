@@ -1,44 +1,74 @@
 package cmd
 
 import (
+	"context"
 	"log"
 	"os"
-	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/spf13/cobra"
-	_ "github.com/joho/godotenv/autoload"
+	"github.com/zulubit/steamboat/pkg/steamboat/devserver"
+	"github.com/zulubit/steamboat/pkg/steamboat/env"
 )
 
 var (
-	port string
+	port    string
+	mainPkg string
 )
 
 var serveCmd = &cobra.Command{
 	Use:   "serve",
-	Short: "Start the Steamboat server",
-	Long:  `Start the Steamboat API server with the configured settings.`,
+	Short: "Start the development server",
+	Long: `Build and run the project's server, then rebuild and restart it whenever a .go,
+.templ, .sql or .env file changes. templ generate runs when a template changes.
+Build errors are printed and the last good build keeps running.
+
+The server entrypoint is cmd/web, or the only main package in cmd/ besides
+cmd/cli; pick another with --main.`,
+	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		// Override port if provided
-		if port != "" {
-			os.Setenv("PORT", port)
-		}
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
 
-		// Run the API server using exec
-		apiCmd := exec.Command("go", "run", "cmd/api/main.go")
-		apiCmd.Stdout = os.Stdout
-		apiCmd.Stderr = os.Stderr
-		apiCmd.Stdin = os.Stdin
-		
-		log.Println("Starting Steamboat server...")
-		if err := apiCmd.Run(); err != nil {
-			log.Fatalf("Server failed to start: %v", err)
+		opts := devserver.Options{Main: mainPkg, Env: appEnv()}
+		if err := devserver.Run(ctx, opts); err != nil {
+			log.Fatalf("Failed to run the server: %v", err)
 		}
 	},
 }
 
+// appEnv returns the environment of the app without the variables loaded
+// from .env files, so the app reads them again on every restart and picks up
+// edits
+func appEnv() []string {
+	fromFiles := make(map[string]bool)
+	for _, v := range environment.Vars {
+		if v.Key != "APP_ENV" && v.Source != env.SourceProcess && v.Source != env.SourceFlag && v.Source != env.SourceDefault {
+			fromFiles[v.Key] = true
+		}
+	}
+
+	var vars []string
+	for _, entry := range os.Environ() {
+		key, _, _ := strings.Cut(entry, "=")
+		if fromFiles[key] || key == "PORT" && port != "" {
+			continue
+		}
+		vars = append(vars, entry)
+	}
+	// --port overrides PORT from any source
+	if port != "" {
+		vars = append(vars, "PORT="+port)
+	}
+	return vars
+}
+
 func init() {
 	rootCmd.AddCommand(serveCmd)
-	
+
 	// Add flags
 	serveCmd.Flags().StringVarP(&port, "port", "p", "", "Port to run the server on (overrides PORT env var)")
-}
\ No newline at end of file
+	serveCmd.Flags().StringVar(&mainPkg, "main", "", "Main package of the server (default: cmd/web)")
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package devserver
+
+import "os/exec"
+
+// detach is a no-op where processes cannot be moved to another group
+func detach(cmd *exec.Cmd) {}
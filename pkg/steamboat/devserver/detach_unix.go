@@ -0,0 +1,14 @@
+//go:build unix
+
+package devserver
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detach starts cmd in its own process group, so Ctrl-C in the terminal
+// reaches only the dev server, which then stops the app once
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
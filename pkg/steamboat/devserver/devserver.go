@@ -0,0 +1,232 @@
+// Package devserver runs a Steamboat project for development, rebuilding and
+// restarting it whenever its sources change.
+//
+// The project directory is polled for changes to .go, .templ, .sql and .env
+// files. On a change templ generate runs if a template changed, the main
+// package is built, and only if the build succeeds is the running app
+// stopped and the new binary started, so a typo never takes the server down.
+package devserver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Options configures Run
+type Options struct {
+	// Dir is the project root (default: the working directory)
+	Dir string
+	// Main is the package to run, e.g. ./cmd/web (default: FindMain)
+	Main string
+	// Env is the environment of the app process (default: os.Environ())
+	Env []string
+	// Interval is how often the sources are checked for changes (default: 500ms)
+	Interval time.Duration
+	// StopTimeout is how long the app gets to shut down gracefully before it
+	// is killed (default: 15s)
+	StopTimeout time.Duration
+}
+
+// app is a started build of the project
+type app struct {
+	cmd    *exec.Cmd
+	exited chan struct{}
+}
+
+// Run builds and starts the app, then rebuilds and restarts it on every
+// change until ctx is done, when the app is stopped. Build errors are
+// printed and the previous build keeps running.
+func Run(ctx context.Context, opts Options) error {
+	if opts.Dir == "" {
+		opts.Dir = "."
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 500 * time.Millisecond
+	}
+	if opts.StopTimeout <= 0 {
+		opts.StopTimeout = 15 * time.Second
+	}
+	if opts.Env == nil {
+		opts.Env = os.Environ()
+	}
+	if opts.Main == "" {
+		main, err := FindMain(opts.Dir)
+		if err != nil {
+			return err
+		}
+		opts.Main = main
+	}
+
+	binary, err := filepath.Abs(filepath.Join(opts.Dir, "tmp", "serve", "app"))
+	if err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+
+	fmt.Printf("Watching %s for changes, running %s\n", opts.Dir, opts.Main)
+	files := snapshot(opts.Dir)
+
+	var running *app
+	if err := build(ctx, opts, binary, true); err != nil {
+		printBuildError(err)
+	} else if running, err = start(opts, binary); err != nil {
+		return err
+	}
+	defer func() {
+		if running != nil {
+			running.stop(opts.StopTimeout)
+		}
+	}()
+
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	for {
+		var exited chan struct{}
+		if running != nil {
+			exited = running.exited
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-exited:
+			fmt.Printf("⚠ App exited (%v), waiting for changes\n", running.cmd.ProcessState)
+			running = nil
+			continue
+		case <-ticker.C:
+		}
+
+		current := snapshot(opts.Dir)
+		changed := diff(files, current)
+		if len(changed) == 0 {
+			continue
+		}
+		// Editors often write several files at once; wait until it settles
+		for {
+			time.Sleep(opts.Interval)
+			settled := snapshot(opts.Dir)
+			more := diff(current, settled)
+			if len(more) == 0 {
+				break
+			}
+			changed = append(changed, more...)
+			current = settled
+		}
+		files = current
+
+		fmt.Printf("\n↻ %s changed, rebuilding\n", describe(opts.Dir, changed))
+		templChanged := false
+		for _, path := range changed {
+			templChanged = templChanged || strings.HasSuffix(path, ".templ")
+		}
+
+		started := time.Now()
+		if err := build(ctx, opts, binary, templChanged); err != nil {
+			if ctx.Err() == nil {
+				printBuildError(err)
+			}
+			continue
+		}
+		fmt.Printf("✓ Built in %s, restarting\n", time.Since(started).Round(time.Millisecond))
+
+		if running != nil {
+			running.stop(opts.StopTimeout)
+		}
+		if running, err = start(opts, binary); err != nil {
+			fmt.Printf("✗ %v\n", err)
+		}
+	}
+}
+
+// build runs templ generate if needed and builds the main package to binary
+func build(ctx context.Context, opts Options, binary string, generate bool) error {
+	if generate {
+		if out, err := run(ctx, opts, "go", "tool", "templ", "generate"); err != nil {
+			return fmt.Errorf("templ generate failed:\n%s", out)
+		}
+	}
+	if out, err := run(ctx, opts, "go", "build", "-o", binary, opts.Main); err != nil {
+		return fmt.Errorf("go build failed:\n%s", out)
+	}
+	return nil
+}
+
+func run(ctx context.Context, opts Options, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = opts.Dir
+	cmd.Env = opts.Env
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	return strings.TrimSpace(out.String()), err
+}
+
+func start(opts Options, binary string) (*app, error) {
+	cmd := exec.Command(binary)
+	cmd.Dir = opts.Dir
+	cmd.Env = opts.Env
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", binary, err)
+	}
+
+	a := &app{cmd: cmd, exited: make(chan struct{})}
+	go func() {
+		cmd.Wait()
+		close(a.exited)
+	}()
+	return a, nil
+}
+
+// stop asks the app to shut down like Ctrl-C would and kills it if it has
+// not exited after timeout
+func (a *app) stop(timeout time.Duration) {
+	if err := a.cmd.Process.Signal(os.Interrupt); err != nil {
+		// Windows cannot deliver an interrupt to another process
+		a.cmd.Process.Kill()
+	}
+	select {
+	case <-a.exited:
+	case <-time.After(timeout):
+		fmt.Printf("⚠ App did not stop within %s, killing it\n", timeout)
+		a.cmd.Process.Kill()
+		<-a.exited
+	}
+}
+
+func printBuildError(err error) {
+	fmt.Printf("✗ %v\n\nWaiting for changes...\n", err)
+}
+
+// describe names the first changed file and counts the others
+func describe(dir string, changed []string) string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, path := range changed {
+		if !seen[path] {
+			seen[path] = true
+			unique = append(unique, path)
+		}
+	}
+
+	first := unique[0]
+	if rel, err := filepath.Rel(dir, first); err == nil {
+		first = rel
+	}
+	if len(unique) == 1 {
+		return first
+	}
+	return fmt.Sprintf("%s and %d more", first, len(unique)-1)
+}
@@ -0,0 +1,65 @@
+package devserver
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FindMain returns the package path of the project's server entrypoint,
+// relative to dir: cmd/web in generated projects, otherwise the only main
+// package under cmd/ besides the cli, or the project root itself
+func FindMain(dir string) (string, error) {
+	if isMain(filepath.Join(dir, "cmd", "web")) {
+		return "./cmd/web", nil
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, "cmd"))
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read cmd directory: %w", err)
+	}
+	var candidates []string
+	for _, e := range entries {
+		// cmd/cli runs project tasks for the steamboat CLI, it is no server
+		if e.IsDir() && e.Name() != "cli" && isMain(filepath.Join(dir, "cmd", e.Name())) {
+			candidates = append(candidates, "./cmd/"+e.Name())
+		}
+	}
+	sort.Strings(candidates)
+
+	switch {
+	case len(candidates) == 1:
+		return candidates[0], nil
+	case len(candidates) > 1:
+		return "", fmt.Errorf("found several main packages (%s), pick one with --main", strings.Join(candidates, ", "))
+	case isMain(dir):
+		return ".", nil
+	}
+	return "", fmt.Errorf("no main package found in %s, pass one with --main", filepath.Join(dir, "cmd"))
+}
+
+// isMain reports whether the Go files in dir declare package main with a main function
+func isMain(dir string) bool {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		parsed, err := parser.ParseFile(fset, file, nil, parser.SkipObjectResolution)
+		if err != nil || parsed.Name.Name != "main" {
+			continue
+		}
+		for _, decl := range parsed.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == "main" {
+				return true
+			}
+		}
+	}
+	return false
+}
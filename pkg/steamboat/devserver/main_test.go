@@ -0,0 +1,81 @@
+package devserver
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const mainSource = "package main\n\nfunc main() {}\n"
+
+// writeProject creates the files, keyed by slash separated path, in a new directory
+func writeProject(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFindMainPrefersCmdWeb(t *testing.T) {
+	dir := writeProject(t, map[string]string{
+		"cmd/web/main.go":    mainSource,
+		"cmd/worker/main.go": mainSource,
+		"main.go":            mainSource,
+	})
+
+	if got, err := FindMain(dir); err != nil || got != "./cmd/web" {
+		t.Errorf("Expected ./cmd/web, got %q (%v)", got, err)
+	}
+}
+
+func TestFindMainSkipsCliAndNonMainPackages(t *testing.T) {
+	dir := writeProject(t, map[string]string{
+		"cmd/api/main.go":        mainSource,
+		"cmd/api/handlers.go":    "package main\n",
+		"cmd/cli/main.go":        mainSource,
+		"cmd/shared/shared.go":   "package shared\n\nfunc main() {}\n",
+		"cmd/nomain/main.go":     "package main\n\nfunc run() {}\n",
+		"cmd/method/main.go":     "package main\n\ntype s struct{}\n\nfunc (s) main() {}\n",
+		"cmd/testonly/x_test.go": mainSource,
+		"cmd/broken/main.go":     "package main\n\nfunc main() {",
+	})
+
+	if got, err := FindMain(dir); err != nil || got != "./cmd/api" {
+		t.Errorf("Expected ./cmd/api, got %q (%v)", got, err)
+	}
+}
+
+func TestFindMainFallsBackToRoot(t *testing.T) {
+	dir := writeProject(t, map[string]string{
+		"main.go":         mainSource,
+		"cmd/cli/main.go": mainSource,
+	})
+
+	if got, err := FindMain(dir); err != nil || got != "." {
+		t.Errorf("Expected the project root, got %q (%v)", got, err)
+	}
+}
+
+func TestFindMainErrors(t *testing.T) {
+	dir := writeProject(t, map[string]string{
+		"cmd/worker/main.go": mainSource,
+		"cmd/api/main.go":    mainSource,
+	})
+	want := "found several main packages (./cmd/api, ./cmd/worker), pick one with --main"
+	if _, err := FindMain(dir); err == nil || err.Error() != want {
+		t.Errorf("Expected %q, got %v", want, err)
+	}
+
+	dir = writeProject(t, map[string]string{"lib.go": "package lib\n"})
+	if _, err := FindMain(dir); err == nil || !strings.HasPrefix(err.Error(), "no main package found") {
+		t.Errorf("Expected no main package to be found, got %v", err)
+	}
+}
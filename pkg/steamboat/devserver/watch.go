@@ -0,0 +1,77 @@
+package devserver
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// watchedExts are the extensions whose changes trigger a rebuild
+var watchedExts = map[string]bool{".go": true, ".templ": true, ".sql": true}
+
+// skippedDirs hold no sources, or output the app itself writes
+var skippedDirs = map[string]bool{
+	"node_modules": true, "vendor": true, "tmp": true, "db": true,
+	"logs": true, "storage": true, "certs": true,
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// snapshot records the state of every watched file under dir
+func snapshot(dir string) map[string]fileState {
+	files := make(map[string]fileState)
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(name, ".") || skippedDirs[name]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !watched(name) {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			files[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+		}
+		return nil
+	})
+	return files
+}
+
+// watched reports whether changes to the file name trigger a rebuild
+func watched(name string) bool {
+	if name == ".env" || strings.HasPrefix(name, ".env.") {
+		return name != ".env.example"
+	}
+	// Written by templ generate, which the rebuild runs itself
+	if strings.HasSuffix(name, "_templ.go") {
+		return false
+	}
+	return watchedExts[filepath.Ext(name)]
+}
+
+// diff returns the files added, changed or removed between two snapshots
+func diff(before, after map[string]fileState) []string {
+	var changed []string
+	for path, state := range after {
+		if old, ok := before[path]; !ok || !old.modTime.Equal(state.modTime) || old.size != state.size {
+			changed = append(changed, path)
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
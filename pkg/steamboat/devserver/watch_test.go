@@ -0,0 +1,73 @@
+package devserver
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatched(t *testing.T) {
+	for name, want := range map[string]bool{
+		"main.go":                    true,
+		"home.templ":                 true,
+		"000001_create_users.up.sql": true,
+		".env":                       true,
+		".env.local":                 true,
+		"home_templ.go":              false,
+		".env.example":               false,
+		".envrc":                     false,
+		"styles.css":                 false,
+		"app.db":                     false,
+	} {
+		if got := watched(name); got != want {
+			t.Errorf("watched(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestSnapshotSkipsOutputAndHiddenDirs(t *testing.T) {
+	dir := writeProject(t, map[string]string{
+		"main.go":                   "x",
+		".env":                      "x",
+		"views/home.templ":          "x",
+		"views/home_templ.go":       "x",
+		"views/styles.css":          "x",
+		"node_modules/pkg/index.go": "x",
+		".git/hooks/x.go":           "x",
+		"storage/upload.go":         "x",
+		"db/migrations/1.up.sql":    "x",
+	})
+
+	var got []string
+	for path := range snapshot(dir) {
+		rel, _ := filepath.Rel(dir, path)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	sort.Strings(got)
+	if strings.Join(got, " ") != ".env main.go views/home.templ" {
+		t.Errorf("Unexpected watched files: %v", got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	now := time.Now()
+	before := map[string]fileState{
+		"a.go": {modTime: now, size: 10},
+		"b.go": {modTime: now, size: 20},
+		"c.go": {modTime: now, size: 30},
+	}
+	after := map[string]fileState{
+		"a.go": {modTime: now, size: 10},
+		"b.go": {modTime: now, size: 21}, // rewritten within the same mtime tick
+		"d.go": {modTime: now, size: 1},
+	}
+
+	if got := diff(before, after); strings.Join(got, " ") != "b.go c.go d.go" {
+		t.Errorf("Expected the resized, removed and added files, got %v", got)
+	}
+	if got := diff(before, before); len(got) > 0 {
+		t.Errorf("Expected no changes, got %v", got)
+	}
+}
@@ -0,0 +1,239 @@
+// Package env loads the .env files of a Steamboat project for the selected
+// environment.
+//
+// Files are loaded in this order, later files overriding earlier ones:
+//
+//	.env          shared defaults, committed
+//	.env.{env}    per environment, e.g. .env.test or .env.production
+//	.env.local    machine-specific overrides, not committed
+//
+// Variables already set in the process environment always win over every
+// file, so `DB_URL=x steamboat migrate` behaves as expected.
+package env
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// Default is the environment used when neither --env nor APP_ENV is set
+const Default = "development"
+
+// Sources of a variable besides the file it was read from
+const (
+	SourceFlag    = "--env flag"
+	SourceProcess = "environment"
+	SourceDefault = "default"
+)
+
+// secretPattern matches variable names whose values are masked by Mask
+var secretPattern = regexp.MustCompile(`(?i)(SECRET|KEY|TOKEN|PASSWORD|PASSWD|PRIVATE|CREDENTIAL)`)
+
+// namePattern restricts environment names to safe file name suffixes
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// configFile declares the variables a project reads, in env struct tags
+const configFile = "internal/config/config.go"
+
+// envTagPattern matches the env struct tags of configFile, e.g. env:"DB_URL"
+var envTagPattern = regexp.MustCompile(`env:"([A-Za-z0-9_]+)"`)
+
+// Var is an effective variable and where its value came from
+type Var struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// Environment describes what Load did
+type Environment struct {
+	Name string
+	// Files lists the files that were found, in load order
+	Files []string
+	// Missing lists the files that were looked for but do not exist
+	Missing []string
+	// Vars holds APP_ENV, every variable defined in the files and the
+	// project variables set in the process, sorted by key
+	Vars []Var
+}
+
+// Load resolves the environment name and loads its files into the process
+// environment. An empty name falls back to APP_ENV from the process, then
+// APP_ENV from .env, then Default.
+func Load(name string) (*Environment, error) {
+	process := make(map[string]bool)
+	for _, entry := range os.Environ() {
+		key, _, _ := strings.Cut(entry, "=")
+		process[key] = true
+	}
+
+	base, err := readFile(".env")
+	if err != nil {
+		return nil, err
+	}
+
+	nameSource := SourceFlag
+	switch {
+	case name != "":
+	case os.Getenv("APP_ENV") != "":
+		name, nameSource = os.Getenv("APP_ENV"), SourceProcess
+	case base["APP_ENV"] != "":
+		name, nameSource = base["APP_ENV"], ".env"
+	default:
+		name, nameSource = Default, SourceDefault
+	}
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid environment name %q", name)
+	}
+
+	environment := &Environment{Name: name}
+	values := make(map[string]string)
+	sources := make(map[string]string)
+
+	for _, file := range []string{".env", ".env." + name, ".env.local"} {
+		vars := base
+		if file != ".env" {
+			if vars, err = readFile(file); err != nil {
+				return nil, err
+			}
+		}
+		if vars == nil {
+			environment.Missing = append(environment.Missing, file)
+			continue
+		}
+
+		environment.Files = append(environment.Files, file)
+		for key, value := range vars {
+			values[key] = value
+			sources[key] = file
+		}
+	}
+
+	for key, value := range values {
+		if process[key] {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return nil, fmt.Errorf("failed to set %s: %w", key, err)
+		}
+	}
+
+	// The resolved name is exported so subprocesses such as the app itself agree
+	if err := os.Setenv("APP_ENV", name); err != nil {
+		return nil, fmt.Errorf("failed to set APP_ENV: %w", err)
+	}
+
+	for key := range values {
+		source := sources[key]
+		if process[key] {
+			source = SourceProcess
+		}
+		environment.Vars = append(environment.Vars, Var{Key: key, Value: os.Getenv(key), Source: source})
+	}
+	prefixes, err := projectPrefixes()
+	if err != nil {
+		return nil, err
+	}
+	for key := range process {
+		if _, ok := values[key]; !ok && prefixes.match(key) {
+			environment.Vars = append(environment.Vars, Var{Key: key, Value: os.Getenv(key), Source: SourceProcess})
+		}
+	}
+	environment.setVar(Var{Key: "APP_ENV", Value: name, Source: nameSource})
+
+	sort.Slice(environment.Vars, func(i, j int) bool { return environment.Vars[i].Key < environment.Vars[j].Key })
+
+	return environment, nil
+}
+
+func (e *Environment) setVar(v Var) {
+	for i := range e.Vars {
+		if e.Vars[i].Key == v.Key {
+			e.Vars[i] = v
+			return
+		}
+	}
+	e.Vars = append(e.Vars, v)
+}
+
+// readFile parses a .env file, returning nil if it does not exist
+func readFile(path string) (map[string]string, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	vars, err := godotenv.Read(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+	return vars, nil
+}
+
+// prefixes holds the variable names a project reads, and the first word of
+// each, e.g. "DB_" for DB_URL, which also covers related names like DB_MAIN_URL
+type prefixes map[string]bool
+
+func (p prefixes) match(key string) bool {
+	if p[key] {
+		return true
+	}
+	word, _, found := strings.Cut(key, "_")
+	return found && p[word+"_"]
+}
+
+// projectPrefixes collects the variables the project reads from the env tags
+// of its config and the keys of .env.example
+func projectPrefixes() (prefixes, error) {
+	var keys []string
+
+	source, err := os.ReadFile(configFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", configFile, err)
+	}
+	for _, match := range envTagPattern.FindAllStringSubmatch(string(source), -1) {
+		keys = append(keys, match[1])
+	}
+
+	example, err := readFile(".env.example")
+	if err != nil {
+		return nil, err
+	}
+	for key := range example {
+		keys = append(keys, key)
+	}
+
+	p := make(prefixes)
+	for _, key := range keys {
+		p[key] = true
+		if word, _, found := strings.Cut(key, "_"); found {
+			p[word+"_"] = true
+		}
+	}
+	return p, nil
+}
+
+// Mask hides the value of secret-looking variables, and passwords in URLs
+func Mask(key, value string) string {
+	if value == "" {
+		return ""
+	}
+
+	if secretPattern.MatchString(key) {
+		return "********"
+	}
+
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		if _, hasPassword := u.User.Password(); hasPassword {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+			return strings.Replace(u.String(), "xxxxx", "********", 1)
+		}
+	}
+
+	return value
+}
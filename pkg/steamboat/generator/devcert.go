@@ -0,0 +1,203 @@
+package generator
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DevCertValidity is how long a development certificate is valid
+const DevCertValidity = 365 * 24 * time.Hour
+
+// DevCAValidity is how long the local development CA is valid
+const DevCAValidity = 10 * 365 * 24 * time.Hour
+
+// DevCert lists the files written by GenerateDevCert
+type DevCert struct {
+	CertPath string
+	KeyPath  string
+	// CAPath is the certificate of the local CA that signed the certificate
+	CAPath string
+	// CACreated is set when the CA was created rather than reused
+	CACreated bool
+}
+
+// GenerateDevCert writes a certificate and its private key for hosts (names
+// or IP addresses) to dir/dev.crt and dir/dev.key, signed by a local CA kept
+// in dir/dev-ca.crt and dir/dev-ca.key. The CA is created on first use and
+// reused after, so it only has to be trusted once. It is meant for local
+// HTTPS only. An existing dev.key is only replaced with force.
+func GenerateDevCert(dir string, hosts []string, force bool) (*DevCert, error) {
+	result := &DevCert{
+		CertPath: filepath.Join(dir, "dev.crt"),
+		KeyPath:  filepath.Join(dir, "dev.key"),
+		CAPath:   filepath.Join(dir, "dev-ca.crt"),
+	}
+	if _, err := os.Stat(result.KeyPath); err == nil && !force {
+		return nil, fmt.Errorf("%s already exists, use --force to replace it", result.KeyPath)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	caKeyPath := filepath.Join(dir, "dev-ca.key")
+	ca, caKey, err := loadDevCA(result.CAPath, caKeyPath)
+	if errors.Is(err, os.ErrNotExist) {
+		ca, caKey, err = createDevCA(result.CAPath, caKeyPath)
+		result.CACreated = true
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{Organization: []string{"Steamboat development"}, CommonName: hosts[0]},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(DevCertValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		// A leaf certificate, which cannot sign others
+		BasicConstraintsValid: true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode key: %w", err)
+	}
+
+	// The certificate is followed by the CA so clients get the whole chain
+	chain := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})...)
+	if err := os.WriteFile(result.CertPath, chain, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write certificate: %w", err)
+	}
+	if err := os.WriteFile(result.KeyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write key: %w", err)
+	}
+
+	return result, nil
+}
+
+// createDevCA writes a new local CA certificate and key
+func createDevCA(certPath, keyPath string) (*x509.Certificate, crypto.Signer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate CA key: %w", err)
+	}
+
+	serial, err := newSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"Steamboat development"}, CommonName: "Steamboat development CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(DevCAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse CA certificate: %w", err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode CA key: %w", err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return nil, nil, fmt.Errorf("failed to write CA key: %w", err)
+	}
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return nil, nil, fmt.Errorf("failed to write CA certificate: %w", err)
+	}
+
+	return cert, key, nil
+}
+
+// loadDevCA reads the local CA written by createDevCA. The error wraps
+// os.ErrNotExist when there is none yet.
+func loadDevCA(certPath, keyPath string) (*x509.Certificate, crypto.Signer, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CA key: %w", err)
+	}
+
+	certBlock, _ := pem.Decode(certPEM)
+	keyBlock, _ := pem.Decode(keyPEM)
+	if certBlock == nil || keyBlock == nil {
+		return nil, nil, fmt.Errorf("invalid CA in %s and %s", certPath, keyPath)
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse CA certificate: %w", err)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse CA key: %w", err)
+	}
+	key, ok := parsed.(crypto.Signer)
+	if !ok || !cert.IsCA {
+		return nil, nil, fmt.Errorf("invalid CA in %s and %s", certPath, keyPath)
+	}
+
+	return cert, key, nil
+}
+
+func newSerial() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+	return serial, nil
+}
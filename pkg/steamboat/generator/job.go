@@ -0,0 +1,77 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+const jobTemplate = `package jobs
+
+import (
+	"context"
+)
+
+// {{.TypeName}} is the payload of the {{.Kind}} job. It is stored as JSON,
+// so only exported fields are kept.
+type {{.TypeName}} struct {
+	// Add the data the job needs here, e.g. UserID int ` + "`json:\"user_id\"`" + `
+}
+
+func ({{.TypeName}}) Kind() string { return "{{.Kind}}" }
+
+func init() {
+	Register(handle{{.TypeName}})
+}
+
+// handle{{.TypeName}} runs the job with the services in deps, e.g.
+// deps.Mailer.Send(ctx, msg). Returning an error retries it with backoff;
+// wrap the error with Permanent when retrying cannot help.
+func handle{{.TypeName}}(ctx context.Context, deps Deps, job {{.TypeName}}) error {
+	// Do the work here
+	return nil
+}
+`
+
+type JobData struct {
+	TypeName string
+	Kind     string
+}
+
+// GenerateJob creates internal/jobs/<name>.go with the payload type and
+// handler of a background job, e.g. "SendWelcomeEmail" creates
+// send_welcome_email.go
+func GenerateJob(name string) (string, error) {
+	kind := toSnakeCase(name)
+	data := JobData{
+		TypeName: toIdentifier(kind),
+		Kind:     kind,
+	}
+
+	jobPath := filepath.Join("internal", "jobs", kind+".go")
+	if _, err := os.Stat(jobPath); err == nil {
+		return "", fmt.Errorf("job %s already exists", jobPath)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(jobPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	file, err := os.Create(jobPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	tmpl, err := template.New("job").Parse(jobTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	if err := tmpl.Execute(file, data); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	return jobPath, nil
+}
@@ -6,25 +6,35 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
-
-	_ "github.com/joho/godotenv/autoload"
+	"time"
 )
 
-// GenerateMigration creates a new SQL migration file pair
-func GenerateMigration(name string) (string, error) {
-	// Find the next migration number
-	migrationNum, err := getNextMigrationNumber()
+// timestampFormat is the layout of timestamp-based migration versions, e.g. 20261018153000
+const timestampFormat = "20060102150405"
+
+// GenerateMigration creates a new SQL migration file pair in migrationsDir.
+// Versions are a six-digit sequence unless timestamp is set or the existing
+// migrations already use timestamps.
+func GenerateMigration(name, migrationsDir string, timestamp bool) (string, error) {
+	return writeMigration(name, migrationsDir, timestamp, "-- Add your SQL here\n\n", "-- Add your rollback SQL here\n\n")
+}
+
+// GenerateMigrationSQL creates a migration file pair with the given up and down SQL
+func GenerateMigrationSQL(name, migrationsDir string, timestamp bool, up, down string) (string, error) {
+	return writeMigration(name, migrationsDir, timestamp, up, down)
+}
+
+func writeMigration(name, migrationsDir string, timestamp bool, up, down string) (string, error) {
+	// Find the next migration version
+	version, err := getNextMigrationVersion(migrationsDir, timestamp)
 	if err != nil {
 		return "", fmt.Errorf("failed to get next migration number: %w", err)
 	}
 	
 	// Create migration file names
 	migrationName := toSnakeCase(name)
-	upFile := fmt.Sprintf("%06d_%s.up.sql", migrationNum, migrationName)
-	downFile := fmt.Sprintf("%06d_%s.down.sql", migrationNum, migrationName)
-	
-	// Use hardcoded migrations path
-	migrationsDir := "internal/database/migrations"
+	upFile := fmt.Sprintf("%s_%s.up.sql", version, migrationName)
+	downFile := fmt.Sprintf("%s_%s.down.sql", version, migrationName)
 	
 	// Ensure directory exists
 	if err := os.MkdirAll(migrationsDir, 0755); err != nil {
@@ -38,9 +48,7 @@ func GenerateMigration(name string) (string, error) {
 	upContent := fmt.Sprintf(`-- Migration: %s
 -- Created: %s
 
--- Add your SQL here
-
-`, toHumanReadableMigration(name), fmt.Sprintf("%06d", migrationNum))
+%s`, toHumanReadableMigration(name), version, up)
 	
 	if err := os.WriteFile(upPath, []byte(upContent), 0644); err != nil {
 		return "", fmt.Errorf("failed to create up migration file: %w", err)
@@ -50,9 +58,7 @@ func GenerateMigration(name string) (string, error) {
 	downContent := fmt.Sprintf(`-- Rollback: %s
 -- Created: %s
 
--- Add your rollback SQL here
-
-`, toHumanReadableMigration(name), fmt.Sprintf("%06d", migrationNum))
+%s`, toHumanReadableMigration(name), version, down)
 	
 	if err := os.WriteFile(downPath, []byte(downContent), 0644); err != nil {
 		return "", fmt.Errorf("failed to create down migration file: %w", err)
@@ -61,20 +67,43 @@ func GenerateMigration(name string) (string, error) {
 	return fmt.Sprintf("Migration files created:\n  - %s\n  - %s", upPath, downPath), nil
 }
 
-func getNextMigrationNumber() (int, error) {
-	migrationsDir := "internal/database/migrations"
-	
+// getNextMigrationVersion returns the formatted version for a new migration
+func getNextMigrationVersion(migrationsDir string, timestamp bool) (string, error) {
+	maxNum, usesTimestamps, err := getMaxMigrationNumber(migrationsDir)
+	if err != nil {
+		return "", err
+	}
+
+	if !timestamp && !usesTimestamps {
+		return fmt.Sprintf("%06d", maxNum+1), nil
+	}
+
+	// Two migrations generated within the same second must not share a version
+	now := time.Now().UTC()
+	version, _ := strconv.ParseInt(now.Format(timestampFormat), 10, 64)
+	for version <= maxNum {
+		now = now.Add(time.Second)
+		version, _ = strconv.ParseInt(now.Format(timestampFormat), 10, 64)
+	}
+
+	return now.Format(timestampFormat), nil
+}
+
+// getMaxMigrationNumber returns the highest existing version and whether the
+// existing migrations use timestamp versions
+func getMaxMigrationNumber(migrationsDir string) (int64, bool, error) {
 	// Ensure directory exists
 	if err := os.MkdirAll(migrationsDir, 0755); err != nil {
-		return 0, err
+		return 0, false, err
 	}
 	
 	entries, err := os.ReadDir(migrationsDir)
 	if err != nil {
-		return 0, err
+		return 0, false, err
 	}
 	
-	maxNum := 0
+	var maxNum int64
+	usesTimestamps := false
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
@@ -91,14 +120,17 @@ func getNextMigrationNumber() (int, error) {
 			continue
 		}
 		
-		if num, err := strconv.Atoi(parts[0]); err == nil {
+		if num, err := strconv.ParseInt(parts[0], 10, 64); err == nil {
 			if num > maxNum {
 				maxNum = num
 			}
+			if len(parts[0]) == len(timestampFormat) {
+				usesTimestamps = true
+			}
 		}
 	}
 	
-	return maxNum + 1, nil
+	return maxNum, usesTimestamps, nil
 }
 
 func toHumanReadableMigration(s string) string {
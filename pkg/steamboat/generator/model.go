@@ -14,8 +14,6 @@ const modelTemplate = `package models
 import (
 	"context"
 	"time"
-
-	"github.com/jmoiron/sqlx"
 )
 
 type {{.StructName}} struct {
@@ -24,11 +22,16 @@ type {{.StructName}} struct {
 	UpdatedAt time.Time ` + "`" + `db:"updated_at" json:"updated_at"` + "`" + `
 }
 
+// TableName is read by ` + "`" + `steamboat make migration --auto` + "`" + `
+func ({{.StructName}}) TableName() string {
+	return "{{.TableName}}"
+}
+
 type {{.StructName}}Queries struct {
-	db *sqlx.DB
+	db DBTX
 }
 
-func New{{.StructName}}Queries(db *sqlx.DB) *{{.StructName}}Queries {
+func New{{.StructName}}Queries(db DBTX) *{{.StructName}}Queries {
 	return &{{.StructName}}Queries{db: db}
 }
 
@@ -88,7 +91,7 @@ func (q *{{.StructName}}Queries) Delete(ctx context.Context, id int) error {
 }
 `
 
-const modelTestTemplate = `package models
+const modelTestTemplate = `package models_test
 
 import (
 	"context"
@@ -97,14 +100,18 @@ import (
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/mattn/go-sqlite3"
+
+	"{{.ProjectName}}/internal/database/factories"
+	"{{.ProjectName}}/internal/database/models"
 )
 
-func setup{{.StructName}}Test(t *testing.T) (*{{.StructName}}Queries, func()) {
+func setup{{.StructName}}Test(t *testing.T) (*models.{{.StructName}}Queries, *sqlx.DB, func()) {
 	// Create in-memory SQLite database
 	db, err := sqlx.Open("sqlite3", ":memory:")
 	if err != nil {
 		t.Fatalf("Failed to open test database: %v", err)
 	}
+	db.SetMaxOpenConns(1)
 
 	// Create table
 	_, err = db.Exec(` + "`" + `CREATE TABLE {{.TableName}} (
@@ -116,31 +123,20 @@ func setup{{.StructName}}Test(t *testing.T) (*{{.StructName}}Queries, func()) {
 		t.Fatalf("Failed to create test table: %v", err)
 	}
 
-	queries := New{{.StructName}}Queries(db)
+	queries := models.New{{.StructName}}Queries(db)
 	
 	cleanup := func() {
 		db.Close()
 	}
 
-	return queries, cleanup
+	return queries, db, cleanup
 }
 
 func Test{{.StructName}}Queries_Create(t *testing.T) {
-	queries, cleanup := setup{{.StructName}}Test(t)
+	_, db, cleanup := setup{{.StructName}}Test(t)
 	defer cleanup()
 
-	ctx := context.Background()
-	now := time.Now()
-	
-	{{.VarName}} := &{{.StructName}}{
-		CreatedAt: now,
-		UpdatedAt: now,
-	}
-
-	err := queries.Create(ctx, {{.VarName}})
-	if err != nil {
-		t.Fatalf("Failed to create {{.VarName}}: %v", err)
-	}
+	{{.VarName}} := factories.{{.StructName}}(t, db)
 
 	if {{.VarName}}.ID == 0 {
 		t.Error("Expected ID to be set after creation")
@@ -148,21 +144,11 @@ func Test{{.StructName}}Queries_Create(t *testing.T) {
 }
 
 func Test{{.StructName}}Queries_GetByID(t *testing.T) {
-	queries, cleanup := setup{{.StructName}}Test(t)
+	queries, db, cleanup := setup{{.StructName}}Test(t)
 	defer cleanup()
 
 	ctx := context.Background()
-	now := time.Now()
-	
-	// Create a {{.VarName}} first
-	{{.VarName}} := &{{.StructName}}{
-		CreatedAt: now,
-		UpdatedAt: now,
-	}
-	err := queries.Create(ctx, {{.VarName}})
-	if err != nil {
-		t.Fatalf("Failed to create {{.VarName}}: %v", err)
-	}
+	{{.VarName}} := factories.{{.StructName}}(t, db)
 
 	// Get the {{.VarName}} by ID
 	retrieved, err := queries.GetByID(ctx, {{.VarName}}.ID)
@@ -176,23 +162,11 @@ func Test{{.StructName}}Queries_GetByID(t *testing.T) {
 }
 
 func Test{{.StructName}}Queries_GetAll(t *testing.T) {
-	queries, cleanup := setup{{.StructName}}Test(t)
+	queries, db, cleanup := setup{{.StructName}}Test(t)
 	defer cleanup()
 
 	ctx := context.Background()
-	now := time.Now()
-
-	// Create multiple {{.PluralVarName}}
-	for i := 0; i < 3; i++ {
-		{{.VarName}} := &{{.StructName}}{
-			CreatedAt: now,
-			UpdatedAt: now,
-		}
-		err := queries.Create(ctx, {{.VarName}})
-		if err != nil {
-			t.Fatalf("Failed to create {{.VarName}} %d: %v", i, err)
-		}
-	}
+	factories.{{.PluralStructName}}(t, db, 3)
 
 	// Get all {{.PluralVarName}}
 	{{.PluralVarName}}, err := queries.GetAll(ctx)
@@ -206,25 +180,16 @@ func Test{{.StructName}}Queries_GetAll(t *testing.T) {
 }
 
 func Test{{.StructName}}Queries_Update(t *testing.T) {
-	queries, cleanup := setup{{.StructName}}Test(t)
+	queries, db, cleanup := setup{{.StructName}}Test(t)
 	defer cleanup()
 
 	ctx := context.Background()
-	now := time.Now()
-	
-	// Create a {{.VarName}} first
-	{{.VarName}} := &{{.StructName}}{
-		CreatedAt: now,
-		UpdatedAt: now,
-	}
-	err := queries.Create(ctx, {{.VarName}})
-	if err != nil {
-		t.Fatalf("Failed to create {{.VarName}}: %v", err)
-	}
+	{{.VarName}} := factories.{{.StructName}}(t, db)
+	original := {{.VarName}}.UpdatedAt
 
 	// Update the {{.VarName}}
-	{{.VarName}}.UpdatedAt = time.Now().Add(time.Hour)
-	err = queries.Update(ctx, {{.VarName}})
+	{{.VarName}}.UpdatedAt = original.Add(time.Hour)
+	err := queries.Update(ctx, {{.VarName}})
 	if err != nil {
 		t.Fatalf("Failed to update {{.VarName}}: %v", err)
 	}
@@ -235,30 +200,20 @@ func Test{{.StructName}}Queries_Update(t *testing.T) {
 		t.Fatalf("Failed to get updated {{.VarName}}: %v", err)
 	}
 
-	if retrieved.UpdatedAt.Equal(now) {
+	if retrieved.UpdatedAt.Equal(original) {
 		t.Error("Expected UpdatedAt to be changed after update")
 	}
 }
 
 func Test{{.StructName}}Queries_Delete(t *testing.T) {
-	queries, cleanup := setup{{.StructName}}Test(t)
+	queries, db, cleanup := setup{{.StructName}}Test(t)
 	defer cleanup()
 
 	ctx := context.Background()
-	now := time.Now()
-	
-	// Create a {{.VarName}} first
-	{{.VarName}} := &{{.StructName}}{
-		CreatedAt: now,
-		UpdatedAt: now,
-	}
-	err := queries.Create(ctx, {{.VarName}})
-	if err != nil {
-		t.Fatalf("Failed to create {{.VarName}}: %v", err)
-	}
+	{{.VarName}} := factories.{{.StructName}}(t, db)
 
 	// Delete the {{.VarName}}
-	err = queries.Delete(ctx, {{.VarName}}.ID)
+	err := queries.Delete(ctx, {{.VarName}}.ID)
 	if err != nil {
 		t.Fatalf("Failed to delete {{.VarName}}: %v", err)
 	}
@@ -271,25 +226,82 @@ func Test{{.StructName}}Queries_Delete(t *testing.T) {
 }
 `
 
+const factoryTemplate = `package factories
+
+import (
+	"context"
+	"testing"
+
+	"{{.ProjectName}}/internal/database/models"
+)
+
+func init() {
+	Register("{{.TableName}}", func(t testing.TB, db models.DBTX) int {
+		return {{.StructName}}(t, db).ID
+	})
+}
+
+// {{.StructName}} creates a {{.StructName}} with fake values, applies overrides and
+// saves it through {{.StructName}}Queries.Create.
+func {{.StructName}}(t testing.TB, db models.DBTX, overrides ...func(*models.{{.StructName}})) *models.{{.StructName}} {
+	t.Helper()
+
+	{{.VarName}} := &models.{{.StructName}}{}
+	Fill(t, db, {{.VarName}})
+	// Overrides run last so they can also set zero values, e.g. Active = false
+	for _, override := range overrides {
+		override({{.VarName}})
+	}
+
+	if err := models.New{{.StructName}}Queries(db).Create(context.Background(), {{.VarName}}); err != nil {
+		t.Fatalf("factories: failed to create {{.StructName}}: %v", err)
+	}
+
+	return {{.VarName}}
+}
+
+// {{.PluralStructName}} creates n {{.PluralVarName}} with the same overrides.
+func {{.PluralStructName}}(t testing.TB, db models.DBTX, n int, overrides ...func(*models.{{.StructName}})) []*models.{{.StructName}} {
+	t.Helper()
+
+	{{.PluralVarName}} := make([]*models.{{.StructName}}, n)
+	for i := range {{.PluralVarName}} {
+		{{.PluralVarName}}[i] = {{.StructName}}(t, db, overrides...)
+	}
+	return {{.PluralVarName}}
+}
+`
+
 type ModelData struct {
-	StructName    string
-	VarName       string
-	PluralVarName string
-	TableName     string
+	StructName       string
+	PluralStructName string
+	VarName          string
+	PluralVarName    string
+	TableName        string
+	ProjectName      string
 }
 
-func GenerateModel(name string) error {
+// GenerateModel creates the model, its tests and factory, and registers its
+// queries in database.go on the named connection (empty for the primary one)
+func GenerateModel(name, database string) error {
 	// Convert name to proper case formats
 	structName := toPascalCase(name)
 	varName := toCamelCase(name)
 	pluralVarName := pluralize(varName)
 	tableName := toSnakeCase(pluralize(name))
 
+	projectName, err := readModuleName()
+	if err != nil {
+		return err
+	}
+
 	data := ModelData{
-		StructName:    structName,
-		VarName:       varName,
-		PluralVarName: pluralVarName,
-		TableName:     tableName,
+		StructName:       structName,
+		PluralStructName: pluralize(structName),
+		VarName:          varName,
+		PluralVarName:    pluralVarName,
+		TableName:        tableName,
+		ProjectName:      projectName,
 	}
 
 	// Create the model file
@@ -335,15 +347,37 @@ func GenerateModel(name string) error {
 		return fmt.Errorf("failed to execute test template: %w", err)
 	}
 
+	// Create the factory file
+	factoryPath := filepath.Join("internal", "database", "factories", fmt.Sprintf("%s.go", name))
+	if err := os.MkdirAll(filepath.Dir(factoryPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	factoryFile, err := os.Create(factoryPath)
+	if err != nil {
+		return fmt.Errorf("failed to create factory file: %w", err)
+	}
+	defer factoryFile.Close()
+
+	// Execute factory template
+	factoryTmpl, err := template.New("factory").Parse(factoryTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse factory template: %w", err)
+	}
+
+	if err := factoryTmpl.Execute(factoryFile, data); err != nil {
+		return fmt.Errorf("failed to execute factory template: %w", err)
+	}
+
 	// Update database.go to include the new model
-	if err := updateDatabaseFile(structName); err != nil {
+	if err := updateDatabaseFile(structName, database); err != nil {
 		return fmt.Errorf("failed to update database.go: %w", err)
 	}
 
 	return nil
 }
 
-func updateDatabaseFile(structName string) error {
+func updateDatabaseFile(structName, database string) error {
 	dbPath := filepath.Join("internal", "database", "database.go")
 	
 	// Read the existing file
@@ -363,16 +397,16 @@ func updateDatabaseFile(structName string) error {
 	
 	// Check if models import exists, add it if it doesn't
 	if !strings.Contains(fileContent, "/internal/database/models") {
-		// Find the utils import line and add models import before it
-		// Extract the project name from the existing utils import
-		re := regexp.MustCompile(`"([^"]+)/internal/utils"`)
+		// Find the import that sorts after models and add models import before it
+		// Extract the project name from that import
+		re := regexp.MustCompile(`"([^"]+)/internal/(tracing|utils)"`)
 		matches := re.FindStringSubmatch(fileContent)
 		if len(matches) > 1 {
 			projectName := matches[1]
-			utilsImport := fmt.Sprintf(`"%s/internal/utils"`, projectName)
+			nextImport := matches[0]
 			modelsImport := fmt.Sprintf(`"%s/internal/database/models"
-	%s`, projectName, utilsImport)
-			fileContent = strings.Replace(fileContent, utilsImport, modelsImport, 1)
+	%s`, projectName, nextImport)
+			fileContent = strings.Replace(fileContent, nextImport, modelsImport, 1)
 		}
 	}
 	
@@ -391,7 +425,11 @@ func updateDatabaseFile(structName string) error {
 	// Add to New() function initialization using markers
 	initStart := "// STEAMBOAT:INIT_START - Auto-generated query initialization"
 	initEnd := "// STEAMBOAT:INIT_END"
-	initAddition := fmt.Sprintf("\t\t%s: models.New%sQueries(db),", varName, structName)
+	connection := "traced[Primary]"
+	if database != "" && database != "primary" {
+		connection = fmt.Sprintf("traced[%q]", strings.ToLower(database))
+	}
+	initAddition := fmt.Sprintf("\t\t%s: models.New%sQueries(%s),", varName, structName, connection)
 	fileContent = addBetweenMarkers(fileContent, initStart, initEnd, initAddition)
 	
 	// Add getter method using markers
@@ -1,10 +1,13 @@
 package generator
 
 import (
+	"bufio"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 )
 
 // CreateProject creates a new Steamboat project from templates
@@ -41,6 +44,11 @@ func CreateProject(projectName string, targetDir string, force bool) error {
 		return fmt.Errorf("failed to copy templates: %w", err)
 	}
 
+	// A missing embed target only shows up as a build error later
+	if err := checkEmbeds(targetDir); err != nil {
+		return err
+	}
+
 	// Initialize go module
 	if err := initGoModule(targetDir, projectName); err != nil {
 		return fmt.Errorf("failed to initialize go module: %w", err)
@@ -77,4 +85,37 @@ func initGoModule(projectDir, projectName string) error {
 	// For now, we skip go mod tidy since the CLI package may not be published yet
 	// Users should run 'go mod tidy' manually after creating the project
 	return nil
-}
\ No newline at end of file
+}
+
+// checkEmbeds makes sure every //go:embed pattern in the project matches a
+// file, so a template directory lost to .gitignore fails here and not in the
+// first build of the project
+func checkEmbeds(projectDir string) error {
+	return filepath.WalkDir(projectDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".go" {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			patterns, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "//go:embed ")
+			if !ok {
+				continue
+			}
+			for _, pattern := range strings.Fields(patterns) {
+				pattern = strings.TrimPrefix(pattern, "all:")
+				matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), filepath.FromSlash(pattern)))
+				if len(matches) == 0 {
+					rel, _ := filepath.Rel(projectDir, path)
+					return fmt.Errorf("template is incomplete: //go:embed %s in %s matches no files", pattern, rel)
+				}
+			}
+		}
+		return scanner.Err()
+	})
+}
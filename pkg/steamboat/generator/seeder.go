@@ -0,0 +1,159 @@
+package generator
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+const seederTemplate = `package seeders
+
+import (
+	"context"
+{{- if .ModelStruct}}
+	"time"
+{{- end}}
+
+	"github.com/jmoiron/sqlx"
+{{- if .ModelStruct}}
+
+	"{{.ProjectName}}/internal/database/models"
+{{- end}}
+)
+
+func init() {
+	Register(Seeder{
+		Name: "{{.Name}}",
+		// Seeders listed here run first, e.g. []string{"users"}
+		DependsOn: []string{},
+{{- if .Database}}
+		Database:  "{{.Database}}",
+{{- end}}
+		Run:       seed{{.FuncName}},
+	})
+}
+
+func seed{{.FuncName}}(ctx context.Context, tx *sqlx.Tx) error {
+{{- if .ModelStruct}}
+	queries := models.New{{.ModelStruct}}Queries(tx)
+	now := time.Now()
+
+	for i := 0; i < 10; i++ {
+		record := &models.{{.ModelStruct}}{
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if err := queries.Create(ctx, record); err != nil {
+			return err
+		}
+	}
+{{- else}}
+	// Insert your data here, e.g. with models.NewUserQueries(tx)
+{{- end}}
+
+	return nil
+}
+`
+
+type SeederData struct {
+	Name        string
+	Database    string
+	FuncName    string
+	ModelStruct string
+	ProjectName string
+}
+
+// GenerateSeeder creates internal/database/seeders/<name>.go for the named
+// database (empty for the primary one). When a model matching the seeder name
+// exists, the seeder creates records through its generated queries.
+func GenerateSeeder(name, database string) (string, error) {
+	seederName := toSnakeCase(name)
+
+	data := SeederData{
+		Name:     seederName,
+		FuncName: toIdentifier(seederName),
+	}
+	if database != "" && database != "primary" {
+		data.Database = strings.ToLower(database)
+	}
+
+	// Look for a model named after the singular form, e.g. "users" -> models/user.go
+	modelName := singularize(seederName)
+	if _, err := os.Stat(filepath.Join("internal", "database", "models", modelName+".go")); err == nil {
+		projectName, err := readModuleName()
+		if err != nil {
+			return "", err
+		}
+		data.ModelStruct = toPascalCase(modelName)
+		data.ProjectName = projectName
+	}
+
+	seederPath := filepath.Join("internal", "database", "seeders", seederName+".go")
+	if _, err := os.Stat(seederPath); err == nil {
+		return "", fmt.Errorf("seeder %s already exists", seederPath)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(seederPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	file, err := os.Create(seederPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	tmpl, err := template.New("seeder").Parse(seederTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	if err := tmpl.Execute(file, data); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	return seederPath, nil
+}
+
+// readModuleName returns the module path declared in the project's go.mod
+func readModuleName() (string, error) {
+	file, err := os.Open("go.mod")
+	if err != nil {
+		return "", fmt.Errorf("failed to read go.mod: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "module ") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "module ")), nil
+		}
+	}
+
+	return "", fmt.Errorf("no module declaration found in go.mod")
+}
+
+// toIdentifier converts snake_case to PascalCase, e.g. "demo_users" -> "DemoUsers"
+func toIdentifier(s string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == '_' || r == '-' }) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+func singularize(s string) string {
+	switch {
+	case strings.HasSuffix(s, "ies"):
+		return s[:len(s)-3] + "y"
+	case strings.HasSuffix(s, "ches"), strings.HasSuffix(s, "xes"), strings.HasSuffix(s, "ses"):
+		return s[:len(s)-2]
+	case strings.HasSuffix(s, "s"):
+		return s[:len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,644 @@
+package migrate
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Change is one difference between the models and the database schema
+type Change struct {
+	Description string
+	// Rebuild is set when SQLite cannot make the change with ALTER TABLE
+	// and the table is recreated instead
+	Rebuild bool
+	// Destructive is set when the change throws data away
+	Destructive bool
+}
+
+// AutoMigration holds the SQL that brings the database schema in line with the models
+type AutoMigration struct {
+	Changes []Change
+	Up      string
+	Down    string
+	// Unknown lists the live tables without a model that were left alone
+	Unknown []string
+}
+
+// block is the SQL for the changes to a single table
+type block struct {
+	changes []Change
+	up      []string
+	down    []string
+}
+
+// Autogenerate compares the models in ModelsPath against the schema of the
+// named database and returns the migration that reconciles them. The database
+// must be fully migrated, otherwise pending migrations would be generated twice.
+// Tables without a model, such as the ones written by hand, are only dropped
+// with dropUnknown.
+func Autogenerate(name string, dropUnknown bool) (*AutoMigration, error) {
+	target, err := LookupDatabase(name)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := readMigrations(target.MigrationsPath)
+	if err != nil {
+		return nil, err
+	}
+	if issues := checkFiles(target.MigrationsPath, migrations); len(issues) > 0 {
+		return nil, &ConflictError{Issues: issues}
+	}
+
+	var live []tableDef
+	if _, err := os.Stat(target.URL); err == nil {
+		current, err := readVersion(target.URL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read database version: %w", err)
+		}
+		if len(migrations) > 0 && migrations[len(migrations)-1].Version > current {
+			return nil, fmt.Errorf("database %s has pending migrations, run `steamboat migrate` first", target.URL)
+		}
+
+		db, err := sql.Open("sqlite3", "file:"+target.URL+"?mode=ro")
+		if err != nil {
+			return nil, fmt.Errorf("failed to open database: %w", err)
+		}
+		defer db.Close()
+
+		if live, err = readTables(db); err != nil {
+			return nil, err
+		}
+	} else if len(migrations) > 0 {
+		return nil, fmt.Errorf("database %s does not exist yet, run `steamboat migrate` first", target.URL)
+	}
+
+	models, err := parseModels(ModelsPath, target.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffTables(models, live, dropUnknown)
+}
+
+// diffTables returns the migration that turns the live tables into the model
+// tables, dropping the live tables without a model when dropUnknown is set
+func diffTables(models, live []tableDef, dropUnknown bool) (*AutoMigration, error) {
+	liveByName := make(map[string]tableDef)
+	for _, t := range live {
+		liveByName[t.Name] = t
+	}
+	modelByName := make(map[string]bool)
+	for _, t := range models {
+		modelByName[t.Name] = true
+	}
+
+	var blocks []block
+
+	// New tables first, referenced tables before the tables that point at them
+	var created []tableDef
+	for _, t := range models {
+		if _, ok := liveByName[t.Name]; !ok {
+			created = append(created, t)
+		}
+	}
+	for _, t := range sortByReferences(created) {
+		blocks = append(blocks, createBlock(t))
+	}
+
+	for _, t := range models {
+		if old, ok := liveByName[t.Name]; ok {
+			b, err := alterBlock(old, t)
+			if err != nil {
+				return nil, err
+			}
+			if len(b.changes) > 0 {
+				blocks = append(blocks, b)
+			}
+		}
+	}
+
+	result := &AutoMigration{}
+	for _, t := range live {
+		if modelByName[t.Name] {
+			continue
+		}
+		if dropUnknown {
+			blocks = append(blocks, dropBlock(t))
+		} else {
+			result.Unknown = append(result.Unknown, t.Name)
+		}
+	}
+
+	var up, down strings.Builder
+	for _, b := range blocks {
+		result.Changes = append(result.Changes, b.changes...)
+		writeBlock(&up, "", b.changes, b.up)
+	}
+	for i := len(blocks) - 1; i >= 0; i-- {
+		writeBlock(&down, "revert ", blocks[i].changes, blocks[i].down)
+	}
+	result.Up = up.String()
+	result.Down = down.String()
+
+	return result, nil
+}
+
+func writeBlock(b *strings.Builder, prefix string, changes []Change, statements []string) {
+	for _, change := range changes {
+		fmt.Fprintf(b, "-- %s%s\n", prefix, change.Description)
+	}
+	for _, statement := range statements {
+		b.WriteString(statement + ";\n")
+	}
+	b.WriteString("\n")
+}
+
+func createBlock(t tableDef) block {
+	b := block{
+		changes: []Change{{Description: "create table " + t.Name}},
+		up:      []string{createTableSQL(t)},
+		down:    []string{"DROP TABLE " + ident(t.Name)},
+	}
+	for _, idx := range t.Indexes {
+		b.up = append(b.up, createIndexSQL(t.Name, idx))
+	}
+	return b
+}
+
+func dropBlock(t tableDef) block {
+	b := block{
+		changes: []Change{{Description: "drop table " + t.Name + " (no model)", Destructive: true}},
+		up:      []string{"DROP TABLE " + ident(t.Name)},
+		down:    []string{t.SQL},
+	}
+	for _, idx := range t.Indexes {
+		b.down = append(b.down, idx.SQL)
+	}
+	b.down = append(b.down, t.Triggers...)
+	return b
+}
+
+// alterBlock changes old into t with ALTER TABLE where SQLite allows it, and
+// with a table rebuild where it does not
+func alterBlock(old, t tableDef) (block, error) {
+	var b block
+	var reasons []string
+	var added, dropped []columnDef
+
+	for _, c := range t.Columns {
+		oc, ok := old.column(c.Name)
+		if !ok {
+			added = append(added, c)
+			if problem := addProblem(c); problem != "" {
+				reasons = append(reasons, fmt.Sprintf("add column %s (%s)", c.Name, problem))
+			}
+			continue
+		}
+
+		if affinity(c.Type) != affinity(oc.Type) {
+			reasons = append(reasons, fmt.Sprintf("change type of %s from %s to %s", c.Name, oc.Type, c.Type))
+		}
+		if c.PrimaryKey != oc.PrimaryKey {
+			reasons = append(reasons, fmt.Sprintf("change primary key of %s", c.Name))
+		} else if c.NotNull != oc.NotNull && !c.PrimaryKey {
+			if c.NotNull {
+				reasons = append(reasons, fmt.Sprintf("make %s NOT NULL", c.Name))
+			} else {
+				reasons = append(reasons, fmt.Sprintf("make %s nullable", c.Name))
+			}
+		}
+		if c.References != oc.References {
+			reasons = append(reasons, fmt.Sprintf("change foreign key of %s", c.Name))
+		}
+	}
+
+	for _, oc := range old.Columns {
+		if _, ok := t.column(oc.Name); !ok {
+			dropped = append(dropped, oc)
+			if problem := dropProblem(old, oc); problem != "" {
+				reasons = append(reasons, fmt.Sprintf("drop column %s (%s)", oc.Name, problem))
+			}
+		}
+	}
+
+	var addedIndexes, droppedIndexes []indexDef
+	for _, idx := range t.Indexes {
+		if oi, ok := old.index(idx.Name); !ok || !sameIndex(oi, idx) {
+			addedIndexes = append(addedIndexes, idx)
+		}
+	}
+	for _, oi := range old.Indexes {
+		if idx, ok := t.index(oi.Name); !ok || !sameIndex(oi, idx) {
+			droppedIndexes = append(droppedIndexes, oi)
+		}
+	}
+
+	if len(reasons) > 0 {
+		// The rebuild also carries the changes ALTER TABLE could have made
+		for _, c := range added {
+			if addProblem(c) == "" {
+				reasons = append(reasons, "add column "+c.Name)
+			}
+		}
+		for _, c := range dropped {
+			if dropProblem(old, c) == "" {
+				reasons = append(reasons, "drop column "+c.Name)
+			}
+		}
+		for _, idx := range addedIndexes {
+			reasons = append(reasons, "create index "+idx.Name)
+		}
+		for _, oi := range droppedIndexes {
+			reasons = append(reasons, "drop index "+oi.Name)
+		}
+
+		up, err := rebuildTo(old, t, createTableSQL(t), t.indexSQL(), old.Triggers, old.Views)
+		if err != nil {
+			return b, err
+		}
+		down, err := rebuildTo(t, old, old.SQL, old.indexSQL(), old.Triggers, old.Views)
+		if err != nil {
+			return b, err
+		}
+
+		b.changes = append(b.changes, Change{
+			Description: fmt.Sprintf("rebuild table %s: %s", t.Name, strings.Join(reasons, ", ")),
+			Rebuild:     true,
+			Destructive: len(dropped) > 0,
+		})
+		b.up = up
+		b.down = down
+		return b, nil
+	}
+
+	for _, oi := range droppedIndexes {
+		b.changes = append(b.changes, Change{Description: "drop index " + oi.Name})
+		b.up = append(b.up, "DROP INDEX "+ident(oi.Name))
+	}
+	for _, c := range added {
+		b.changes = append(b.changes, Change{Description: fmt.Sprintf("add column %s.%s", t.Name, c.Name)})
+		b.up = append(b.up, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", ident(t.Name), columnSQL(c)))
+	}
+	for _, c := range dropped {
+		b.changes = append(b.changes, Change{Description: fmt.Sprintf("drop column %s.%s", t.Name, c.Name), Destructive: true})
+		b.up = append(b.up, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", ident(t.Name), ident(c.Name)))
+	}
+	for _, idx := range addedIndexes {
+		b.changes = append(b.changes, Change{Description: "create index " + idx.Name})
+		b.up = append(b.up, createIndexSQL(t.Name, idx))
+	}
+
+	// Dropped columns are restored with a rebuild, which keeps the original
+	// definition and column order that ADD COLUMN could not
+	if len(dropped) > 0 {
+		down, err := rebuildTo(t, old, old.SQL, old.indexSQL(), old.Triggers, old.Views)
+		if err != nil {
+			return b, err
+		}
+		b.down = down
+		return b, nil
+	}
+
+	for _, idx := range addedIndexes {
+		b.down = append(b.down, "DROP INDEX IF EXISTS "+ident(idx.Name))
+	}
+	for _, c := range added {
+		b.down = append(b.down, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", ident(t.Name), ident(c.Name)))
+	}
+	for _, oi := range droppedIndexes {
+		b.down = append(b.down, oi.SQL)
+	}
+
+	return b, nil
+}
+
+// rebuildTo returns the statements that rebuild from into the shape of to,
+// copying the columns they share
+func rebuildTo(from, to tableDef, createSQL string, indexes, triggers []string, views []viewDef) ([]string, error) {
+	var columns, exprs []string
+	for _, c := range to.Columns {
+		fc, ok := from.column(c.Name)
+		if !ok {
+			continue
+		}
+		columns = append(columns, c.Name)
+		if c.NotNull && !fc.NotNull && c.Default != "" {
+			exprs = append(exprs, fmt.Sprintf("COALESCE(%s, %s)", ident(c.Name), c.Default))
+		} else {
+			exprs = append(exprs, ident(c.Name))
+		}
+	}
+
+	return rebuildStatements(to.Name, createSQL, columns, exprs, append(indexes, triggers...), views)
+}
+
+// addProblem explains why ALTER TABLE ADD COLUMN cannot add c, or returns ""
+func addProblem(c columnDef) string {
+	switch {
+	case c.PrimaryKey:
+		return "primary key"
+	case c.NotNull && c.Default == "":
+		return "NOT NULL without a default"
+	case c.References != "" && c.Default != "" && !strings.EqualFold(c.Default, "NULL"):
+		return "foreign key with a non-NULL default"
+	}
+
+	switch upper := strings.ToUpper(c.Default); {
+	case upper == "CURRENT_TIMESTAMP", upper == "CURRENT_DATE", upper == "CURRENT_TIME", strings.HasPrefix(upper, "("):
+		return "non-constant default"
+	}
+	return ""
+}
+
+// dropProblem explains why ALTER TABLE DROP COLUMN cannot drop c from t, or returns ""
+func dropProblem(t tableDef, c columnDef) string {
+	switch {
+	case c.PrimaryKey:
+		return "primary key"
+	case c.References != "":
+		return "foreign key"
+	case t.constrained[c.Name]:
+		return "UNIQUE constraint"
+	}
+	return ""
+}
+
+func sameIndex(a, b indexDef) bool {
+	return a.Unique == b.Unique && strings.Join(a.Columns, ",") == strings.Join(b.Columns, ",")
+}
+
+// sortByReferences orders tables so referenced tables come before the tables referencing them
+func sortByReferences(tables []tableDef) []tableDef {
+	byName := make(map[string]tableDef)
+	for _, t := range tables {
+		byName[t.Name] = t
+	}
+
+	var sorted []tableDef
+	visited := make(map[string]bool)
+	var visit func(t tableDef)
+	visit = func(t tableDef) {
+		if visited[t.Name] {
+			return
+		}
+		visited[t.Name] = true
+		for _, c := range t.Columns {
+			if ref, ok := byName[c.References]; ok {
+				visit(ref)
+			}
+		}
+		sorted = append(sorted, t)
+	}
+	for _, t := range tables {
+		visit(t)
+	}
+	return sorted
+}
+
+// affinity returns the SQLite type affinity of a declared column type
+// (https://www.sqlite.org/datatype3.html#determination_of_column_affinity)
+func affinity(declared string) string {
+	t := strings.ToUpper(declared)
+	switch {
+	case strings.Contains(t, "INT"):
+		return "INTEGER"
+	case strings.Contains(t, "CHAR"), strings.Contains(t, "CLOB"), strings.Contains(t, "TEXT"):
+		return "TEXT"
+	case t == "", strings.Contains(t, "BLOB"):
+		return "BLOB"
+	case strings.Contains(t, "REAL"), strings.Contains(t, "FLOA"), strings.Contains(t, "DOUB"):
+		return "REAL"
+	}
+	return "NUMERIC"
+}
+
+func columnSQL(c columnDef) string {
+	parts := []string{ident(c.Name), c.Type}
+	switch {
+	case c.PrimaryKey && c.Type == "INTEGER":
+		parts = append(parts, "PRIMARY KEY AUTOINCREMENT")
+	case c.PrimaryKey:
+		parts = append(parts, "PRIMARY KEY NOT NULL")
+	case c.NotNull:
+		parts = append(parts, "NOT NULL")
+	}
+	if c.Default != "" {
+		parts = append(parts, "DEFAULT "+c.Default)
+	}
+	if c.References != "" {
+		parts = append(parts, fmt.Sprintf("REFERENCES %s (id)", ident(c.References)))
+	}
+	return strings.Join(parts, " ")
+}
+
+func createTableSQL(t tableDef) string {
+	columns := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		columns[i] = "    " + columnSQL(c)
+	}
+	return fmt.Sprintf("CREATE TABLE %s (\n%s\n)", ident(t.Name), strings.Join(columns, ",\n"))
+}
+
+func createIndexSQL(table string, idx indexDef) string {
+	columns := make([]string, len(idx.Columns))
+	for i, c := range idx.Columns {
+		columns[i] = ident(c)
+	}
+	unique := ""
+	if idx.Unique {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)", unique, ident(idx.Name), ident(table), strings.Join(columns, ", "))
+}
+
+// indexSQL returns the CREATE INDEX statements of t, using the original SQL of live indexes
+func (t *tableDef) indexSQL() []string {
+	statements := make([]string, len(t.Indexes))
+	for i, idx := range t.Indexes {
+		if idx.SQL != "" {
+			statements[i] = idx.SQL
+		} else {
+			statements[i] = createIndexSQL(t.Name, idx)
+		}
+	}
+	return statements
+}
+
+// readTables reads the user tables of db with their columns, indexes and
+// triggers. Virtual tables, such as FTS indexes, and the shadow tables that
+// hold their data are left out: they cannot be altered like plain tables.
+func readTables(db querier) ([]tableDef, error) {
+	var tables []tableDef
+	rows, err := db.Query(`SELECT m.name, m.sql FROM sqlite_master m
+		JOIN pragma_table_list l ON l.schema = 'main' AND l.name = m.name
+		WHERE m.type = 'table' AND l.type = 'table' AND m.name NOT LIKE 'sqlite_%'
+			AND m.name NOT IN ('schema_migrations', '` + historyTable + `')
+		ORDER BY m.name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	for rows.Next() {
+		var t tableDef
+		if err := rows.Scan(&t.Name, &t.SQL); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to read schema: %w", err)
+		}
+		tables = append(tables, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	for i := range tables {
+		if err := readTable(db, &tables[i]); err != nil {
+			return nil, fmt.Errorf("failed to read table %s: %w", tables[i].Name, err)
+		}
+	}
+
+	return tables, nil
+}
+
+func readTable(db querier, t *tableDef) error {
+	name := quoteIdentifier(t.Name)
+
+	rows, err := db.Query("PRAGMA table_info(" + name + ")")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var cid, notNull, pk int
+		var c columnDef
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &c.Name, &c.Type, &notNull, &dflt, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		c.Type = strings.ToUpper(c.Type)
+		c.NotNull = notNull == 1 || pk > 0
+		c.PrimaryKey = pk > 0
+		c.Default = dflt.String
+		t.Columns = append(t.Columns, c)
+	}
+	rows.Close()
+
+	rows, err = db.Query("PRAGMA foreign_key_list(" + name + ")")
+	if err != nil {
+		return err
+	}
+	references := make(map[string]string)
+	for rows.Next() {
+		var id, seq int
+		var table, from string
+		var to, onUpdate, onDelete, match sql.NullString
+		if err := rows.Scan(&id, &seq, &table, &from, &to, &onUpdate, &onDelete, &match); err != nil {
+			rows.Close()
+			return err
+		}
+		references[from] = table
+	}
+	rows.Close()
+	for i := range t.Columns {
+		t.Columns[i].References = references[t.Columns[i].Name]
+	}
+
+	rows, err = db.Query("PRAGMA index_list(" + name + ")")
+	if err != nil {
+		return err
+	}
+	type listedIndex struct {
+		name    string
+		unique  bool
+		origin  string
+		partial bool
+	}
+	var listed []listedIndex
+	for rows.Next() {
+		var seq, unique, partial int
+		var idx listedIndex
+		if err := rows.Scan(&seq, &idx.name, &unique, &idx.origin, &partial); err != nil {
+			rows.Close()
+			return err
+		}
+		idx.unique = unique == 1
+		idx.partial = partial == 1
+		listed = append(listed, idx)
+	}
+	rows.Close()
+
+	t.constrained = make(map[string]bool)
+	for _, l := range listed {
+		columns, err := indexColumns(db, l.name)
+		if err != nil {
+			return err
+		}
+
+		// Indexes behind UNIQUE and PRIMARY KEY constraints belong to the table definition
+		if l.origin != "c" {
+			for _, c := range columns {
+				t.constrained[c] = true
+			}
+			continue
+		}
+
+		idx := indexDef{Name: l.name, Unique: l.unique, Partial: l.partial, Columns: columns}
+		if err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'index' AND name = ?`, l.name).Scan(&idx.SQL); err != nil {
+			return err
+		}
+		t.Indexes = append(t.Indexes, idx)
+	}
+	sort.Slice(t.Indexes, func(i, j int) bool { return t.Indexes[i].Name < t.Indexes[j].Name })
+
+	rows, err = db.Query(`SELECT sql FROM sqlite_master WHERE type = 'trigger' AND tbl_name = ? ORDER BY name`, t.Name)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var trigger string
+		if err := rows.Scan(&trigger); err != nil {
+			rows.Close()
+			return err
+		}
+		t.Triggers = append(t.Triggers, trigger)
+	}
+	rows.Close()
+
+	// Views are matched by name, a false positive only means a view is recreated unchanged
+	rows, err = db.Query(`SELECT name, sql FROM sqlite_master WHERE type = 'view' AND sql LIKE '%' || ? || '%' ORDER BY rowid`, t.Name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var view viewDef
+		if err := rows.Scan(&view.Name, &view.SQL); err != nil {
+			return err
+		}
+		t.Views = append(t.Views, view)
+	}
+
+	return rows.Err()
+}
+
+func indexColumns(db querier, index string) ([]string, error) {
+	rows, err := db.Query("PRAGMA index_info(" + quoteIdentifier(index) + ")")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var seqno, cid int
+		var name sql.NullString
+		if err := rows.Scan(&seqno, &cid, &name); err != nil {
+			return nil, err
+		}
+		columns = append(columns, name.String)
+	}
+	return columns, rows.Err()
+}
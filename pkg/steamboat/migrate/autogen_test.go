@@ -0,0 +1,208 @@
+package migrate
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// applyAuto writes auto as the migration with the given version and runs it
+func applyAuto(t *testing.T, version int, auto *AutoMigration) {
+	writeMigration(t, fmt.Sprintf("%06d_auto.up.sql", version), auto.Up)
+	writeMigration(t, fmt.Sprintf("%06d_auto.down.sql", version), auto.Down)
+	if err := Run(""); err != nil {
+		t.Fatalf("Failed to run the generated migration: %v\n%s", err, auto.Up)
+	}
+}
+
+func describeChanges(auto *AutoMigration) string {
+	var descriptions []string
+	for _, change := range auto.Changes {
+		descriptions = append(descriptions, change.Description)
+	}
+	return strings.Join(descriptions, "; ")
+}
+
+func openDB(t *testing.T, path string) *sql.DB {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestAutogenerateCreatesReferencedTablesFirst(t *testing.T) {
+	setupProject(t, nil)
+	writeModels(t, "", map[string]string{
+		"post.go": "type Post struct {\n\tID     int64 `db:\"id\"`\n\tUserID int64 `db:\"user_id,references=users,index\"`\n}\n",
+		"user.go": "type User struct {\n\tID    int64  `db:\"id\"`\n\tEmail string `db:\"email,unique\"`\n}\n",
+	})
+
+	auto, err := Autogenerate("", false)
+	if err != nil {
+		t.Fatalf("Autogenerate failed: %v", err)
+	}
+	if got := describeChanges(auto); got != "create table users; create table posts" {
+		t.Fatalf("Expected users before posts, got %q", got)
+	}
+	if !strings.Contains(auto.Up, `user_id INTEGER NOT NULL REFERENCES users (id)`) {
+		t.Errorf("Expected a foreign key on posts.user_id, got:\n%s", auto.Up)
+	}
+
+	applyAuto(t, 1, auto)
+	if again, err := Autogenerate("", false); err != nil || len(again.Changes) > 0 {
+		t.Errorf("Expected no changes after migrating, got %q (%v)", describeChanges(again), err)
+	}
+}
+
+func TestAutogenerateAltersInPlace(t *testing.T) {
+	dbPath := setupProject(t, map[string]string{
+		"000001_create_users.up.sql": `CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, email TEXT NOT NULL DEFAULT '', legacy TEXT);
+CREATE INDEX idx_users_legacy ON users (legacy);
+INSERT INTO users (email, legacy) VALUES ('a@example.com', 'x');`,
+		"000001_create_users.down.sql": `DROP TABLE users;`,
+	})
+	if err := Run(""); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	before := schemaOf(t, dbPath)
+
+	writeModels(t, "", map[string]string{
+		"user.go": "type User struct {\n\tID       int64   `db:\"id\"`\n\tEmail    string  `db:\"email,unique\"`\n\tNickname *string `db:\"nickname\"`\n}\n",
+	})
+	auto, err := Autogenerate("", false)
+	if err != nil {
+		t.Fatalf("Autogenerate failed: %v", err)
+	}
+
+	want := "drop index idx_users_legacy; add column users.nickname; drop column users.legacy; create index idx_users_email"
+	if got := describeChanges(auto); got != want {
+		t.Fatalf("Expected %q, got %q", want, got)
+	}
+	for _, change := range auto.Changes {
+		if change.Rebuild || change.Destructive != strings.HasPrefix(change.Description, "drop column") {
+			t.Errorf("Unexpected flags on %+v", change)
+		}
+	}
+
+	applyAuto(t, 2, auto)
+	var email string
+	if err := openDB(t, dbPath).QueryRow(`SELECT email FROM users`).Scan(&email); err != nil || email != "a@example.com" {
+		t.Errorf("Expected the row to survive, got %q (%v)", email, err)
+	}
+
+	// The down migration restores the dropped column with its original definition
+	if err := Rollback(""); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if diffs := diffSchema(before, schemaOf(t, dbPath)); len(diffs) > 0 {
+		t.Errorf("Expected the rollback to restore the schema, got %v", diffs)
+	}
+}
+
+func TestAutogenerateRebuildsForNotNull(t *testing.T) {
+	dbPath := setupProject(t, map[string]string{
+		"000001_create_users.up.sql": `CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, age TEXT);
+CREATE TRIGGER users_name AFTER UPDATE OF name ON users BEGIN SELECT 1; END;
+INSERT INTO users (name, age) VALUES (NULL, '42');`,
+		"000001_create_users.down.sql": `DROP TABLE users;`,
+	})
+	if err := Run(""); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	before := schemaOf(t, dbPath)
+
+	// SQLite can neither add NOT NULL nor change a type in place
+	writeModels(t, "", map[string]string{
+		"user.go": "type User struct {\n\tID   int64  `db:\"id\"`\n\tName string `db:\"name\"`\n\tAge  *int64 `db:\"age\"`\n}\n",
+	})
+	auto, err := Autogenerate("", false)
+	if err != nil {
+		t.Fatalf("Autogenerate failed: %v", err)
+	}
+	if len(auto.Changes) != 1 || !auto.Changes[0].Rebuild ||
+		auto.Changes[0].Description != "rebuild table users: make name NOT NULL, change type of age from TEXT to INTEGER" {
+		t.Fatalf("Expected a single rebuild, got %+v", auto.Changes)
+	}
+
+	applyAuto(t, 2, auto)
+	var name string
+	var age int64
+	if err := openDB(t, dbPath).QueryRow(`SELECT name, age FROM users`).Scan(&name, &age); err != nil || name != "" || age != 42 {
+		t.Errorf("Expected the NULL name to get the default and age to convert, got %q, %d (%v)", name, age, err)
+	}
+	if _, ok := schemaOf(t, dbPath)["trigger users_name"]; !ok {
+		t.Error("Expected the trigger to survive the rebuild")
+	}
+
+	if err := Rollback(""); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if diffs := diffSchema(before, schemaOf(t, dbPath)); len(diffs) > 0 {
+		t.Errorf("Expected the rollback to restore the schema, got %v", diffs)
+	}
+}
+
+func TestAutogenerateRequiresMigratedDatabase(t *testing.T) {
+	dbPath := setupProject(t, map[string]string{
+		"000001_create_users.up.sql":   `CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT);`,
+		"000001_create_users.down.sql": `DROP TABLE users;`,
+	})
+	writeModels(t, "", map[string]string{"user.go": "type User struct {\n\tID int64 `db:\"id\"`\n}\n"})
+
+	if _, err := Autogenerate("", false); err == nil || !strings.Contains(err.Error(), "does not exist yet") {
+		t.Errorf("Expected a missing database to be refused, got %v", err)
+	}
+
+	if err := Run(""); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	writeMigration(t, "000002_add_email.up.sql", `ALTER TABLE users ADD COLUMN email TEXT;`)
+	writeMigration(t, "000002_add_email.down.sql", `ALTER TABLE users DROP COLUMN email;`)
+	if _, err := Autogenerate("", false); err == nil || err.Error() != "database "+dbPath+" has pending migrations, run `steamboat migrate` first" {
+		t.Errorf("Expected pending migrations to be refused, got %v", err)
+	}
+}
+
+func TestAutogenerateLeavesUnknownTables(t *testing.T) {
+	dbPath := setupProject(t, map[string]string{
+		"000001_create_users.up.sql": `CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT);
+CREATE TABLE audit_log (id INTEGER PRIMARY KEY, entry TEXT);
+CREATE INDEX idx_audit_log_entry ON audit_log (entry);
+CREATE VIRTUAL TABLE search USING fts4(body);`,
+		"000001_create_users.down.sql": `DROP TABLE search; DROP TABLE audit_log; DROP TABLE users;`,
+	})
+	if err := Run(""); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	writeModels(t, "", map[string]string{"user.go": "type User struct {\n\tID int64 `db:\"id\"`\n}\n"})
+
+	// The FTS table and its search_content, search_segments, ... shadow tables are never touched
+	auto, err := Autogenerate("", false)
+	if err != nil {
+		t.Fatalf("Autogenerate failed: %v", err)
+	}
+	if len(auto.Changes) > 0 || strings.Join(auto.Unknown, " ") != "audit_log" {
+		t.Fatalf("Expected audit_log to be reported and left alone, got %q and unknown %v", describeChanges(auto), auto.Unknown)
+	}
+
+	auto, err = Autogenerate("", true)
+	if err != nil {
+		t.Fatalf("Autogenerate failed: %v", err)
+	}
+	if got := describeChanges(auto); got != "drop table audit_log (no model)" || len(auto.Unknown) > 0 {
+		t.Fatalf("Expected only audit_log to be dropped, got %q", got)
+	}
+	before := schemaOf(t, dbPath)
+	applyAuto(t, 2, auto)
+
+	// The down migration brings the table back with its index
+	if err := Rollback(""); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if diffs := diffSchema(before, schemaOf(t, dbPath)); len(diffs) > 0 {
+		t.Errorf("Expected the rollback to restore audit_log, got %v", diffs)
+	}
+}
@@ -0,0 +1,70 @@
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PrimaryDatabase is the name of the database configured by DB_URL
+const PrimaryDatabase = "primary"
+
+// Database is a named database connection and the directory of its migrations.
+//
+// The primary database reads DB_URL and DB_MIGRATIONS (default
+// internal/database/migrations). A database called analytics reads
+// DB_ANALYTICS_URL and DB_ANALYTICS_MIGRATIONS (default
+// internal/database/migrations/analytics).
+type Database struct {
+	Name           string
+	URL            string
+	MigrationsPath string
+}
+
+// LookupDatabase resolves a database by name from the environment. An empty
+// name selects the primary database.
+func LookupDatabase(name string) (Database, error) {
+	name = strings.ToLower(name)
+	if name == "" {
+		name = PrimaryDatabase
+	}
+
+	db := Database{
+		Name:           name,
+		URL:            os.Getenv(urlVar(name)),
+		MigrationsPath: os.Getenv(migrationsVar(name)),
+	}
+
+	if db.MigrationsPath == "" {
+		db.MigrationsPath = MigrationsPath(name)
+	}
+
+	if db.URL == "" {
+		return db, fmt.Errorf("%s environment variable is not set", urlVar(name))
+	}
+
+	return db, nil
+}
+
+// MigrationsPath returns the default migrations directory of a database
+func MigrationsPath(name string) string {
+	if name == "" || name == PrimaryDatabase {
+		return migrationsPath
+	}
+	return filepath.Join(migrationsPath, strings.ToLower(name))
+}
+
+func urlVar(name string) string {
+	if name == PrimaryDatabase {
+		return "DB_URL"
+	}
+	return "DB_" + strings.ToUpper(name) + "_URL"
+}
+
+func migrationsVar(name string) string {
+	if name == PrimaryDatabase {
+		return "DB_MIGRATIONS"
+	}
+	return "DB_" + strings.ToUpper(name) + "_MIGRATIONS"
+}
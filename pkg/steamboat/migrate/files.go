@@ -0,0 +1,245 @@
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// migrationFilePattern matches golang-migrate file names, e.g. "000001_create_users.up.sql"
+// or "20261018153000_add_email.down.sql"
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration describes one versioned migration on disk
+type Migration struct {
+	ID       string // file name without direction and extension, e.g. "000001_create_users"
+	Version  uint
+	Name     string
+	UpPath   string
+	DownPath string
+}
+
+// readMigrations lists the migrations in dir ordered by version. Files that
+// share a version but not a name are returned as separate entries so that
+// checkFiles can report them.
+func readMigrations(dir string) ([]Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	byID := make(map[string]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		matches := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(matches[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		id := matches[1] + "_" + matches[2]
+		m, ok := byID[id]
+		if !ok {
+			m = &Migration{ID: id, Version: uint(version), Name: matches[2]}
+			byID[id] = m
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		if matches[3] == "up" {
+			m.UpPath = path
+		} else {
+			m.DownPath = path
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byID))
+	for _, m := range byID {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		if migrations[i].Version != migrations[j].Version {
+			return migrations[i].Version < migrations[j].Version
+		}
+		return migrations[i].ID < migrations[j].ID
+	})
+
+	return migrations, nil
+}
+
+// Issue describes a problem with the migration files or their applied state
+type Issue struct {
+	Problem string
+	Fix     string
+}
+
+// ConflictError is returned when migrations cannot be run safely
+type ConflictError struct {
+	Issues []Issue
+}
+
+func (e *ConflictError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "found %d migration problem(s):", len(e.Issues))
+	for _, issue := range e.Issues {
+		fmt.Fprintf(&b, "\n  - %s\n    fix: %s", issue.Problem, issue.Fix)
+	}
+	return b.String()
+}
+
+// checkFiles reports duplicate versions and incomplete up/down pairs
+func checkFiles(dir string, migrations []Migration) []Issue {
+	var issues []Issue
+
+	byVersion := make(map[uint][]Migration)
+	var maxVersion uint
+	for _, m := range migrations {
+		byVersion[m.Version] = append(byVersion[m.Version], m)
+		if m.Version > maxVersion {
+			maxVersion = m.Version
+		}
+	}
+
+	for _, m := range migrations {
+		switch {
+		case m.UpPath == "":
+			issues = append(issues, Issue{
+				Problem: fmt.Sprintf("%s has a down file but no up file", m.ID),
+				Fix:     fmt.Sprintf("create %s", filepath.Join(dir, m.ID+".up.sql")),
+			})
+		case m.DownPath == "":
+			issues = append(issues, Issue{
+				Problem: fmt.Sprintf("%s has an up file but no down file", m.ID),
+				Fix:     fmt.Sprintf("create %s", filepath.Join(dir, m.ID+".down.sql")),
+			})
+		}
+	}
+
+	versions := make([]uint, 0, len(byVersion))
+	for version := range byVersion {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+
+	next := maxVersion
+	for _, version := range versions {
+		group := byVersion[version]
+		if len(group) < 2 {
+			continue
+		}
+
+		ids := make([]string, len(group))
+		for i, m := range group {
+			ids[i] = m.ID
+		}
+
+		// Every migration after the first needs a fresh version
+		var renames []string
+		for _, m := range group[1:] {
+			next++
+			renames = append(renames, fmt.Sprintf("%s -> %s", m.ID, renameVersion(m, next)))
+		}
+
+		issues = append(issues, Issue{
+			Problem: fmt.Sprintf("version %d is used by %s", version, strings.Join(ids, ", ")),
+			Fix: fmt.Sprintf("rename %s (or recreate them with `steamboat make migration --timestamp`)",
+				strings.Join(renames, ", ")),
+		})
+	}
+
+	return issues
+}
+
+// checkApplied reports migrations below the current version that were never
+// applied, and databases stuck at a version that no longer exists on disk.
+// Migrations below the start of the history are left to checkUnknown.
+func checkApplied(dir string, migrations []Migration, current uint, applied map[uint]bool) []Issue {
+	var issues []Issue
+
+	if current > 0 && !hasVersion(migrations, current) {
+		issue := Issue{
+			Problem: fmt.Sprintf("the database is at version %d, but no migration with that version exists in %s", current, dir),
+			Fix:     "restore the missing migration files, or rebuild the database with `steamboat migrate fresh`",
+		}
+		for _, m := range migrations {
+			if m.Name == baselineName && m.Version > current {
+				issue.Problem = fmt.Sprintf("the database is at version %d, which was squashed into %s", current, m.ID)
+				issue.Fix = fmt.Sprintf("migrate it to version %d with the migration files from before the squash, "+
+					"or rebuild it with `steamboat migrate fresh`", m.Version)
+				break
+			}
+		}
+		return append(issues, issue)
+	}
+
+	next := current
+	if len(migrations) > 0 && migrations[len(migrations)-1].Version > next {
+		next = migrations[len(migrations)-1].Version
+	}
+
+	start := historyStart(applied, current)
+	for _, m := range migrations {
+		if m.Version >= current || m.Version < start || applied[m.Version] {
+			continue
+		}
+
+		next++
+		issues = append(issues, Issue{
+			Problem: fmt.Sprintf("%s has not been applied but the database is already at version %d", m.ID, current),
+			Fix: fmt.Sprintf("rename it to %s in %s so it runs after the applied migrations",
+				renameVersion(m, next), dir),
+		})
+	}
+
+	return issues
+}
+
+// checkUnknown reports migrations below the start of the history, which the
+// database moved past before the history was kept. Whether they were applied
+// cannot be told, so they only warrant a warning.
+func checkUnknown(migrations []Migration, current uint, applied map[uint]bool) []Issue {
+	start := historyStart(applied, current)
+	var unknown []string
+	for _, m := range migrations {
+		if m.Version < start && !applied[m.Version] {
+			unknown = append(unknown, m.ID)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	return []Issue{{
+		Problem: fmt.Sprintf("the migration history of this database starts at version %d, so whether %s ran is unknown",
+			start, strings.Join(unknown, ", ")),
+		Fix: fmt.Sprintf("nothing if they ran before then; a migration merged in since needs a version above %d to run", current),
+	}}
+}
+
+// renameVersion formats m's ID with a new version, keeping the width of the original
+func renameVersion(m Migration, version uint) string {
+	width := len(m.ID) - len(m.Name) - 1
+	return fmt.Sprintf("%0*d_%s", width, version, m.Name)
+}
+
+func hasVersion(migrations []Migration, version uint) bool {
+	for _, m := range migrations {
+		if m.Version == version {
+			return true
+		}
+	}
+	return false
+}
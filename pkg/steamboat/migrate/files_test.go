@@ -0,0 +1,130 @@
+package migrate
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadMigrationsPairsFiles(t *testing.T) {
+	setupProject(t, map[string]string{
+		"20261018153000_add_email.up.sql":   ``,
+		"20261018153000_add_email.down.sql": ``,
+		"000001_create_users.up.sql":        ``,
+		"000001_create_users.down.sql":      ``,
+		"000002_add_roles.up.sql":           ``,
+		"notes.md":                          ``,
+		"000003_draft.sql":                  ``,
+	})
+
+	migrations, err := readMigrations(migrationsPath)
+	if err != nil {
+		t.Fatalf("readMigrations failed: %v", err)
+	}
+
+	var ids []string
+	for _, m := range migrations {
+		ids = append(ids, m.ID)
+	}
+	if strings.Join(ids, " ") != "000001_create_users 000002_add_roles 20261018153000_add_email" {
+		t.Fatalf("Expected sequential and timestamp migrations in version order, got %v", ids)
+	}
+	if m := migrations[1]; m.UpPath == "" || m.DownPath != "" {
+		t.Errorf("Expected 000002_add_roles to have only an up file, got %+v", m)
+	}
+	if m := migrations[2]; m.Version != 20261018153000 || m.Name != "add_email" {
+		t.Errorf("Unexpected timestamp migration: %+v", m)
+	}
+}
+
+func TestCheckFilesDuplicateVersions(t *testing.T) {
+	setupProject(t, map[string]string{
+		"000002_add_email.up.sql":    ``,
+		"000002_add_email.down.sql":  ``,
+		"000002_add_posts.up.sql":    ``,
+		"000002_add_posts.down.sql":  ``,
+		"000002_add_tags.up.sql":     ``,
+		"000002_add_tags.down.sql":   ``,
+		"000003_add_avatar.up.sql":   ``,
+		"000003_add_avatar.down.sql": ``,
+		"000004_add_roles.down.sql":  ``,
+	})
+	migrations, err := readMigrations(migrationsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issues := checkFiles(migrationsPath, migrations)
+	if len(issues) != 2 {
+		t.Fatalf("Expected a missing up file and a duplicate version, got %+v", issues)
+	}
+	if issues[0].Problem != "000004_add_roles has a down file but no up file" || issues[0].Fix != "create "+filepath.Join(migrationsPath, "000004_add_roles.up.sql") {
+		t.Errorf("Unexpected missing file issue: %+v", issues[0])
+	}
+
+	// The renames continue after the highest version, not after the duplicate
+	if issues[1].Problem != "version 2 is used by 000002_add_email, 000002_add_posts, 000002_add_tags" ||
+		!strings.HasPrefix(issues[1].Fix, "rename 000002_add_posts -> 000005_add_posts, 000002_add_tags -> 000006_add_tags") {
+		t.Errorf("Unexpected duplicate version issue: %+v", issues[1])
+	}
+}
+
+func TestRenameVersionKeepsWidth(t *testing.T) {
+	for _, m := range []struct {
+		migration Migration
+		version   uint
+		want      string
+	}{
+		{Migration{ID: "000002_add_posts", Name: "add_posts"}, 7, "000007_add_posts"},
+		{Migration{ID: "20261018153000_add_posts", Name: "add_posts"}, 20261018153001, "20261018153001_add_posts"},
+		{Migration{ID: "2_add_posts", Name: "add_posts"}, 12, "12_add_posts"},
+	} {
+		if got := renameVersion(m.migration, m.version); got != m.want {
+			t.Errorf("renameVersion(%s, %d) = %s, want %s", m.migration.ID, m.version, got, m.want)
+		}
+	}
+}
+
+func TestRunRefusesMigrationBelowCurrentVersion(t *testing.T) {
+	dbPath := setupProject(t, map[string]string{
+		"000001_create_users.up.sql":   `CREATE TABLE users (id INTEGER PRIMARY KEY);`,
+		"000001_create_users.down.sql": `DROP TABLE users;`,
+		"000003_create_posts.up.sql":   `CREATE TABLE posts (id INTEGER PRIMARY KEY);`,
+		"000003_create_posts.down.sql": `DROP TABLE posts;`,
+	})
+	if err := Run(""); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	// A branch merged in with a version the database has already moved past
+	writeMigration(t, "000002_create_tags.up.sql", `CREATE TABLE tags (id INTEGER PRIMARY KEY);`)
+	writeMigration(t, "000002_create_tags.down.sql", `DROP TABLE tags;`)
+
+	err := Run("")
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || len(conflict.Issues) != 1 {
+		t.Fatalf("Expected one conflict, got %v", err)
+	}
+	if issue := conflict.Issues[0]; !strings.Contains(issue.Problem, "000002_create_tags has not been applied but the database is already at version 3") ||
+		!strings.Contains(issue.Fix, "rename it to 000004_create_tags") {
+		t.Errorf("Unexpected issue: %+v", issue)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var tags int
+	db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'tags'`).Scan(&tags)
+	if tags != 0 {
+		t.Error("Expected the conflicting migration not to run")
+	}
+
+	// Check reports the same conflict without running anything
+	if issues, err := Check(""); err != nil || len(issues) != 1 {
+		t.Errorf("Expected Check to report the conflict, got %+v (%v)", issues, err)
+	}
+}
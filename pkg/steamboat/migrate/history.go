@@ -0,0 +1,112 @@
+package migrate
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// historyTable records every version that has been applied. golang-migrate
+// only stores the current version, which is not enough to tell whether a
+// migration below it was merged in after the database moved past it.
+const historyTable = "schema_migrations_history"
+
+// loadHistory returns the applied versions, creating the history table on first
+// use. For a database migrated before the table existed only the current
+// version is provable, so it is the only one recorded; the versions below it
+// are unknown (see historyStart).
+func loadHistory(db *sql.DB, current uint) (map[uint]bool, error) {
+	var exists int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, historyTable).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration history: %w", err)
+	}
+
+	if exists == 0 {
+		if _, err := db.Exec(`CREATE TABLE ` + historyTable + ` (
+			version INTEGER PRIMARY KEY,
+			applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`); err != nil {
+			return nil, fmt.Errorf("failed to create migration history: %w", err)
+		}
+
+		if current > 0 {
+			if err := recordApplied(db, []uint{current}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return queryHistory(db)
+}
+
+// readHistory returns the applied versions like loadHistory, but never writes.
+// exists reports whether the history table was found; when it was not, only
+// current is known to be applied.
+func readHistory(db *sql.DB, current uint) (applied map[uint]bool, exists bool, err error) {
+	var count int
+	err = db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, historyTable).Scan(&count)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read migration history: %w", err)
+	}
+
+	if count == 0 {
+		applied = make(map[uint]bool)
+		if current > 0 {
+			applied[current] = true
+		}
+		return applied, false, nil
+	}
+
+	applied, err = queryHistory(db)
+	return applied, true, err
+}
+
+func queryHistory(db *sql.DB) (map[uint]bool, error) {
+	rows, err := db.Query(`SELECT version FROM ` + historyTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration history: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[uint]bool)
+	for rows.Next() {
+		var version uint
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to read migration history: %w", err)
+		}
+		applied[version] = true
+	}
+
+	return applied, rows.Err()
+}
+
+// historyStart returns the lowest version the history knows about. Whether
+// the migrations below it were applied is unknown: the database moved past
+// them before the history was kept.
+func historyStart(applied map[uint]bool, current uint) uint {
+	start := current
+	for version := range applied {
+		if version < start {
+			start = version
+		}
+	}
+	return start
+}
+
+// recordApplied marks versions as applied
+func recordApplied(db *sql.DB, versions []uint) error {
+	for _, version := range versions {
+		if _, err := db.Exec(`INSERT OR IGNORE INTO `+historyTable+` (version) VALUES (?)`, version); err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", version, err)
+		}
+	}
+	return nil
+}
+
+// recordRolledBack removes version from the history
+func recordRolledBack(db *sql.DB, version uint) error {
+	if _, err := db.Exec(`DELETE FROM `+historyTable+` WHERE version = ?`, version); err != nil {
+		return fmt.Errorf("failed to record rollback of migration %d: %w", version, err)
+	}
+	return nil
+}
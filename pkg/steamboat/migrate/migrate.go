@@ -1,55 +1,263 @@
 package migrate
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
-	_ "github.com/joho/godotenv/autoload"
 )
 
-// Run executes all pending migrations
-func Run() error {
-	m, err := getMigrator()
+// migrationsPath is where the migration files of the primary database live
+const migrationsPath = "internal/database/migrations"
+
+// Run executes all pending migrations. It refuses to run when the migration
+// files conflict or a migration below the current version was never applied.
+func Run(name string) error {
+	target, err := resolveDatabase(name)
+	if err != nil {
+		return err
+	}
+
+	migrations, err := readMigrations(target.MigrationsPath)
+	if err != nil {
+		return err
+	}
+	if issues := checkFiles(target.MigrationsPath, migrations); len(issues) > 0 {
+		return &ConflictError{Issues: issues}
+	}
+
+	// golang-migrate reports an empty directory as an error, there is simply nothing to do
+	if len(migrations) == 0 {
+		return nil
+	}
+
+	m, err := newMigrator(target.MigrationsPath, target.URL)
 	if err != nil {
 		return err
 	}
 	defer m.Close()
 
-	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		return fmt.Errorf("failed to run migrations: %w", err)
+	current, _, err := currentVersion(m)
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite3", target.URL)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	applied, err := loadHistory(db, current)
+	if err != nil {
+		return err
+	}
+	if issues := checkApplied(target.MigrationsPath, migrations, current, applied); len(issues) > 0 {
+		return &ConflictError{Issues: issues}
+	}
+
+	upErr := m.Up()
+
+	// Record whatever was applied, even if a later migration failed
+	reached, _, err := currentVersion(m)
+	if err != nil {
+		return err
+	}
+	var versions []uint
+	for _, migration := range migrations {
+		if migration.Version > current && migration.Version <= reached {
+			versions = append(versions, migration.Version)
+		}
+	}
+	if err := recordApplied(db, versions); err != nil {
+		return err
+	}
+
+	if upErr != nil && !errors.Is(upErr, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to run migrations: %w", upErr)
 	}
 
 	return nil
 }
 
 // Rollback rolls back one migration
-func Rollback() error {
-	m, err := getMigrator()
+func Rollback(name string) error {
+	target, err := resolveDatabase(name)
+	if err != nil {
+		return err
+	}
+
+	m, err := newMigrator(target.MigrationsPath, target.URL)
 	if err != nil {
 		return err
 	}
 	defer m.Close()
 
-	if err := m.Steps(-1); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+	current, _, err := currentVersion(m)
+	if err != nil {
+		return err
+	}
+
+	if err := m.Steps(-1); err != nil {
+		if errors.Is(err, migrate.ErrNoChange) {
+			return nil
+		}
 		return fmt.Errorf("failed to rollback migration: %w", err)
 	}
 
-	return nil
+	db, err := sql.Open("sqlite3", target.URL)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	if _, err := loadHistory(db, current); err != nil {
+		return err
+	}
+
+	return recordRolledBack(db, current)
+}
+
+// Fresh drops every table in the database and runs all migrations from scratch
+func Fresh(name string) error {
+	target, err := resolveDatabase(name)
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite3", target.URL)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	dropErr := dropAll(db)
+	db.Close()
+	if dropErr != nil {
+		return fmt.Errorf("failed to drop database: %w", dropErr)
+	}
+
+	return Run(name)
+}
+
+// dropAll drops every view, trigger, index and table in one transaction.
+// golang-migrate's Drop also tries to drop SQLite's own tables such as
+// sqlite_sequence and fails halfway on any AUTOINCREMENT table.
+func dropAll(db *sql.DB) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Foreign keys can only be switched outside a transaction
+	if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Views and triggers first, then virtual tables, which take their shadow
+	// tables with them, then the rest
+	rows, err := tx.QueryContext(ctx, `SELECT type, name FROM sqlite_master
+		WHERE name NOT LIKE 'sqlite_%' AND type IN ('view', 'trigger', 'index', 'table')
+		ORDER BY CASE
+			WHEN type = 'view' THEN 0
+			WHEN type = 'trigger' THEN 1
+			WHEN type = 'table' AND sql LIKE 'CREATE VIRTUAL TABLE%' THEN 2
+			WHEN type = 'index' THEN 3
+			ELSE 4
+		END, name`)
+	if err != nil {
+		return err
+	}
+	type object struct{ kind, name string }
+	var objects []object
+	for rows.Next() {
+		var o object
+		if err := rows.Scan(&o.kind, &o.name); err != nil {
+			rows.Close()
+			return err
+		}
+		objects = append(objects, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, o := range objects {
+		statement := fmt.Sprintf("DROP %s IF EXISTS %s", strings.ToUpper(o.kind), ident(o.name))
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to drop %s %s: %w", o.kind, o.name, err)
+		}
+	}
+
+	return tx.Commit()
 }
 
 // Status returns the current migration version
-func Status() (uint, bool, error) {
-	m, err := getMigrator()
+func Status(name string) (uint, bool, error) {
+	m, err := getMigrator(name)
 	if err != nil {
 		return 0, false, err
 	}
 	defer m.Close()
 
+	return currentVersion(m)
+}
+
+// Check reports conflicting migration files and migrations that were merged
+// in below the current version without being applied
+func Check(name string) ([]Issue, error) {
+	target, err := LookupDatabase(name)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := readMigrations(target.MigrationsPath)
+	if err != nil {
+		return nil, err
+	}
+	if issues := checkFiles(target.MigrationsPath, migrations); len(issues) > 0 {
+		return issues, nil
+	}
+
+	// Read the database without creating it or its history table
+	var current uint
+	applied := make(map[uint]bool)
+	if _, err := os.Stat(target.URL); err == nil {
+		if current, _, err = readState(target.URL); err != nil {
+			return nil, fmt.Errorf("failed to read database version: %w", err)
+		}
+
+		db, err := sql.Open("sqlite3", "file:"+target.URL+"?mode=ro")
+		if err != nil {
+			return nil, fmt.Errorf("failed to open database: %w", err)
+		}
+		defer db.Close()
+
+		if applied, _, err = readHistory(db, current); err != nil {
+			return nil, err
+		}
+	}
+
+	issues := checkApplied(target.MigrationsPath, migrations, current, applied)
+	return append(issues, checkUnknown(migrations, current, applied)...), nil
+}
+
+func currentVersion(m *migrate.Migrate) (uint, bool, error) {
 	version, dirty, err := m.Version()
 	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
 		return 0, false, fmt.Errorf("failed to get migration status: %w", err)
@@ -58,31 +266,42 @@ func Status() (uint, bool, error) {
 	return version, dirty, nil
 }
 
-func getMigrator() (*migrate.Migrate, error) {
-	// Get database URL from environment
-	dbURL := os.Getenv("DB_URL")
-	if dbURL == "" {
-		return nil, fmt.Errorf("DB_URL environment variable is not set")
+func getMigrator(name string) (*migrate.Migrate, error) {
+	target, err := resolveDatabase(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return newMigrator(target.MigrationsPath, target.URL)
+}
+
+// resolveDatabase looks up the named database and makes sure its directory exists
+func resolveDatabase(name string) (Database, error) {
+	target, err := LookupDatabase(name)
+	if err != nil {
+		return target, err
 	}
 
 	// Ensure the database directory exists
-	dbDir := filepath.Dir(dbURL)
+	dbDir := filepath.Dir(target.URL)
 	if dbDir != "." && dbDir != "" {
 		if err := os.MkdirAll(dbDir, 0755); err != nil {
-			return nil, fmt.Errorf("failed to create database directory: %w", err)
+			return target, fmt.Errorf("failed to create database directory: %w", err)
 		}
 	}
 
-	// Use hardcoded migrations path
-	migrationsPath := "internal/database/migrations"
+	return target, nil
+}
 
+// newMigrator creates a migrator for the given migrations directory and SQLite file
+func newMigrator(dir, dbURL string) (*migrate.Migrate, error) {
 	// Ensure migrations directory exists
-	if err := os.MkdirAll(migrationsPath, 0755); err != nil {
+	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create migrations directory: %w", err)
 	}
 
 	// Convert to absolute path for file:// URL
-	absPath, err := filepath.Abs(migrationsPath)
+	absPath, err := filepath.Abs(dir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get absolute path: %w", err)
 	}
@@ -0,0 +1,99 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFresh(t *testing.T) {
+	dbPath := setupProject(t, map[string]string{
+		"000001_create_users.up.sql": `CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, email TEXT NOT NULL UNIQUE);
+CREATE INDEX idx_users_email ON users (email);`,
+		"000001_create_users.down.sql": `DROP TABLE users;`,
+		"000002_create_posts.up.sql": `CREATE TABLE posts (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER REFERENCES users (id));
+CREATE VIEW post_authors AS SELECT posts.id, users.email FROM posts JOIN users ON users.id = posts.user_id;
+CREATE TRIGGER users_cleanup AFTER DELETE ON users BEGIN DELETE FROM posts WHERE user_id = old.id; END;`,
+		"000002_create_posts.down.sql": `DROP VIEW post_authors; DROP TABLE posts;`,
+	})
+
+	if err := Run(""); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	db := openDB(t, dbPath)
+	if _, err := db.Exec(`INSERT INTO users (email) VALUES ('a@example.com'); INSERT INTO posts (user_id) VALUES (1);
+		CREATE TABLE scratch (id INTEGER PRIMARY KEY AUTOINCREMENT); INSERT INTO scratch DEFAULT VALUES;`); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := Fresh(""); err != nil {
+			t.Fatalf("Fresh failed: %v", err)
+		}
+	}
+
+	version, dirty, err := Status("")
+	if err != nil || version != 2 || dirty {
+		t.Fatalf("Expected clean version 2, got %d dirty=%v err=%v", version, dirty, err)
+	}
+
+	var users, scratch int
+	db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&users)
+	db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'scratch'`).Scan(&scratch)
+	if users != 0 || scratch != 0 {
+		t.Errorf("Expected an empty database, got %d users and scratch table %d", users, scratch)
+	}
+
+	var seq int
+	db.QueryRow(`SELECT COUNT(*) FROM sqlite_sequence`).Scan(&seq)
+	if seq != 0 {
+		t.Errorf("Expected no AUTOINCREMENT counters to survive, got %d", seq)
+	}
+
+	if err := Run(""); err != nil {
+		t.Errorf("Expected migrate to work after fresh, got %v", err)
+	}
+}
+
+func TestCheckWithoutHistory(t *testing.T) {
+	dbPath := setupProject(t, map[string]string{
+		"000001_create_users.up.sql":   `CREATE TABLE users (id INTEGER PRIMARY KEY);`,
+		"000001_create_users.down.sql": `DROP TABLE users;`,
+		"000003_create_posts.up.sql":   `CREATE TABLE posts (id INTEGER PRIMARY KEY);`,
+		"000003_create_posts.down.sql": `DROP TABLE posts;`,
+	})
+	if err := Run(""); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	// A database migrated before the history was kept, with a migration
+	// merged in below its version since
+	db := openDB(t, dbPath)
+	if _, err := db.Exec(`DROP TABLE ` + historyTable); err != nil {
+		t.Fatal(err)
+	}
+	writeMigration(t, "000002_create_tags.up.sql", `CREATE TABLE tags (id INTEGER PRIMARY KEY);`)
+	writeMigration(t, "000002_create_tags.down.sql", `DROP TABLE tags;`)
+
+	issues, err := Check("")
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(issues) != 1 || !strings.Contains(issues[0].Problem, "000001_create_users, 000002_create_tags ran is unknown") {
+		t.Errorf("Expected the versions below 3 to be unknown, got %+v", issues)
+	}
+
+	var tables int
+	db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = ?`, historyTable).Scan(&tables)
+	if tables != 0 {
+		t.Error("Expected Check not to create the history table")
+	}
+
+	// Run starts the history at the current version rather than assuming the rest
+	if err := Run(""); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	applied, err := queryHistory(db)
+	if err != nil || len(applied) != 1 || !applied[3] {
+		t.Errorf("Expected only version 3 in the history, got %v, %v", applied, err)
+	}
+}
@@ -0,0 +1,430 @@
+package migrate
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ModelsPath is the directory autogenerated migrations read models from
+const ModelsPath = "internal/database/models"
+
+// queriesPattern finds the connection each model's queries use in database.go,
+// e.g. "models.NewEventQueries(traced["analytics"])". traced[Primary] and a
+// plain db mean the primary database; connections["name"] is what projects
+// created before tracing use.
+var queriesPattern = regexp.MustCompile(`models\.New(\w+)Queries\((?:(?:traced|connections)\[(?:Primary|"(\w+)")\]|\w+)\)`)
+
+// tableDef is a table as declared by a model or read from SQLite
+type tableDef struct {
+	Name     string
+	Columns  []columnDef
+	Indexes  []indexDef
+	SQL      string    // CREATE TABLE statement, only set for live tables
+	Triggers []string  // CREATE TRIGGER statements, only set for live tables
+	Views    []viewDef // views that select from the table, only set for live tables
+
+	// constrained holds the columns of UNIQUE and PRIMARY KEY constraints of live tables
+	constrained map[string]bool
+}
+
+// columnDef describes one column
+type columnDef struct {
+	Name       string
+	Type       string
+	NotNull    bool
+	PrimaryKey bool
+	Default    string // SQL expression, empty for none
+	References string // referenced table, empty for none
+}
+
+// indexDef describes one index
+type indexDef struct {
+	Name    string
+	Unique  bool
+	Partial bool
+	Columns []string // empty names stand for expressions
+	SQL     string   // CREATE INDEX statement, only set for live indexes
+}
+
+// viewDef is a view read from SQLite
+type viewDef struct {
+	Name string
+	SQL  string
+}
+
+func (t *tableDef) column(name string) (columnDef, bool) {
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return columnDef{}, false
+}
+
+func (t *tableDef) index(name string) (indexDef, bool) {
+	for _, i := range t.Indexes {
+		if i.Name == name {
+			return i, true
+		}
+	}
+	return indexDef{}, false
+}
+
+// parseModels reads the structs in dir and returns the tables they describe.
+//
+// A struct is a model when it has a field tagged db:"id" or a TableName()
+// method returning a string literal. Columns come from the db tags, which may
+// carry options after the column name:
+//
+//	Email  string `db:"email,unique"`            // unique index idx_users_email
+//	Slug   string `db:"slug,index"`              // index idx_users_slug
+//	UserID int    `db:"user_id,references=users"` // REFERENCES users (id)
+//	A      int    `db:"a,index=idx_posts_a_b"`   // composite index, in field order
+//
+// Only models whose queries are wired to the given database in
+// internal/database/database.go are returned; unwired models belong to the
+// primary database.
+func parseModels(dir, database string) ([]tableDef, error) {
+	fset := token.NewFileSet()
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+
+	structs := make(map[string]*ast.StructType)
+	tableNames := make(map[string]string)
+	for _, path := range files {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+
+		for _, decl := range file.Decls {
+			switch d := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					if ts, ok := spec.(*ast.TypeSpec); ok {
+						if st, ok := ts.Type.(*ast.StructType); ok {
+							structs[ts.Name.Name] = st
+						}
+					}
+				}
+			case *ast.FuncDecl:
+				if receiver, name, ok := tableNameMethod(d); ok {
+					tableNames[receiver] = name
+				}
+			}
+		}
+	}
+
+	databases, err := modelDatabases()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(structs))
+	for name := range structs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var tables []tableDef
+	seen := make(map[string]string)
+	for _, name := range names {
+		modelDB := databases[name]
+		if modelDB == "" {
+			modelDB = PrimaryDatabase
+		}
+		if modelDB != database {
+			continue
+		}
+
+		columns, err := structColumns(structs, name, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		tableName, hasTableName := tableNames[name]
+		if !hasTableName {
+			if _, ok := findColumn(columns, "id"); !ok {
+				continue
+			}
+			tableName = defaultTableName(name)
+		}
+
+		if other, ok := seen[tableName]; ok {
+			return nil, fmt.Errorf("models %s and %s both map to table %s, give one a TableName() method", other, name, tableName)
+		}
+		seen[tableName] = name
+
+		table, err := buildTable(tableName, columns)
+		if err != nil {
+			return nil, fmt.Errorf("model %s: %w", name, err)
+		}
+		tables = append(tables, table)
+	}
+
+	return tables, nil
+}
+
+// taggedColumn is a column together with its index options
+type taggedColumn struct {
+	columnDef
+	options []string
+}
+
+func findColumn(columns []taggedColumn, name string) (taggedColumn, bool) {
+	for _, c := range columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return taggedColumn{}, false
+}
+
+// structColumns returns the tagged columns of a struct, flattening embedded
+// structs declared in the same package like sqlx does
+func structColumns(structs map[string]*ast.StructType, name string, path []string) ([]taggedColumn, error) {
+	for _, p := range path {
+		if p == name {
+			return nil, fmt.Errorf("model %s embeds itself", name)
+		}
+	}
+
+	var columns []taggedColumn
+	for _, field := range structs[name].Fields.List {
+		tag := ""
+		if field.Tag != nil {
+			unquoted, err := strconv.Unquote(field.Tag.Value)
+			if err == nil {
+				tag = reflect.StructTag(unquoted).Get("db")
+			}
+		}
+
+		if len(field.Names) == 0 && tag == "" {
+			if ident, ok := field.Type.(*ast.Ident); ok && structs[ident.Name] != nil {
+				embedded, err := structColumns(structs, ident.Name, append(path, name))
+				if err != nil {
+					return nil, err
+				}
+				columns = append(columns, embedded...)
+			}
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		if parts[0] == "" || parts[0] == "-" {
+			continue
+		}
+
+		sqlType, nullable, err := columnType(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("model %s, column %s: %w", name, parts[0], err)
+		}
+
+		column := columnDef{Name: parts[0], Type: sqlType, NotNull: !nullable}
+		var options []string
+		for _, option := range parts[1:] {
+			if table, ok := strings.CutPrefix(option, "references="); ok {
+				column.References = table
+				continue
+			}
+			options = append(options, option)
+		}
+		columns = append(columns, taggedColumn{columnDef: column, options: options})
+	}
+
+	return columns, nil
+}
+
+// buildTable turns tagged columns into a table definition with defaults and indexes
+func buildTable(name string, tagged []taggedColumn) (tableDef, error) {
+	table := tableDef{Name: name}
+	indexes := make(map[string]*indexDef)
+	var order []string
+
+	for _, c := range tagged {
+		column := c.columnDef
+		switch {
+		case column.Name == "id":
+			column.PrimaryKey = true
+			column.NotNull = true
+		case column.NotNull && column.References == "":
+			column.Default = zeroDefault(column)
+		}
+		table.Columns = append(table.Columns, column)
+
+		for _, option := range c.options {
+			kind, indexName, _ := strings.Cut(option, "=")
+			if kind != "index" && kind != "unique" {
+				return table, fmt.Errorf("unknown db tag option %q on column %s", option, column.Name)
+			}
+			if indexName == "" {
+				indexName = "idx_" + name + "_" + column.Name
+			}
+
+			idx, ok := indexes[indexName]
+			if !ok {
+				idx = &indexDef{Name: indexName}
+				indexes[indexName] = idx
+				order = append(order, indexName)
+			}
+			idx.Unique = idx.Unique || kind == "unique"
+			idx.Columns = append(idx.Columns, column.Name)
+		}
+	}
+
+	for _, indexName := range order {
+		table.Indexes = append(table.Indexes, *indexes[indexName])
+	}
+
+	return table, nil
+}
+
+// columnType maps a Go field type to an SQLite column type and reports whether it is nullable
+func columnType(expr ast.Expr) (string, bool, error) {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		sqlType, _, err := columnType(t.X)
+		return sqlType, true, err
+	case *ast.ArrayType:
+		if ident, ok := t.Elt.(*ast.Ident); ok && t.Len == nil && ident.Name == "byte" {
+			return "BLOB", true, nil
+		}
+	case *ast.Ident:
+		switch t.Name {
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "bool":
+			return "INTEGER", false, nil
+		case "float32", "float64":
+			return "REAL", false, nil
+		case "string":
+			return "TEXT", false, nil
+		}
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok {
+			switch pkg.Name + "." + t.Sel.Name {
+			case "time.Time":
+				return "DATETIME", false, nil
+			case "sql.NullString":
+				return "TEXT", true, nil
+			case "sql.NullInt64", "sql.NullInt32", "sql.NullInt16", "sql.NullByte", "sql.NullBool":
+				return "INTEGER", true, nil
+			case "sql.NullFloat64":
+				return "REAL", true, nil
+			case "sql.NullTime":
+				return "DATETIME", true, nil
+			}
+		}
+	}
+
+	return "", false, fmt.Errorf("unsupported type %s", typeString(expr))
+}
+
+func typeString(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return "*" + typeString(t.X)
+	case *ast.ArrayType:
+		return "[]" + typeString(t.Elt)
+	case *ast.SelectorExpr:
+		return typeString(t.X) + "." + t.Sel.Name
+	}
+	return fmt.Sprintf("%T", expr)
+}
+
+// zeroDefault is the DEFAULT of a NOT NULL column, so rows that predate it stay valid
+func zeroDefault(column columnDef) string {
+	switch column.Type {
+	case "INTEGER", "REAL":
+		return "0"
+	case "DATETIME":
+		return "CURRENT_TIMESTAMP"
+	case "BLOB":
+		return "X''"
+	}
+	return "''"
+}
+
+// tableNameMethod recognises `func (X) TableName() string { return "name" }`
+func tableNameMethod(fn *ast.FuncDecl) (string, string, bool) {
+	if fn.Name.Name != "TableName" || fn.Recv == nil || len(fn.Recv.List) != 1 || fn.Body == nil || len(fn.Body.List) != 1 {
+		return "", "", false
+	}
+
+	receiver := fn.Recv.List[0].Type
+	if star, ok := receiver.(*ast.StarExpr); ok {
+		receiver = star.X
+	}
+	ident, ok := receiver.(*ast.Ident)
+	if !ok {
+		return "", "", false
+	}
+
+	ret, ok := fn.Body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return "", "", false
+	}
+	lit, ok := ret.Results[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", "", false
+	}
+	name, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return "", "", false
+	}
+
+	return ident.Name, name, true
+}
+
+// modelDatabases maps model struct names to the database their queries use
+// in internal/database/database.go
+func modelDatabases() (map[string]string, error) {
+	content, err := os.ReadFile(filepath.Join("internal", "database", "database.go"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]string{}, nil
+		}
+		return nil, fmt.Errorf("failed to read database.go: %w", err)
+	}
+
+	databases := make(map[string]string)
+	for _, match := range queriesPattern.FindAllStringSubmatch(string(content), -1) {
+		databases[match[1]] = match[2]
+	}
+	return databases, nil
+}
+
+// defaultTableName derives a table name from a struct name, e.g. BlogPost -> blog_posts
+func defaultTableName(structName string) string {
+	var b strings.Builder
+	for i, r := range structName {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			b.WriteRune('_')
+		}
+		b.WriteRune(r)
+	}
+	name := strings.ToLower(b.String())
+
+	switch {
+	case strings.HasSuffix(name, "y"):
+		return name[:len(name)-1] + "ies"
+	case strings.HasSuffix(name, "s"), strings.HasSuffix(name, "x"), strings.HasSuffix(name, "ch"):
+		return name + "es"
+	}
+	return name + "s"
+}
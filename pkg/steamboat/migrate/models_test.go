@@ -0,0 +1,163 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zulubit/steamboat/pkg/steamboat/generator"
+)
+
+// writeModels writes Go files to ModelsPath, and database.go when wiring is set
+func writeModels(t *testing.T, wiring string, files map[string]string) {
+	if err := os.MkdirAll(ModelsPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(ModelsPath, name), []byte("package models\n\n"+content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if wiring != "" {
+		if err := os.WriteFile(filepath.Join("internal", "database", "database.go"), []byte(wiring), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestParseModels(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeModels(t, `package database
+
+func New() Service {
+	return &service{
+		user:  models.NewUserQueries(db),
+		event: models.NewEventQueries(connections["analytics"]),
+	}
+}
+`, map[string]string{
+		"user.go": "import \"time\"\n\n" +
+			"type Timestamps struct {\n\tCreatedAt time.Time `db:\"created_at\"`\n}\n\n" +
+			"type User struct {\n" +
+			"\tID       int64   `db:\"id\"`\n" +
+			"\tEmail    string  `db:\"email,unique\"`\n" +
+			"\tNickname *string `db:\"nickname\"`\n" +
+			"\tTeamID   int64   `db:\"team_id,references=teams\"`\n" +
+			"\tPassword string  `db:\"-\"`\n" +
+			"\tTimestamps\n}\n",
+		"team.go": "type Team struct {\n\tID int64 `db:\"id\"`\n" +
+			"\tOrg  string `db:\"org,index=idx_teams_org_slug\"`\n" +
+			"\tSlug string `db:\"slug,index=idx_teams_org_slug\"`\n}\n\n" +
+			"func (Team) TableName() string { return \"crews\" }\n",
+		"event.go":     "type Event struct {\n\tID int64 `db:\"id\"`\n\tPayload []byte `db:\"payload\"`\n}\n",
+		"filter.go":    "// Not a model: no id and no TableName\ntype Filter struct {\n\tQuery string `db:\"query\"`\n}\n",
+		"user_test.go": "type Fixture struct {\n\tID int64 `db:\"id\"`\n}\n",
+	})
+
+	tables, err := parseModels(ModelsPath, PrimaryDatabase)
+	if err != nil {
+		t.Fatalf("parseModels failed: %v", err)
+	}
+	if len(tables) != 2 || tables[0].Name != "crews" || tables[1].Name != "users" {
+		t.Fatalf("Expected the crews and users tables, got %+v", tables)
+	}
+
+	crews := tables[0]
+	if len(crews.Indexes) != 1 || crews.Indexes[0].Name != "idx_teams_org_slug" || strings.Join(crews.Indexes[0].Columns, ",") != "org,slug" {
+		t.Errorf("Expected a composite index in field order, got %+v", crews.Indexes)
+	}
+
+	users := tables[1]
+	var columns []string
+	for _, c := range users.Columns {
+		columns = append(columns, c.Name)
+	}
+	if strings.Join(columns, ",") != "id,email,nickname,team_id,created_at" {
+		t.Errorf("Expected the embedded columns to be flattened, got %v", columns)
+	}
+	if id, _ := users.column("id"); !id.PrimaryKey || id.Type != "INTEGER" {
+		t.Errorf("Expected id to be the primary key, got %+v", id)
+	}
+	if email, _ := users.column("email"); !email.NotNull || email.Default != "''" {
+		t.Errorf("Expected email to be NOT NULL with a zero default, got %+v", email)
+	}
+	if nickname, _ := users.column("nickname"); nickname.NotNull {
+		t.Errorf("Expected a pointer to be nullable, got %+v", nickname)
+	}
+	if team, _ := users.column("team_id"); team.References != "teams" || team.Default != "" {
+		t.Errorf("Expected a foreign key without a default, got %+v", team)
+	}
+	if created, _ := users.column("created_at"); created.Type != "DATETIME" || created.Default != "CURRENT_TIMESTAMP" {
+		t.Errorf("Unexpected created_at: %+v", created)
+	}
+	if len(users.Indexes) != 1 || !users.Indexes[0].Unique || users.Indexes[0].Name != "idx_users_email" {
+		t.Errorf("Expected a unique email index, got %+v", users.Indexes)
+	}
+
+	// Models wired to a named connection belong to that database only
+	analytics, err := parseModels(ModelsPath, "analytics")
+	if err != nil || len(analytics) != 1 || analytics[0].Name != "events" {
+		t.Errorf("Expected only the events table on analytics, got %+v (%v)", analytics, err)
+	}
+}
+
+func TestParseModelsGeneratedWiring(t *testing.T) {
+	template, err := os.ReadFile(filepath.Join("..", "templates", "internal", "database", "database.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(t.TempDir())
+	writeModels(t, generator.ProcessTemplate(string(template), generator.TemplateData{ProjectName: "app"}), nil)
+	if err := os.WriteFile("go.mod", []byte("module app\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// make:model wires the queries to traced[Primary] and traced["analytics"]
+	if err := generator.GenerateModel("user", ""); err != nil {
+		t.Fatalf("GenerateModel failed: %v", err)
+	}
+	if err := generator.GenerateModel("event", "analytics"); err != nil {
+		t.Fatalf("GenerateModel failed: %v", err)
+	}
+
+	for database, want := range map[string]string{PrimaryDatabase: "users", "analytics": "events"} {
+		tables, err := parseModels(ModelsPath, database)
+		if err != nil || len(tables) != 1 || tables[0].Name != want {
+			t.Errorf("Expected only %s on the %s database, got %+v (%v)", want, database, tables, err)
+		}
+	}
+}
+
+func TestParseModelsErrors(t *testing.T) {
+	for _, tc := range []struct {
+		source string
+		err    string
+	}{
+		{"type User struct {\n\tID int64 `db:\"id\"`\n\tTags []string `db:\"tags\"`\n}\n", "model User, column tags: unsupported type []string"},
+		{"type User struct {\n\tID int64 `db:\"id\"`\n\tEmail string `db:\"email,indexed\"`\n}\n", `model User: unknown db tag option "indexed" on column email`},
+		{"type User struct {\n\tID int64 `db:\"id\"`\n}\n\ntype Account struct {\n\tID int64 `db:\"id\"`\n}\n\nfunc (Account) TableName() string { return \"users\" }\n",
+			"models Account and User both map to table users"},
+		{"type Node struct {\n\tID int64 `db:\"id\"`\n\tNode\n}\n", "model Node embeds itself"},
+	} {
+		t.Chdir(t.TempDir())
+		writeModels(t, "", map[string]string{"models.go": tc.source})
+
+		if _, err := parseModels(ModelsPath, PrimaryDatabase); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("Expected error %q, got %v", tc.err, err)
+		}
+	}
+}
+
+func TestDefaultTableName(t *testing.T) {
+	for name, want := range map[string]string{
+		"User":     "users",
+		"BlogPost": "blog_posts",
+		"Category": "categories",
+		"Address":  "addresses",
+	} {
+		if got := defaultTableName(name); got != want {
+			t.Errorf("defaultTableName(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
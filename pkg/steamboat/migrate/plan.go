@@ -0,0 +1,197 @@
+package migrate
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// PlanStep is one migration a dry run would apply or roll back
+type PlanStep struct {
+	Migration Migration
+	From      uint
+	To        uint
+	Path      string
+	SQL       string
+}
+
+// Plan lists what Run or Rollback would do to a database
+type Plan struct {
+	Database Database
+	Rollback bool
+	Current  uint
+	Steps    []PlanStep
+
+	// backfill holds the versions to record when the history table does not exist yet
+	backfill []uint
+}
+
+// DryRun returns the migrations Run (or Rollback, when rollback is set) would
+// execute, in order, without creating or changing the database
+func DryRun(name string, rollback bool) (*Plan, error) {
+	target, err := LookupDatabase(name)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := readMigrations(target.MigrationsPath)
+	if err != nil {
+		return nil, err
+	}
+	if issues := checkFiles(target.MigrationsPath, migrations); len(issues) > 0 {
+		return nil, &ConflictError{Issues: issues}
+	}
+
+	plan := &Plan{Database: target, Rollback: rollback}
+
+	applied := make(map[uint]bool)
+	historyExists := false
+	if _, err := os.Stat(target.URL); err == nil {
+		current, dirty, err := readState(target.URL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read database version: %w", err)
+		}
+		if dirty {
+			return nil, fmt.Errorf("database is dirty at version %d, fix it and force the version before migrating", current)
+		}
+		plan.Current = current
+
+		db, err := sql.Open("sqlite3", "file:"+target.URL+"?mode=ro")
+		if err != nil {
+			return nil, fmt.Errorf("failed to open database: %w", err)
+		}
+		defer db.Close()
+
+		if applied, historyExists, err = readHistory(db, current); err != nil {
+			return nil, err
+		}
+	}
+
+	if issues := checkApplied(target.MigrationsPath, migrations, plan.Current, applied); len(issues) > 0 {
+		return nil, &ConflictError{Issues: issues}
+	}
+	if !historyExists {
+		for version := range applied {
+			plan.backfill = append(plan.backfill, version)
+		}
+	}
+
+	if rollback {
+		if plan.Current == 0 {
+			return plan, nil
+		}
+
+		var previous uint
+		for _, m := range migrations {
+			if m.Version < plan.Current {
+				previous = m.Version
+			}
+			if m.Version == plan.Current {
+				step, err := newPlanStep(m, m.DownPath, plan.Current, previous)
+				if err != nil {
+					return nil, err
+				}
+				plan.Steps = append(plan.Steps, step)
+			}
+		}
+		return plan, nil
+	}
+
+	from := plan.Current
+	for _, m := range migrations {
+		if m.Version <= plan.Current {
+			continue
+		}
+		step, err := newPlanStep(m, m.UpPath, from, m.Version)
+		if err != nil {
+			return nil, err
+		}
+		plan.Steps = append(plan.Steps, step)
+		from = m.Version
+	}
+
+	return plan, nil
+}
+
+func newPlanStep(m Migration, path string, from, to uint) (PlanStep, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return PlanStep{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return PlanStep{Migration: m, From: from, To: to, Path: path, SQL: string(content)}, nil
+}
+
+// Target returns the version the database ends up at
+func (p *Plan) Target() uint {
+	if len(p.Steps) == 0 {
+		return p.Current
+	}
+	return p.Steps[len(p.Steps)-1].To
+}
+
+// Script returns the plan as a SQL script that can be applied by hand with the
+// sqlite3 shell. Every migration runs in its own transaction together with the
+// version bookkeeping steamboat migrate would do, so the CLI agrees with the
+// database afterwards.
+func (p *Plan) Script() string {
+	var b strings.Builder
+
+	direction := "Migrate"
+	if p.Rollback {
+		direction = "Roll back"
+	}
+	fmt.Fprintf(&b, "-- %s the %s database (%s)\n", direction, p.Database.Name, p.Database.URL)
+	fmt.Fprintf(&b, "-- Generated: %s\n", time.Now().UTC().Format(createdFormat+" MST"))
+	fmt.Fprintf(&b, "-- Version %d -> %d, %d migration(s)\n", p.Current, p.Target(), len(p.Steps))
+	if len(p.Steps) == 0 {
+		return b.String()
+	}
+	fmt.Fprintf(&b, "--\n-- Apply with: sqlite3 %s < script.sql\n\n", p.Database.URL)
+
+	// The tables golang-migrate and steamboat keep their bookkeeping in
+	b.WriteString("CREATE TABLE IF NOT EXISTS schema_migrations (version uint64, dirty bool);\n")
+	b.WriteString("CREATE UNIQUE INDEX IF NOT EXISTS version_unique ON schema_migrations (version);\n")
+	fmt.Fprintf(&b, "CREATE TABLE IF NOT EXISTS %s (\n    version INTEGER PRIMARY KEY,\n    applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP\n);\n", historyTable)
+	for _, m := range p.backfill {
+		fmt.Fprintf(&b, "INSERT OR IGNORE INTO %s (version) VALUES (%d);\n", historyTable, m)
+	}
+	b.WriteString("\n")
+
+	for _, step := range p.Steps {
+		fmt.Fprintf(&b, "-- %s: version %d -> %d\n", step.Path, step.From, step.To)
+		b.WriteString("BEGIN;\n\n")
+		b.WriteString(terminate(step.SQL))
+		b.WriteString("\nDELETE FROM schema_migrations;\n")
+		if step.To > 0 {
+			fmt.Fprintf(&b, "INSERT INTO schema_migrations (version, dirty) VALUES (%d, 0);\n", step.To)
+		}
+		if p.Rollback {
+			fmt.Fprintf(&b, "DELETE FROM %s WHERE version = %d;\n", historyTable, step.From)
+		} else {
+			fmt.Fprintf(&b, "INSERT OR IGNORE INTO %s (version) VALUES (%d);\n", historyTable, step.To)
+		}
+		b.WriteString("COMMIT;\n\n")
+	}
+
+	return b.String()
+}
+
+// terminate makes sure the last statement of a migration ends with a
+// semicolon, so the bookkeeping that follows is not appended to it
+func terminate(content string) string {
+	content = strings.TrimRight(content, " \t\r\n")
+	lines := strings.Split(content, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "--") {
+			continue
+		}
+		if !strings.HasSuffix(line, ";") {
+			content += "\n;"
+		}
+		break
+	}
+	return content + "\n"
+}
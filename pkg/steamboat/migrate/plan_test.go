@@ -0,0 +1,162 @@
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var planFiles = map[string]string{
+	"000001_create_users.up.sql":   `CREATE TABLE users (id INTEGER PRIMARY KEY);`,
+	"000001_create_users.down.sql": `DROP TABLE users;`,
+	"000002_create_posts.up.sql":   "CREATE TABLE posts (id INTEGER PRIMARY KEY)\n-- no semicolon",
+	"000002_create_posts.down.sql": `DROP TABLE posts;`,
+}
+
+func describeSteps(plan *Plan) string {
+	var steps []string
+	for _, step := range plan.Steps {
+		steps = append(steps, fmt.Sprintf("%d -> %d %s", step.From, step.To, filepath.Base(step.Path)))
+	}
+	return strings.Join(steps, "; ")
+}
+
+func historyOf(t *testing.T, path string) string {
+	rows, err := openDB(t, path).Query(`SELECT version FROM ` + historyTable + ` ORDER BY version`)
+	if err != nil {
+		t.Fatalf("Failed to read the history: %v", err)
+	}
+	defer rows.Close()
+
+	var versions []string
+	for rows.Next() {
+		var version string
+		rows.Scan(&version)
+		versions = append(versions, version)
+	}
+	return strings.Join(versions, ",")
+}
+
+func TestDryRunScriptMatchesRun(t *testing.T) {
+	dbPath := setupProject(t, planFiles)
+
+	plan, err := DryRun("", false)
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+	if got := describeSteps(plan); got != "0 -> 1 000001_create_users.up.sql; 1 -> 2 000002_create_posts.up.sql" {
+		t.Fatalf("Unexpected steps: %s", got)
+	}
+	if _, err := os.Stat(dbPath); !os.IsNotExist(err) {
+		t.Fatal("Expected DryRun not to create the database")
+	}
+
+	// The unterminated posts migration must not swallow the bookkeeping after it
+	if _, err := openDB(t, dbPath).Exec(plan.Script()); err != nil {
+		t.Fatalf("Failed to apply the script: %v\n%s", err, plan.Script())
+	}
+	if version, dirty, err := Status(""); err != nil || dirty || version != 2 {
+		t.Errorf("Expected clean version 2, got %d dirty=%v (%v)", version, dirty, err)
+	}
+	if got := historyOf(t, dbPath); got != "1,2" {
+		t.Errorf("Expected versions 1 and 2 in the history, got %s", got)
+	}
+	if issues, err := Check(""); err != nil || len(issues) > 0 {
+		t.Errorf("Expected the CLI to agree with the script, got %+v (%v)", issues, err)
+	}
+
+	plan, err = DryRun("", false)
+	if err != nil || len(plan.Steps) > 0 || strings.Contains(plan.Script(), "BEGIN;") {
+		t.Errorf("Expected nothing left to run, got %s (%v)", describeSteps(plan), err)
+	}
+}
+
+func TestDryRunStartsHistory(t *testing.T) {
+	dbPath := setupProject(t, map[string]string{
+		"000001_create_users.up.sql":   planFiles["000001_create_users.up.sql"],
+		"000001_create_users.down.sql": planFiles["000001_create_users.down.sql"],
+	})
+	if err := Run(""); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	// A database migrated before steamboat kept a history
+	if _, err := openDB(t, dbPath).Exec(`DROP TABLE ` + historyTable); err != nil {
+		t.Fatal(err)
+	}
+	writeMigration(t, "000002_create_posts.up.sql", planFiles["000002_create_posts.up.sql"])
+	writeMigration(t, "000002_create_posts.down.sql", planFiles["000002_create_posts.down.sql"])
+
+	plan, err := DryRun("", false)
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+	if got := describeSteps(plan); got != "1 -> 2 000002_create_posts.up.sql" {
+		t.Fatalf("Unexpected steps: %s", got)
+	}
+	if _, err := openDB(t, dbPath).Exec(plan.Script()); err != nil {
+		t.Fatalf("Failed to apply the script: %v", err)
+	}
+	if got := historyOf(t, dbPath); got != "1,2" {
+		t.Errorf("Expected the history to start at the current version like Run does, got %s", got)
+	}
+}
+
+func TestDryRunRollback(t *testing.T) {
+	dbPath := setupProject(t, planFiles)
+
+	plan, err := DryRun("", true)
+	if err != nil || len(plan.Steps) > 0 {
+		t.Fatalf("Expected nothing to roll back on a new database, got %s (%v)", describeSteps(plan), err)
+	}
+
+	if err := Run(""); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	plan, err = DryRun("", true)
+	if err != nil {
+		t.Fatalf("DryRun failed: %v", err)
+	}
+	if got := describeSteps(plan); got != "2 -> 1 000002_create_posts.down.sql" {
+		t.Fatalf("Expected only the last migration to be rolled back, got %s", got)
+	}
+
+	if _, err := openDB(t, dbPath).Exec(plan.Script()); err != nil {
+		t.Fatalf("Failed to apply the script: %v", err)
+	}
+	if version, _, err := Status(""); err != nil || version != 1 {
+		t.Errorf("Expected version 1, got %d (%v)", version, err)
+	}
+	if got := historyOf(t, dbPath); got != "1" {
+		t.Errorf("Expected version 2 to leave the history, got %s", got)
+	}
+}
+
+func TestDryRunRefusesDirtyDatabase(t *testing.T) {
+	dbPath := setupProject(t, planFiles)
+	if err := Run(""); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if _, err := openDB(t, dbPath).Exec(`UPDATE schema_migrations SET dirty = 1`); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := DryRun("", false); err == nil || !strings.Contains(err.Error(), "database is dirty at version 2") {
+		t.Errorf("Expected a dirty database to be refused, got %v", err)
+	}
+}
+
+func TestTerminate(t *testing.T) {
+	for content, want := range map[string]string{
+		"SELECT 1;":              "SELECT 1;\n",
+		"SELECT 1":               "SELECT 1\n;\n",
+		"SELECT 1\n-- done\n\n":  "SELECT 1\n-- done\n;\n",
+		"-- nothing to do\n":     "-- nothing to do\n",
+		"SELECT 1;\n-- trailing": "SELECT 1;\n-- trailing\n",
+	} {
+		if got := terminate(content); got != want {
+			t.Errorf("terminate(%q) = %q, want %q", content, got, want)
+		}
+	}
+}
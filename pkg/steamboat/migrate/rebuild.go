@@ -0,0 +1,530 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// createTablePattern matches the table name of a CREATE TABLE statement
+var createTablePattern = regexp.MustCompile(`(?is)^\s*CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?("(?:[^"]|"")+"|` + "`[^`]+`" + `|\[[^\]]+\]|[^\s(]+)`)
+
+// querier is satisfied by *sql.DB and *sql.Tx
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// TableDefinition is a parsed CREATE TABLE statement that RebuildTable lets
+// callers change before the table is recreated
+type TableDefinition struct {
+	Name    string
+	Columns []ColumnDefinition
+	// Constraints holds the table constraints, e.g. "FOREIGN KEY (user_id) REFERENCES users (id)"
+	Constraints []string
+	// Options holds what follows the column list, e.g. "WITHOUT ROWID"
+	Options string
+
+	// renamed maps old column names to new ones
+	renamed map[string]string
+}
+
+// ColumnDefinition is one column of a TableDefinition
+type ColumnDefinition struct {
+	Name string
+	// Definition is everything after the name, e.g. "TEXT NOT NULL DEFAULT ''"
+	Definition string
+	// From is the SELECT expression that fills the column from the old table.
+	// When empty the column of the same name is copied, and new columns get
+	// their default.
+	From string
+}
+
+// Column returns the named column, or nil
+func (t *TableDefinition) Column(name string) *ColumnDefinition {
+	for i := range t.Columns {
+		if strings.EqualFold(t.Columns[i].Name, name) {
+			return &t.Columns[i]
+		}
+	}
+	return nil
+}
+
+// AddColumn appends a column
+func (t *TableDefinition) AddColumn(name, definition string) {
+	t.Columns = append(t.Columns, ColumnDefinition{Name: name, Definition: definition})
+}
+
+// DropColumn removes a column and the indexes that use it
+func (t *TableDefinition) DropColumn(name string) error {
+	for i := range t.Columns {
+		if strings.EqualFold(t.Columns[i].Name, name) {
+			t.Columns = append(t.Columns[:i], t.Columns[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("table %s has no column %s", t.Name, name)
+}
+
+// RenameColumn renames a column, keeping its data and indexes
+func (t *TableDefinition) RenameColumn(oldName, newName string) error {
+	column := t.Column(oldName)
+	if column == nil {
+		return fmt.Errorf("table %s has no column %s", t.Name, oldName)
+	}
+	if column.From == "" {
+		column.From = ident(column.Name)
+	}
+	if t.renamed == nil {
+		t.renamed = make(map[string]string)
+	}
+	t.renamed[strings.ToLower(column.Name)] = newName
+	column.Name = newName
+	return nil
+}
+
+// SQL returns the CREATE TABLE statement
+func (t *TableDefinition) SQL() string {
+	var items []string
+	for _, c := range t.Columns {
+		items = append(items, "    "+strings.TrimSpace(ident(c.Name)+" "+c.Definition))
+	}
+	for _, constraint := range t.Constraints {
+		items = append(items, "    "+constraint)
+	}
+
+	statement := fmt.Sprintf("CREATE TABLE %s (\n%s\n)", ident(t.Name), strings.Join(items, ",\n"))
+	if t.Options != "" {
+		statement += " " + t.Options
+	}
+	return statement
+}
+
+// constraintKeywords start table constraints rather than column definitions
+var constraintKeywords = map[string]bool{
+	"CONSTRAINT": true, "PRIMARY": true, "UNIQUE": true, "CHECK": true, "FOREIGN": true,
+}
+
+// parseCreateTable splits a CREATE TABLE statement into columns, table
+// constraints and table options. Comments are dropped.
+func parseCreateTable(createSQL string) (*TableDefinition, error) {
+	loc := createTablePattern.FindStringSubmatchIndex(createSQL)
+	if loc == nil {
+		return nil, fmt.Errorf("not a CREATE TABLE statement: %s", createSQL)
+	}
+	table := &TableDefinition{Name: unquoteIdentifier(createSQL[loc[2]:loc[3]])}
+
+	rest := createSQL[loc[1]:]
+	open := strings.Index(rest, "(")
+	if open < 0 || strings.TrimSpace(rest[:open]) != "" {
+		return nil, fmt.Errorf("table %s: CREATE TABLE ... AS SELECT cannot be rebuilt", table.Name)
+	}
+
+	items, end, err := splitDefinitions(rest[open+1:])
+	if err != nil {
+		return nil, fmt.Errorf("table %s: %w", table.Name, err)
+	}
+	table.Options = strings.TrimSpace(rest[open+1+end+1:])
+
+	for _, item := range items {
+		name, definition := splitName(item)
+		if constraintKeywords[strings.ToUpper(name)] && !strings.HasPrefix(item, `"`) && !strings.HasPrefix(item, "`") && !strings.HasPrefix(item, "[") {
+			table.Constraints = append(table.Constraints, item)
+			continue
+		}
+		table.Columns = append(table.Columns, ColumnDefinition{Name: unquoteIdentifier(name), Definition: definition})
+	}
+
+	return table, nil
+}
+
+// splitDefinitions splits the column list that follows the opening parenthesis
+// at top-level commas and returns the offset of the closing parenthesis
+func splitDefinitions(s string) ([]string, int, error) {
+	var items []string
+	var current []byte
+	depth := 0
+
+	// space collapses whitespace and comments outside quotes into single spaces
+	space := func() {
+		if len(current) > 0 && current[len(current)-1] != ' ' {
+			current = append(current, ' ')
+		}
+	}
+	flush := func() {
+		if item := strings.TrimSpace(string(current)); item != "" {
+			items = append(items, item)
+		}
+		current = current[:0]
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			j := i + 1
+			for ; j < len(s); j++ {
+				if s[j] == closing {
+					// Doubled quotes are escapes inside the literal
+					if closing != ']' && j+1 < len(s) && s[j+1] == closing {
+						j++
+						continue
+					}
+					break
+				}
+			}
+			if j >= len(s) {
+				return nil, 0, errors.New("unterminated quoted string")
+			}
+			current = append(current, s[i:j+1]...)
+			i = j
+		case c == '-' && i+1 < len(s) && s[i+1] == '-':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+			space()
+		case c == '/' && i+1 < len(s) && s[i+1] == '*':
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				return nil, 0, errors.New("unterminated comment")
+			}
+			i += end + 3
+			space()
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space()
+		case c == '(':
+			depth++
+			current = append(current, c)
+		case c == ')' && depth == 0:
+			flush()
+			return items, i, nil
+		case c == ')':
+			depth--
+			current = append(current, c)
+		case c == ',' && depth == 0:
+			flush()
+		default:
+			current = append(current, c)
+		}
+	}
+
+	return nil, 0, errors.New("missing closing parenthesis")
+}
+
+// splitName separates the leading, possibly quoted, identifier of a definition
+func splitName(item string) (string, string) {
+	end := strings.IndexAny(item, " \t\n")
+	switch item[0] {
+	case '"', '`', '[':
+		closing := item[0]
+		if closing == '[' {
+			closing = ']'
+		}
+		for i := 1; i < len(item); i++ {
+			if item[i] == closing {
+				if closing != ']' && i+1 < len(item) && item[i+1] == closing {
+					i++
+					continue
+				}
+				end = i + 1
+				break
+			}
+		}
+	}
+	if end < 0 || end >= len(item) {
+		return item, ""
+	}
+	return item[:end], strings.TrimSpace(item[end:])
+}
+
+func unquoteIdentifier(s string) string {
+	if len(s) >= 2 {
+		switch {
+		case s[0] == '"' && s[len(s)-1] == '"':
+			return strings.ReplaceAll(s[1:len(s)-1], `""`, `"`)
+		case s[0] == '`' && s[len(s)-1] == '`':
+			return strings.ReplaceAll(s[1:len(s)-1], "``", "`")
+		case s[0] == '[' && s[len(s)-1] == ']':
+			return s[1 : len(s)-1]
+		}
+	}
+	return s
+}
+
+// renameCreateTable rewrites a CREATE TABLE statement to create a table called name
+func renameCreateTable(createSQL, name string) (string, error) {
+	loc := createTablePattern.FindStringSubmatchIndex(createSQL)
+	if loc == nil {
+		return "", fmt.Errorf("not a CREATE TABLE statement: %s", createSQL)
+	}
+	return createSQL[:loc[2]] + ident(name) + createSQL[loc[3]:], nil
+}
+
+// RebuildTable changes a table in ways ALTER TABLE cannot, such as dropping a
+// constrained column or changing a column type, using the procedure from
+// https://www.sqlite.org/lang_altertable.html#otheralter. alter receives the
+// current definition and edits it in place:
+//
+//	err := migrate.RebuildTable(ctx, db, "users", func(t *migrate.TableDefinition) error {
+//		t.Column("age").Definition = "INTEGER NOT NULL DEFAULT 0"
+//		t.Column("age").From = "COALESCE(age, 0)"
+//		return t.DropColumn("legacy_id")
+//	})
+//
+// Indexes, triggers and views are recreated, except indexes on dropped
+// columns. Foreign keys are switched off during the rebuild and checked
+// before committing when they were on.
+func RebuildTable(ctx context.Context, db *sql.DB, table string, alter func(*TableDefinition) error) (err error) {
+	// PRAGMA foreign_keys only applies outside a transaction and to one connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	var foreignKeys bool
+	if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+		return fmt.Errorf("failed to read foreign_keys: %w", err)
+	}
+	if foreignKeys {
+		if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+			return fmt.Errorf("failed to disable foreign keys: %w", err)
+		}
+		defer func() {
+			if _, enableErr := conn.ExecContext(context.Background(), "PRAGMA foreign_keys = ON"); enableErr != nil && err == nil {
+				err = fmt.Errorf("failed to enable foreign keys: %w", enableErr)
+			}
+		}()
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements, err := planRebuild(tx, table, alter)
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to rebuild %s: %s: %w", table, strings.Join(strings.Fields(statement), " "), err)
+		}
+	}
+
+	if foreignKeys {
+		var violations int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM pragma_foreign_key_check").Scan(&violations); err != nil {
+			return fmt.Errorf("failed to check foreign keys: %w", err)
+		}
+		if violations > 0 {
+			return fmt.Errorf("rebuilding %s would leave %d foreign key violation(s)", table, violations)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit rebuild of %s: %w", table, err)
+	}
+
+	return nil
+}
+
+// RebuildSQL returns up and down SQL that rebuild table in the named database
+// with its current definition, ready to be edited into the change ALTER TABLE
+// cannot make
+func RebuildSQL(name, table string) (string, string, error) {
+	target, err := LookupDatabase(name)
+	if err != nil {
+		return "", "", err
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+target.URL+"?mode=ro")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	statements, err := planRebuild(db, table, nil)
+	if err != nil {
+		return "", "", err
+	}
+
+	var body strings.Builder
+	for _, statement := range statements {
+		body.WriteString(statement + ";\n")
+	}
+
+	up := fmt.Sprintf("-- Rebuild %s: edit new_%s and the INSERT below. The statements after the\n"+
+		"-- rename recreate its indexes, triggers and views; remove any that use dropped columns.\n%s", table, table, body.String())
+
+	// Rolling back rebuilds the table with its current definition
+	down := fmt.Sprintf("-- Rebuild %s with its definition from before the migration\n%s", table, body.String())
+
+	return up, down, nil
+}
+
+// planRebuild reads table from db, applies alter (when set) and returns the rebuild statements
+func planRebuild(db querier, table string, alter func(*TableDefinition) error) ([]string, error) {
+	old := tableDef{Name: table}
+	if err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&old.SQL); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("table %s does not exist", table)
+		}
+		return nil, fmt.Errorf("failed to read table %s: %w", table, err)
+	}
+	if err := readTable(db, &old); err != nil {
+		return nil, fmt.Errorf("failed to read table %s: %w", table, err)
+	}
+
+	definition, err := parseCreateTable(old.SQL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without changes the original statement is kept, comments and all
+	createSQL := old.SQL
+	if alter != nil {
+		if err := alter(definition); err != nil {
+			return nil, err
+		}
+		definition.Name = table
+		createSQL = definition.SQL()
+	}
+
+	var columns, exprs []string
+	for _, c := range definition.Columns {
+		switch {
+		case c.From != "":
+			columns = append(columns, c.Name)
+			exprs = append(exprs, c.From)
+		case hasColumn(old, c.Name):
+			columns = append(columns, c.Name)
+			exprs = append(exprs, ident(c.Name))
+		}
+	}
+
+	var recreate []string
+	for _, idx := range old.Indexes {
+		statement, keep, err := rebuiltIndex(table, idx, definition)
+		if err != nil {
+			return nil, err
+		}
+		if keep {
+			recreate = append(recreate, statement)
+		}
+	}
+	recreate = append(recreate, old.Triggers...)
+
+	return rebuildStatements(table, createSQL, columns, exprs, recreate, old.Views)
+}
+
+// rebuiltIndex returns the statement that recreates idx on the new definition,
+// or false when a column it uses was dropped
+func rebuiltIndex(table string, idx indexDef, definition *TableDefinition) (string, bool, error) {
+	renamed := false
+	columns := make([]string, len(idx.Columns))
+	for i, column := range idx.Columns {
+		newName, ok := definition.renamed[strings.ToLower(column)]
+		switch {
+		case ok:
+			renamed = true
+			columns[i] = newName
+		case column == "" || definition.Column(column) != nil:
+			columns[i] = column
+		default:
+			return "", false, nil
+		}
+	}
+
+	if !renamed {
+		return idx.SQL, true, nil
+	}
+	if idx.Partial {
+		return "", false, fmt.Errorf("index %s is partial and uses a renamed column, drop it and recreate it after the rebuild", idx.Name)
+	}
+	for _, column := range columns {
+		if column == "" {
+			return "", false, fmt.Errorf("index %s is on an expression and uses a renamed column, drop it and recreate it after the rebuild", idx.Name)
+		}
+	}
+	return createIndexSQL(table, indexDef{Name: idx.Name, Unique: idx.Unique, Columns: columns}), true, nil
+}
+
+func hasColumn(t tableDef, name string) bool {
+	for _, c := range t.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// rebuildStatements returns the sequence SQLite recommends for schema changes
+// ALTER TABLE cannot make (https://www.sqlite.org/lang_altertable.html#otheralter):
+// drop the views that use the table, create the new table under a temporary
+// name, copy the rows across, drop the old table, rename the new one and
+// recreate indexes, triggers and views.
+//
+// columns lists the columns to copy and exprs the matching SELECT expressions
+// on the old table. recreate holds the CREATE INDEX and CREATE TRIGGER
+// statements to run once the table is back under its own name.
+func rebuildStatements(table, createSQL string, columns, exprs, recreate []string, views []viewDef) ([]string, error) {
+	tempName := "new_" + table
+	create, err := renameCreateTable(createSQL, tempName)
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []string
+	for _, view := range views {
+		statements = append(statements, "DROP VIEW "+ident(view.Name))
+	}
+
+	statements = append(statements, create)
+	if len(columns) > 0 {
+		quoted := make([]string, len(columns))
+		for i, column := range columns {
+			quoted[i] = ident(column)
+		}
+		statements = append(statements, fmt.Sprintf("INSERT INTO %s (%s)\n    SELECT %s FROM %s",
+			ident(tempName), strings.Join(quoted, ", "), strings.Join(exprs, ", "), ident(table)))
+	}
+	statements = append(statements,
+		fmt.Sprintf("DROP TABLE %s", ident(table)),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", ident(tempName), ident(table)),
+	)
+	statements = append(statements, recreate...)
+
+	for _, view := range views {
+		statements = append(statements, view.SQL)
+	}
+
+	return statements, nil
+}
+
+// identPattern matches identifiers that never need quoting
+var identPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// reservedWords are keywords likely to be used as column names
+var reservedWords = map[string]bool{
+	"check": true, "default": true, "from": true, "group": true, "index": true, "key": true,
+	"limit": true, "order": true, "primary": true, "references": true, "select": true,
+	"table": true, "to": true, "unique": true, "values": true, "where": true,
+}
+
+// ident quotes an identifier only when SQLite needs it to
+func ident(name string) string {
+	if identPattern.MatchString(name) && !reservedWords[strings.ToLower(name)] {
+		return name
+	}
+	return quoteIdentifier(name)
+}
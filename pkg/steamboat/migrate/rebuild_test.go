@@ -0,0 +1,282 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const rebuildSchema = `
+CREATE TABLE users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	email TEXT NOT NULL UNIQUE,
+	name TEXT, -- display name
+	age TEXT,
+	legacy_id INTEGER
+);
+CREATE INDEX idx_users_name ON users (name);
+CREATE INDEX idx_users_legacy_id ON users (legacy_id);
+CREATE TABLE audit (user_id INTEGER, action TEXT);
+CREATE TRIGGER users_audit AFTER INSERT ON users BEGIN
+	INSERT INTO audit (user_id, action) VALUES (new.id, 'insert');
+END;
+CREATE VIEW adults AS SELECT id, email FROM users WHERE age >= 18;
+CREATE TABLE posts (
+	id INTEGER PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE
+);
+INSERT INTO users (email, name, age, legacy_id) VALUES ('a@example.com', 'Ann', '42', 7), ('b@example.com', NULL, NULL, 8);
+INSERT INTO posts (user_id) VALUES (1), (2);
+`
+
+func setupRebuildTest(t *testing.T) *sql.DB {
+	path := filepath.Join(t.TempDir(), "rebuild.db")
+	db, err := sql.Open("sqlite3", "file:"+path+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(rebuildSchema); err != nil {
+		t.Fatalf("Failed to create test schema: %v", err)
+	}
+
+	return db
+}
+
+func schemaObjects(t *testing.T, db *sql.DB) map[string]string {
+	schema, err := readSchema(db)
+	if err != nil {
+		t.Fatalf("Failed to read schema: %v", err)
+	}
+	return schema
+}
+
+func TestParseCreateTable(t *testing.T) {
+	table, err := parseCreateTable(`CREATE TABLE IF NOT EXISTS "order items" (
+		"item id" INTEGER NOT NULL, -- comment, with a comma
+		note TEXT DEFAULT 'a,  b',
+		price NUMERIC(10, 2) CHECK (price >= 0),
+		/* block comment */
+		PRIMARY KEY ("item id"),
+		FOREIGN KEY (note) REFERENCES notes (body)
+	) WITHOUT ROWID`)
+	if err != nil {
+		t.Fatalf("parseCreateTable failed: %v", err)
+	}
+
+	if table.Name != "order items" {
+		t.Errorf("Expected name 'order items', got %q", table.Name)
+	}
+
+	want := []ColumnDefinition{
+		{Name: "item id", Definition: "INTEGER NOT NULL"},
+		{Name: "note", Definition: "TEXT DEFAULT 'a,  b'"},
+		{Name: "price", Definition: "NUMERIC(10, 2) CHECK (price >= 0)"},
+	}
+	if len(table.Columns) != len(want) {
+		t.Fatalf("Expected %d columns, got %+v", len(want), table.Columns)
+	}
+	for i, column := range want {
+		if table.Columns[i] != column {
+			t.Errorf("Expected column %+v, got %+v", column, table.Columns[i])
+		}
+	}
+
+	if len(table.Constraints) != 2 || table.Constraints[0] != `PRIMARY KEY ("item id")` {
+		t.Errorf("Unexpected constraints: %q", table.Constraints)
+	}
+	if table.Options != "WITHOUT ROWID" {
+		t.Errorf("Expected WITHOUT ROWID, got %q", table.Options)
+	}
+}
+
+func TestRebuildTableDropColumn(t *testing.T) {
+	db := setupRebuildTest(t)
+
+	err := RebuildTable(context.Background(), db, "users", func(table *TableDefinition) error {
+		return table.DropColumn("legacy_id")
+	})
+	if err != nil {
+		t.Fatalf("RebuildTable failed: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM users WHERE email IN ('a@example.com', 'b@example.com')`).Scan(&count); err != nil || count != 2 {
+		t.Errorf("Expected rows to be copied, got %d (%v)", count, err)
+	}
+	if _, err := db.Exec(`SELECT legacy_id FROM users`); err == nil {
+		t.Error("Expected legacy_id to be dropped")
+	}
+
+	schema := schemaObjects(t, db)
+	if _, ok := schema["index idx_users_name"]; !ok {
+		t.Error("Expected idx_users_name to be recreated")
+	}
+	if _, ok := schema["index idx_users_legacy_id"]; ok {
+		t.Error("Expected the index on the dropped column to be removed")
+	}
+	if _, ok := schema["trigger users_audit"]; !ok {
+		t.Error("Expected the trigger to be recreated")
+	}
+	if err := db.QueryRow(`SELECT COUNT(*) FROM adults`).Scan(&count); err != nil || count != 1 {
+		t.Errorf("Expected the view to keep working, got %d (%v)", count, err)
+	}
+
+	// The unique constraint is part of the table definition and survives
+	if _, err := db.Exec(`INSERT INTO users (email) VALUES ('a@example.com')`); err == nil {
+		t.Error("Expected UNIQUE constraint on email to be preserved")
+	}
+
+	// Foreign keys are back on and still point at the rebuilt table
+	var foreignKeys bool
+	if err := db.QueryRow(`PRAGMA foreign_keys`).Scan(&foreignKeys); err != nil || !foreignKeys {
+		t.Errorf("Expected foreign keys to be enabled again, got %v (%v)", foreignKeys, err)
+	}
+	if _, err := db.Exec(`DELETE FROM users WHERE email = 'b@example.com'`); err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+	if err := db.QueryRow(`SELECT COUNT(*) FROM posts`).Scan(&count); err != nil || count != 1 {
+		t.Errorf("Expected ON DELETE CASCADE to remove one post, %d left (%v)", count, err)
+	}
+}
+
+func TestRebuildTableChangeColumn(t *testing.T) {
+	db := setupRebuildTest(t)
+
+	err := RebuildTable(context.Background(), db, "users", func(table *TableDefinition) error {
+		age := table.Column("age")
+		age.Definition = "INTEGER NOT NULL DEFAULT 0"
+		age.From = "COALESCE(CAST(age AS INTEGER), 0)"
+		table.AddColumn("active", "INTEGER NOT NULL DEFAULT 1")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RebuildTable failed: %v", err)
+	}
+
+	rows, err := db.Query(`SELECT age, typeof(age), active FROM users ORDER BY id`)
+	if err != nil {
+		t.Fatalf("Failed to query users: %v", err)
+	}
+	defer rows.Close()
+
+	var got []string
+	for rows.Next() {
+		var age, kind string
+		var active int
+		if err := rows.Scan(&age, &kind, &active); err != nil {
+			t.Fatalf("Failed to scan user: %v", err)
+		}
+		if active != 1 {
+			t.Errorf("Expected new column to get its default, got %d", active)
+		}
+		got = append(got, age+":"+kind)
+	}
+	if strings.Join(got, ",") != "42:integer,0:integer" {
+		t.Errorf("Expected converted ages 42:integer,0:integer, got %s", strings.Join(got, ","))
+	}
+}
+
+func TestRebuildTableRenameColumn(t *testing.T) {
+	db := setupRebuildTest(t)
+
+	err := RebuildTable(context.Background(), db, "users", func(table *TableDefinition) error {
+		return table.RenameColumn("name", "display_name")
+	})
+	if err != nil {
+		t.Fatalf("RebuildTable failed: %v", err)
+	}
+
+	var name string
+	if err := db.QueryRow(`SELECT display_name FROM users WHERE email = 'a@example.com'`).Scan(&name); err != nil || name != "Ann" {
+		t.Errorf("Expected renamed column to keep its data, got %q (%v)", name, err)
+	}
+
+	schema := schemaObjects(t, db)
+	if !strings.Contains(schema["index idx_users_name"], "display_name") {
+		t.Errorf("Expected idx_users_name to use the renamed column, got %q", schema["index idx_users_name"])
+	}
+}
+
+func TestRebuildTableForeignKeyViolation(t *testing.T) {
+	db := setupRebuildTest(t)
+	before := schemaObjects(t, db)
+
+	err := RebuildTable(context.Background(), db, "users", func(table *TableDefinition) error {
+		table.Constraints = append(table.Constraints, "FOREIGN KEY (legacy_id) REFERENCES posts (id)")
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "foreign key violation") {
+		t.Fatalf("Expected foreign key violation, got %v", err)
+	}
+
+	if diffs := diffSchema(before, schemaObjects(t, db)); len(diffs) > 0 {
+		t.Errorf("Expected the rebuild to be rolled back, got %v", diffs)
+	}
+}
+
+func TestRebuildTableAlterError(t *testing.T) {
+	db := setupRebuildTest(t)
+	before := schemaObjects(t, db)
+
+	boom := errors.New("boom")
+	err := RebuildTable(context.Background(), db, "users", func(table *TableDefinition) error {
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("Expected alter error, got %v", err)
+	}
+
+	if err := RebuildTable(context.Background(), db, "missing", func(table *TableDefinition) error { return nil }); err == nil {
+		t.Error("Expected error for a missing table")
+	}
+
+	if diffs := diffSchema(before, schemaObjects(t, db)); len(diffs) > 0 {
+		t.Errorf("Expected schema to be unchanged, got %v", diffs)
+	}
+}
+
+func TestRebuildSQLKeepsSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.db")
+	t.Setenv("DB_URL", path)
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(rebuildSchema); err != nil {
+		t.Fatalf("Failed to create test schema: %v", err)
+	}
+	before := schemaObjects(t, db)
+
+	up, down, err := RebuildSQL("", "users")
+	if err != nil {
+		t.Fatalf("RebuildSQL failed: %v", err)
+	}
+	if !strings.Contains(up, "CREATE TABLE new_users") || !strings.Contains(down, "ALTER TABLE new_users RENAME TO users") {
+		t.Fatalf("Unexpected rebuild SQL:\n%s", up)
+	}
+
+	// An unedited rebuild is a no-op, in both directions
+	for _, script := range []string{up, down} {
+		if _, err := db.Exec(script); err != nil {
+			t.Fatalf("Failed to run rebuild SQL: %v\n%s", err, script)
+		}
+		if diffs := diffSchema(before, schemaObjects(t, db)); len(diffs) > 0 {
+			t.Errorf("Expected unchanged schema, got %v\n%s", diffs, script)
+		}
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil || count != 2 {
+		t.Errorf("Expected 2 users after rebuilding, got %d (%v)", count, err)
+	}
+}
@@ -0,0 +1,396 @@
+package migrate
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+const (
+	// baselineName is the name given to squashed migrations, e.g. "000042_baseline"
+	baselineName = "baseline"
+
+	// createdFormat is the layout of the creation date in the baseline header
+	createdFormat = "2006-01-02 15:04:05"
+)
+
+// SquashResult describes the files written and removed by Squash
+type SquashResult struct {
+	Baseline Migration
+	Removed  []string
+}
+
+// Squash replaces every migration up to and including version with a single
+// baseline migration generated from the schema (and any rows) they produce.
+// The baseline keeps the version of the last squashed migration, so databases
+// that are already at or past it never run it.
+func Squash(name string, version uint) (*SquashResult, error) {
+	// The URL is optional here, it is only used to check the database is not left behind
+	target, _ := LookupDatabase(name)
+
+	migrations, err := readMigrations(target.MigrationsPath)
+	if err != nil {
+		return nil, err
+	}
+	if issues := checkFiles(target.MigrationsPath, migrations); len(issues) > 0 {
+		return nil, &ConflictError{Issues: issues}
+	}
+
+	var squashed []Migration
+	var last *Migration
+	for i, m := range migrations {
+		if m.Version <= version {
+			squashed = append(squashed, m)
+			last = &migrations[i]
+		}
+	}
+	if last == nil || last.Version != version {
+		return nil, fmt.Errorf("no migration with version %d in %s", version, target.MigrationsPath)
+	}
+	if len(squashed) < 2 && last.Name == baselineName {
+		return nil, fmt.Errorf("migrations up to version %d are already squashed", version)
+	}
+
+	// A database that is part-way through the squashed range could never be upgraded again
+	if target.URL != "" {
+		if current, err := readVersion(target.URL); err == nil && current > 0 && current < version {
+			return nil, fmt.Errorf("database %s is at version %d, migrate it to at least %d before squashing", target.URL, current, version)
+		}
+	}
+
+	up, down, err := buildBaseline(squashed)
+	if err != nil {
+		return nil, err
+	}
+
+	width := len(last.ID) - len(last.Name) - 1
+	id := fmt.Sprintf("%0*d_%s", width, version, baselineName)
+	baseline := Migration{
+		ID:       id,
+		Version:  version,
+		Name:     baselineName,
+		UpPath:   filepath.Join(target.MigrationsPath, id+".up.sql"),
+		DownPath: filepath.Join(target.MigrationsPath, id+".down.sql"),
+	}
+
+	header := fmt.Sprintf("-- Baseline: squashed %d migration(s) up to version %d\n-- Created: %s\n\n",
+		len(squashed), version, time.Now().UTC().Format(createdFormat))
+
+	// Write the baseline before removing anything, so a failure never leaves
+	// the squashed migrations gone without a replacement
+	if err := writeFileAtomic(baseline.UpPath, []byte(header+up)); err != nil {
+		return nil, fmt.Errorf("failed to write baseline: %w", err)
+	}
+	if err := writeFileAtomic(baseline.DownPath, []byte(header+down)); err != nil {
+		os.Remove(baseline.UpPath)
+		return nil, fmt.Errorf("failed to write baseline: %w", err)
+	}
+
+	result := &SquashResult{Baseline: baseline}
+	for _, m := range squashed {
+		for _, path := range []string{m.UpPath, m.DownPath} {
+			// A previous baseline with the same version has just been replaced
+			if path == baseline.UpPath || path == baseline.DownPath {
+				continue
+			}
+			if err := os.Remove(path); err != nil {
+				return nil, fmt.Errorf("failed to remove %s: %w", path, err)
+			}
+			result.Removed = append(result.Removed, path)
+		}
+	}
+
+	return result, nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so path never holds a partial file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// buildBaseline applies migrations to a scratch database and dumps the result
+func buildBaseline(migrations []Migration) (string, string, error) {
+	sandboxDir, err := os.MkdirTemp("", "steamboat-squash-")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create sandbox directory: %w", err)
+	}
+	defer os.RemoveAll(sandboxDir)
+
+	// Copy only the squashed files so the migrator stops at the baseline version
+	sourceDir := filepath.Join(sandboxDir, "migrations")
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create sandbox directory: %w", err)
+	}
+	for _, m := range migrations {
+		for _, path := range []string{m.UpPath, m.DownPath} {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return "", "", fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			if err := os.WriteFile(filepath.Join(sourceDir, filepath.Base(path)), content, 0644); err != nil {
+				return "", "", fmt.Errorf("failed to copy %s: %w", path, err)
+			}
+		}
+	}
+
+	sandboxPath := filepath.Join(sandboxDir, "squash.db")
+	m, err := newMigrator(sourceDir, sandboxPath)
+	if err != nil {
+		return "", "", err
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return "", "", fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	db, err := sql.Open("sqlite3", sandboxPath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to open sandbox database: %w", err)
+	}
+	defer db.Close()
+
+	return dumpDatabase(db)
+}
+
+// schemaObject is one row of sqlite_master
+type schemaObject struct {
+	kind       string
+	name       string
+	definition string
+	virtual    bool
+}
+
+// contentOption finds the content= option of an FTS table, whose rows live in
+// another table (content="docs") or nowhere at all for contentless tables
+var contentOption = regexp.MustCompile(`(?i)\bcontent\s*=\s*('[^']*'|"[^"]*"|\w+)`)
+
+// dumpDatabase returns SQL that recreates the schema and rows of db, and SQL
+// that removes them again. Like the sqlite3 .dump command it creates the
+// tables, fills them and only then adds the indexes, triggers and views, so no
+// trigger fires for the rows being restored.
+func dumpDatabase(db *sql.DB) (string, string, error) {
+	// The shadow tables of virtual tables, e.g. search_content of an FTS index,
+	// are recreated by the virtual table itself
+	rows, err := db.Query(`SELECT m.type, m.name, m.sql, COALESCE(l.type, '') FROM sqlite_master m
+		LEFT JOIN pragma_table_list l ON m.type = 'table' AND l.schema = 'main' AND l.name = m.name
+		WHERE m.sql IS NOT NULL AND m.name NOT LIKE 'sqlite_%' AND m.tbl_name NOT IN ('schema_migrations', '` + historyTable + `')
+			AND (m.type != 'table' OR l.type IN ('table', 'virtual'))
+		ORDER BY m.type != 'table', m.rowid`)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	var objects []schemaObject
+	for rows.Next() {
+		var object schemaObject
+		var tableType string
+		if err := rows.Scan(&object.kind, &object.name, &object.definition, &tableType); err != nil {
+			rows.Close()
+			return "", "", fmt.Errorf("failed to read schema: %w", err)
+		}
+		object.virtual = tableType == "virtual"
+		objects = append(objects, object)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", "", fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	var up, down strings.Builder
+	for _, object := range objects {
+		if object.kind == "table" {
+			up.WriteString(object.definition + ";\n\n")
+		}
+	}
+
+	var rebuilds []string
+	for _, object := range objects {
+		if object.kind != "table" {
+			continue
+		}
+		if object.virtual {
+			// An FTS index over another table is rebuilt from it once its rows are back
+			if match := contentOption.FindStringSubmatch(object.definition); match != nil {
+				if match[1] != "''" && match[1] != `""` {
+					name := quoteIdentifier(object.name)
+					rebuilds = append(rebuilds, fmt.Sprintf("INSERT INTO %s (%s) VALUES ('rebuild')", name, name))
+				}
+				continue
+			}
+		}
+
+		inserts, err := dumpRows(db, object.name, object.virtual)
+		if err != nil {
+			return "", "", err
+		}
+		for _, insert := range inserts {
+			up.WriteString(insert + ";\n")
+		}
+		if len(inserts) > 0 {
+			up.WriteString("\n")
+		}
+	}
+	for _, rebuild := range rebuilds {
+		up.WriteString(rebuild + ";\n\n")
+	}
+
+	for _, object := range objects {
+		if object.kind != "table" {
+			up.WriteString(object.definition + ";\n\n")
+		}
+	}
+
+	// Drop in reverse order; indexes, triggers and shadow tables go with their tables
+	for i := len(objects) - 1; i >= 0; i-- {
+		switch objects[i].kind {
+		case "table":
+			fmt.Fprintf(&down, "DROP TABLE IF EXISTS %s;\n", quoteIdentifier(objects[i].name))
+		case "view":
+			fmt.Fprintf(&down, "DROP VIEW IF EXISTS %s;\n", quoteIdentifier(objects[i].name))
+		}
+	}
+
+	return up.String(), down.String(), nil
+}
+
+// dumpRows returns an INSERT statement for every row in table. The rows of a
+// virtual table keep their rowid, which SELECT * leaves out but FTS indexes
+// are joined on.
+func dumpRows(db *sql.DB, table string, virtual bool) ([]string, error) {
+	query := "SELECT * FROM " + quoteIdentifier(table)
+	if virtual {
+		query = "SELECT rowid, * FROM " + quoteIdentifier(table)
+	}
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", table, err)
+	}
+
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteIdentifier(column)
+	}
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES", quoteIdentifier(table), strings.Join(quoted, ", "))
+
+	var inserts []string
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", table, err)
+		}
+
+		literals := make([]string, len(values))
+		for i, value := range values {
+			literals[i] = sqlLiteral(value)
+		}
+		inserts = append(inserts, fmt.Sprintf("%s (%s)", prefix, strings.Join(literals, ", ")))
+	}
+
+	return inserts, rows.Err()
+}
+
+// sqlLiteral formats a value scanned from SQLite as a SQL literal
+func sqlLiteral(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case []byte:
+		return "X'" + hex.EncodeToString(v) + "'"
+	case time.Time:
+		return quoteString(v.Format("2006-01-02 15:04:05.999999999-07:00"))
+	case string:
+		return quoteString(v)
+	}
+	return quoteString(fmt.Sprint(value))
+}
+
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func quoteIdentifier(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// readVersion returns the version of an existing database without creating it
+// or the migrations table
+func readVersion(dbURL string) (uint, error) {
+	version, _, err := readState(dbURL)
+	return version, err
+}
+
+// readState returns the version and dirty flag of an existing database
+// without creating it or the migrations table
+func readState(dbURL string) (uint, bool, error) {
+	if _, err := os.Stat(dbURL); err != nil {
+		return 0, false, err
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+dbURL+"?mode=ro")
+	if err != nil {
+		return 0, false, err
+	}
+	defer db.Close()
+
+	var exists int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`).Scan(&exists); err != nil {
+		return 0, false, err
+	}
+	if exists == 0 {
+		return 0, false, nil
+	}
+
+	var version uint
+	var dirty bool
+	err = db.QueryRow(`SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
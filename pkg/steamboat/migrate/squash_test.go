@@ -0,0 +1,260 @@
+package migrate
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var squashFiles = map[string]string{
+	"000001_create_users.up.sql": `CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, email TEXT NOT NULL UNIQUE, avatar BLOB, score REAL);
+CREATE TABLE audit (entry TEXT NOT NULL UNIQUE);
+CREATE TRIGGER users_audit AFTER INSERT ON users BEGIN INSERT INTO audit (entry) VALUES (new.email); END;`,
+	"000001_create_users.down.sql": `DROP TABLE audit; DROP TABLE users;`,
+	"000002_seed_roles.up.sql": `CREATE TABLE roles (name TEXT PRIMARY KEY, note TEXT);
+INSERT INTO roles (name, note) VALUES ('admin', NULL), ('o''brien', 'quoted');
+INSERT INTO users (email, avatar, score) VALUES ('a@example.com', X'cafe', 1.5);`,
+	"000002_seed_roles.down.sql": `DROP TABLE roles; DELETE FROM users;`,
+	"000003_create_views.up.sql": `CREATE INDEX idx_users_score ON users (score);
+CREATE VIEW admins AS SELECT name FROM roles WHERE name = 'admin';`,
+	"000003_create_views.down.sql": `DROP VIEW admins; DROP INDEX idx_users_score;`,
+}
+
+// dumpTables returns every row of the user tables of the database at path as text
+func dumpTables(t *testing.T, path string) string {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var b strings.Builder
+	for _, table := range []string{"users", "audit", "roles", "sqlite_sequence"} {
+		inserts, err := dumpRows(db, table, false)
+		if err != nil {
+			t.Fatalf("Failed to dump %s: %v", table, err)
+		}
+		b.WriteString(strings.Join(inserts, "\n") + "\n")
+	}
+	return b.String()
+}
+
+func schemaOf(t *testing.T, path string) map[string]string {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	schema, err := readSchema(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func TestSquashReproducesSchemaAndRows(t *testing.T) {
+	dbPath := setupProject(t, squashFiles)
+	if err := Run(""); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	wantSchema, wantRows := schemaOf(t, dbPath), dumpTables(t, dbPath)
+
+	result, err := Squash("", 3)
+	if err != nil {
+		t.Fatalf("Squash failed: %v", err)
+	}
+	if result.Baseline.ID != "000003_baseline" || len(result.Removed) != 6 {
+		t.Errorf("Expected 000003_baseline to replace 6 files, got %s and %v", result.Baseline.ID, result.Removed)
+	}
+	if entries, _ := os.ReadDir(migrationsPath); len(entries) != 2 {
+		t.Errorf("Expected only the baseline to be left, got %d files", len(entries))
+	}
+
+	// The migrated database is already at the baseline version
+	if issues, err := Check(""); err != nil || len(issues) > 0 {
+		t.Errorf("Expected the migrated database to accept the baseline, got %+v (%v)", issues, err)
+	}
+
+	// A new database built from the baseline matches the one built from the migrations
+	fresh := filepath.Join(t.TempDir(), "fresh.db")
+	t.Setenv("DB_URL", fresh)
+	if err := Run(""); err != nil {
+		t.Fatalf("Run of the baseline failed: %v", err)
+	}
+	if diffs := diffSchema(wantSchema, schemaOf(t, fresh)); len(diffs) > 0 {
+		t.Errorf("Expected the baseline to recreate the schema, got %v", diffs)
+	}
+	if got := dumpTables(t, fresh); got != wantRows {
+		t.Errorf("Expected the baseline to recreate the rows\nwant:\n%s\ngot:\n%s", wantRows, got)
+	}
+
+	if err := Rollback(""); err != nil {
+		t.Fatalf("Rollback of the baseline failed: %v", err)
+	}
+	if schema := schemaOf(t, fresh); len(schema) > 0 {
+		t.Errorf("Expected the baseline down to remove everything, got %v", schema)
+	}
+}
+
+func TestSquashRefusesDatabaseInsideRange(t *testing.T) {
+	dbPath := setupProject(t, map[string]string{
+		"000001_create_users.up.sql":   squashFiles["000001_create_users.up.sql"],
+		"000001_create_users.down.sql": squashFiles["000001_create_users.down.sql"],
+	})
+	if err := Run(""); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	for name, content := range squashFiles {
+		writeMigration(t, name, content)
+	}
+
+	if _, err := Squash("", 3); err == nil || err.Error() != "database "+dbPath+" is at version 1, migrate it to at least 3 before squashing" {
+		t.Fatalf("Expected the database at version 1 to block the squash, got %v", err)
+	}
+	if entries, _ := os.ReadDir(migrationsPath); len(entries) != 6 {
+		t.Errorf("Expected the migrations to be left alone, got %d files", len(entries))
+	}
+
+	// Squashing what the database has already applied is fine, and it can still be upgraded
+	if _, err := Squash("", 1); err != nil {
+		t.Fatalf("Squash failed: %v", err)
+	}
+	if err := Run(""); err != nil {
+		t.Fatalf("Run after squash failed: %v", err)
+	}
+	if version, _, err := Status(""); err != nil || version != 3 {
+		t.Errorf("Expected version 3, got %d (%v)", version, err)
+	}
+
+	if _, err := Squash("", 1); err == nil || !strings.Contains(err.Error(), "already squashed") {
+		t.Errorf("Expected a second squash to the same version to be refused, got %v", err)
+	}
+	if _, err := Squash("", 5); err == nil || !strings.Contains(err.Error(), "no migration with version 5") {
+		t.Errorf("Expected an unknown version to be refused, got %v", err)
+	}
+}
+
+func TestCheckDatabaseBehindBaseline(t *testing.T) {
+	setupProject(t, map[string]string{
+		"000001_create_users.up.sql":   squashFiles["000001_create_users.up.sql"],
+		"000001_create_users.down.sql": squashFiles["000001_create_users.down.sql"],
+	})
+	if err := Run(""); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	// Another checkout squashed up to version 3 before this database got there
+	os.Remove(filepath.Join(migrationsPath, "000001_create_users.up.sql"))
+	os.Remove(filepath.Join(migrationsPath, "000001_create_users.down.sql"))
+	writeMigration(t, "000003_baseline.up.sql", squashFiles["000001_create_users.up.sql"])
+	writeMigration(t, "000003_baseline.down.sql", squashFiles["000001_create_users.down.sql"])
+
+	issues, err := Check("")
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(issues) != 1 || issues[0].Problem != "the database is at version 1, which was squashed into 000003_baseline" {
+		t.Errorf("Expected the squashed version to be reported, got %+v", issues)
+	}
+}
+
+func TestSQLLiteral(t *testing.T) {
+	for value, want := range map[interface{}]string{
+		nil:                   "NULL",
+		int64(-7):             "-7",
+		0.1:                   "0.1",
+		true:                  "1",
+		"it's":                "'it''s'",
+		time.Unix(0, 0).UTC(): "'1970-01-01 00:00:00+00:00'",
+	} {
+		if got := sqlLiteral(value); got != want {
+			t.Errorf("sqlLiteral(%#v) = %s, want %s", value, got, want)
+		}
+	}
+	if got := sqlLiteral([]byte{0xca, 0xfe}); got != "X'cafe'" {
+		t.Errorf("Expected a blob literal, got %s", got)
+	}
+}
+
+func TestSquashOverBaselineWithSameVersion(t *testing.T) {
+	setupProject(t, squashFiles)
+	if _, err := Squash("", 3); err != nil {
+		t.Fatalf("Squash failed: %v", err)
+	}
+
+	// A branch merged in a migration below the baseline
+	writeMigration(t, "000002_create_tags.up.sql", `CREATE TABLE tags (name TEXT PRIMARY KEY);`)
+	writeMigration(t, "000002_create_tags.down.sql", `DROP TABLE tags;`)
+
+	result, err := Squash("", 3)
+	if err != nil {
+		t.Fatalf("Second squash failed: %v", err)
+	}
+	for _, path := range result.Removed {
+		if strings.Contains(path, "baseline") {
+			t.Errorf("Expected the replaced baseline not to be reported as removed, got %v", result.Removed)
+		}
+	}
+
+	// Only the new baseline is left, with no temporary files next to it
+	entries, _ := os.ReadDir(migrationsPath)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if strings.Join(names, " ") != "000003_baseline.down.sql 000003_baseline.up.sql" {
+		t.Fatalf("Expected only the baseline to be left, got %v", names)
+	}
+	if content, _ := os.ReadFile(result.Baseline.UpPath); !strings.Contains(string(content), "CREATE TABLE tags") {
+		t.Errorf("Expected the new baseline to include the merged migration, got:\n%s", content)
+	}
+	if err := Run(""); err != nil {
+		t.Errorf("Run of the new baseline failed: %v", err)
+	}
+}
+
+func TestSquashVirtualTables(t *testing.T) {
+	setupProject(t, map[string]string{
+		"000001_create_search.up.sql": `CREATE VIRTUAL TABLE search USING fts4(title, body);
+INSERT INTO search (docid, title, body) VALUES (7, 'Welcome', 'hello world');
+CREATE TABLE docs (id INTEGER PRIMARY KEY, body TEXT);
+CREATE VIRTUAL TABLE docs_fts USING fts4(content="docs", body);
+INSERT INTO docs (id, body) VALUES (3, 'squashed baseline');
+INSERT INTO docs_fts (docid, body) VALUES (3, 'squashed baseline');`,
+		"000001_create_search.down.sql": `DROP TABLE docs_fts; DROP TABLE docs; DROP TABLE search;`,
+		"000002_noop.up.sql":            `SELECT 1;`,
+		"000002_noop.down.sql":          `SELECT 1;`,
+	})
+
+	result, err := Squash("", 2)
+	if err != nil {
+		t.Fatalf("Squash failed: %v", err)
+	}
+	content, err := os.ReadFile(result.Baseline.UpPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, shadow := range []string{"search_content", "search_segdir", "docs_fts_segments"} {
+		if strings.Contains(string(content), shadow) {
+			t.Errorf("Expected the shadow table %s to be left to its virtual table, got:\n%s", shadow, content)
+		}
+	}
+
+	if err := Run(""); err != nil {
+		t.Fatalf("Run of the baseline failed: %v", err)
+	}
+	db := openDB(t, os.Getenv("DB_URL"))
+	for query, want := range map[string]int64{
+		`SELECT docid FROM search WHERE search MATCH 'hello'`:        7,
+		`SELECT docid FROM docs_fts WHERE docs_fts MATCH 'squashed'`: 3,
+	} {
+		var docid int64
+		if err := db.QueryRow(query).Scan(&docid); err != nil || docid != want {
+			t.Errorf("Expected %q to find row %d, got %d (%v)", query, want, docid, err)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package migrate
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// destructivePatterns flags statements that throw data away
+var destructivePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\bDROP\s+TABLE\b`),
+	regexp.MustCompile(`(?i)\bDROP\s+COLUMN\b`),
+	regexp.MustCompile(`(?i)\bDELETE\s+FROM\b`),
+}
+
+// VerifyOptions configures a verification run
+type VerifyOptions struct {
+	// Database names the database to verify; empty selects the primary one
+	Database string
+	// Empty starts from an empty database instead of a copy of the real one
+	Empty bool
+}
+
+// VerifyResult holds the findings for a single pending migration
+type VerifyResult struct {
+	Migration   Migration
+	Errors      []string
+	Problems    []string
+	Destructive []string
+}
+
+// OK reports whether the migration applied and reversed cleanly
+func (r VerifyResult) OK() bool {
+	return len(r.Errors) == 0 && len(r.Problems) == 0
+}
+
+// VerifyReport is the outcome of Verify
+type VerifyReport struct {
+	Source       string
+	StartVersion uint
+	Results      []VerifyResult
+}
+
+// OK reports whether every pending migration passed
+func (r *VerifyReport) OK() bool {
+	for _, result := range r.Results {
+		if !result.OK() {
+			return false
+		}
+	}
+	return true
+}
+
+// Verify applies every pending migration up, down and up again in a sandbox
+// copy of the database and compares the schema after each step. The real
+// database is never opened for writing.
+func Verify(opts VerifyOptions) (*VerifyReport, error) {
+	sandboxDir, err := os.MkdirTemp("", "steamboat-verify-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sandbox directory: %w", err)
+	}
+	defer os.RemoveAll(sandboxDir)
+
+	sandboxPath := filepath.Join(sandboxDir, "verify.db")
+	report := &VerifyReport{Source: "empty database"}
+
+	// Without a URL there is nothing to copy, so verify from an empty database
+	target, err := LookupDatabase(opts.Database)
+	if !opts.Empty && err == nil {
+		copied, err := copyDatabase(target.URL, sandboxPath)
+		if err != nil {
+			return nil, err
+		}
+		if copied {
+			report.Source = target.URL
+		}
+	}
+
+	migrations, err := readMigrations(target.MigrationsPath)
+	if err != nil {
+		return nil, err
+	}
+	if issues := checkFiles(target.MigrationsPath, migrations); len(issues) > 0 {
+		return nil, &ConflictError{Issues: issues}
+	}
+
+	m, err := newMigrator(target.MigrationsPath, sandboxPath)
+	if err != nil {
+		return nil, err
+	}
+	defer m.Close()
+
+	db, err := sql.Open("sqlite3", sandboxPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sandbox database: %w", err)
+	}
+	defer db.Close()
+
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return nil, fmt.Errorf("failed to get migration status: %w", err)
+	}
+	if dirty {
+		return nil, fmt.Errorf("database is dirty at version %d, fix it before verifying", version)
+	}
+	report.StartVersion = version
+
+	for _, migration := range migrations {
+		if migration.Version <= version {
+			continue
+		}
+
+		result := verifyMigration(m, db, migration)
+		report.Results = append(report.Results, result)
+
+		// Later migrations build on this one, so stop at the first broken step
+		if len(result.Errors) > 0 {
+			break
+		}
+	}
+
+	return report, nil
+}
+
+func verifyMigration(m *migrate.Migrate, db *sql.DB, migration Migration) VerifyResult {
+	result := VerifyResult{Migration: migration}
+
+	if migration.UpPath == "" || migration.DownPath == "" {
+		result.Errors = append(result.Errors, "missing up or down file")
+		return result
+	}
+
+	if content, err := os.ReadFile(migration.UpPath); err == nil {
+		result.Destructive = findDestructive(string(content))
+	}
+
+	before, err := readSchema(db)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	if err := m.Steps(1); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("up: %s", strings.TrimSpace(err.Error())))
+		return result
+	}
+
+	after, err := readSchema(db)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	if err := m.Steps(-1); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("down: %s", strings.TrimSpace(err.Error())))
+		return result
+	}
+
+	reverted, err := readSchema(db)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+	for _, diff := range diffSchema(before, reverted) {
+		result.Problems = append(result.Problems, "down does not reverse up: "+diff)
+	}
+
+	if err := m.Steps(1); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("up after down: %s", strings.TrimSpace(err.Error())))
+		return result
+	}
+
+	reapplied, err := readSchema(db)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+	for _, diff := range diffSchema(after, reapplied) {
+		result.Problems = append(result.Problems, "up after down differs: "+diff)
+	}
+
+	return result
+}
+
+// copyDatabase copies the SQLite database at src to dst with VACUUM INTO,
+// which takes a consistent snapshot that includes changes still in the
+// -wal file. It reports false when src does not exist yet, leaving dst to be
+// created empty.
+func copyDatabase(src, dst string) (bool, error) {
+	if _, err := os.Stat(src); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+src+"?mode=ro")
+	if err != nil {
+		return false, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`VACUUM INTO ?`, dst); err != nil {
+		return false, fmt.Errorf("failed to copy database: %w", err)
+	}
+
+	return true, nil
+}
+
+// readSchema returns the SQL of every user object keyed by "type name"
+func readSchema(db *sql.DB) (map[string]string, error) {
+	rows, err := db.Query(`SELECT type, name, COALESCE(sql, '') FROM sqlite_master
+		WHERE name NOT LIKE 'sqlite_%' AND tbl_name NOT IN ('schema_migrations', '` + historyTable + `')`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	defer rows.Close()
+
+	schema := make(map[string]string)
+	for rows.Next() {
+		var kind, name, definition string
+		if err := rows.Scan(&kind, &name, &definition); err != nil {
+			return nil, fmt.Errorf("failed to read schema: %w", err)
+		}
+		// A rebuilt table is renamed into place, which makes SQLite quote its name
+		if kind == "table" {
+			if renamed, err := renameCreateTable(definition, name); err == nil {
+				definition = renamed
+			}
+		}
+		schema[kind+" "+name] = strings.Join(strings.Fields(definition), " ")
+	}
+
+	return schema, rows.Err()
+}
+
+// diffSchema describes how got differs from want
+func diffSchema(want, got map[string]string) []string {
+	var diffs []string
+	for key, definition := range want {
+		other, ok := got[key]
+		switch {
+		case !ok:
+			diffs = append(diffs, key+" is missing")
+		case other != definition:
+			diffs = append(diffs, key+" has changed")
+		}
+	}
+	for key := range got {
+		if _, ok := want[key]; !ok {
+			diffs = append(diffs, key+" was not removed")
+		}
+	}
+	sort.Strings(diffs)
+	return diffs
+}
+
+// findDestructive returns the statements in content that drop or delete data
+func findDestructive(content string) []string {
+	var found []string
+	for _, statement := range splitStatements(content) {
+		for _, pattern := range destructivePatterns {
+			if pattern.MatchString(statement) {
+				found = append(found, statement)
+				break
+			}
+		}
+	}
+	return found
+}
+
+// splitStatements strips line comments and splits SQL on semicolons
+func splitStatements(content string) []string {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "--"); i >= 0 {
+			line = line[:i]
+		}
+		lines = append(lines, line)
+	}
+
+	var statements []string
+	for _, statement := range strings.Split(strings.Join(lines, "\n"), ";") {
+		statement = strings.Join(strings.Fields(statement), " ")
+		if statement != "" {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
@@ -0,0 +1,187 @@
+package migrate
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// setupProject moves into an empty project with the given migration files
+// and points DB_URL at a database that does not exist yet
+func setupProject(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	if err := os.MkdirAll(migrationsPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		writeMigration(t, name, content)
+	}
+
+	dbPath := filepath.Join(dir, "app.db")
+	t.Setenv("DB_URL", dbPath)
+	return dbPath
+}
+
+func writeMigration(t *testing.T, name, content string) {
+	if err := os.WriteFile(filepath.Join(migrationsPath, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyReversible(t *testing.T) {
+	dbPath := setupProject(t, map[string]string{
+		"000001_create_users.up.sql":   `CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT);`,
+		"000001_create_users.down.sql": `DROP TABLE users;`,
+		"000002_index_email.up.sql":    `CREATE UNIQUE INDEX idx_users_email ON users (email);`,
+		"000002_index_email.down.sql":  `DROP INDEX idx_users_email;`,
+	})
+
+	report, err := Verify(VerifyOptions{})
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !report.OK() || len(report.Results) != 2 || report.StartVersion != 0 || report.Source != "empty database" {
+		t.Errorf("Expected two clean results from an empty database, got %+v", report)
+	}
+
+	if _, err := os.Stat(dbPath); !os.IsNotExist(err) {
+		t.Errorf("Expected Verify not to create %s, got %v", dbPath, err)
+	}
+}
+
+func TestVerifyIncompleteDown(t *testing.T) {
+	setupProject(t, map[string]string{
+		// IF NOT EXISTS lets the second up run, so only the schema comparison catches it
+		"000001_create_users.up.sql":   `CREATE TABLE users (id INTEGER PRIMARY KEY); CREATE TABLE IF NOT EXISTS roles (name TEXT);`,
+		"000001_create_users.down.sql": `DROP TABLE users;`,
+	})
+
+	report, err := Verify(VerifyOptions{})
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if report.OK() || len(report.Results) != 1 {
+		t.Fatalf("Expected one failed result, got %+v", report.Results)
+	}
+	if problems := report.Results[0].Problems; len(problems) != 1 || problems[0] != "down does not reverse up: table roles was not removed" {
+		t.Errorf("Expected the leftover roles table to be reported, got %q", problems)
+	}
+}
+
+func TestVerifyStopsAtBrokenMigration(t *testing.T) {
+	setupProject(t, map[string]string{
+		"000001_create_users.up.sql":   `CREATE TABLE users (id INTEGER PRIMARY KEY,);`,
+		"000001_create_users.down.sql": `DROP TABLE users;`,
+		"000002_create_posts.up.sql":   `CREATE TABLE posts (id INTEGER PRIMARY KEY);`,
+		"000002_create_posts.down.sql": `DROP TABLE posts;`,
+	})
+
+	report, err := Verify(VerifyOptions{})
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if len(report.Results) != 1 {
+		t.Fatalf("Expected Verify to stop after the broken migration, got %d results", len(report.Results))
+	}
+	if errs := report.Results[0].Errors; len(errs) != 1 || !strings.HasPrefix(errs[0], "up: ") {
+		t.Errorf("Expected an up error, got %q", errs)
+	}
+}
+
+func TestVerifyCopiesDatabase(t *testing.T) {
+	dbPath := setupProject(t, map[string]string{
+		"000001_create_users.up.sql":   `CREATE TABLE users (id INTEGER PRIMARY KEY); INSERT INTO users DEFAULT VALUES;`,
+		"000001_create_users.down.sql": `DROP TABLE users;`,
+	})
+	m, err := newMigrator(migrationsPath, dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	m.Close()
+
+	// The pending migration throws the copied rows away
+	writeMigration(t, "000002_drop_users.up.sql", "-- users moved to accounts\nDROP TABLE users;")
+	writeMigration(t, "000002_drop_users.down.sql", `CREATE TABLE users (id INTEGER PRIMARY KEY);`)
+
+	for _, empty := range []bool{false, true} {
+		report, err := Verify(VerifyOptions{Empty: empty})
+		if err != nil {
+			t.Fatalf("Verify failed: %v", err)
+		}
+
+		wantSource, wantStart, wantResults := dbPath, uint(1), 1
+		if empty {
+			wantSource, wantStart, wantResults = "empty database", 0, 2
+		}
+		if report.Source != wantSource || report.StartVersion != wantStart || len(report.Results) != wantResults {
+			t.Errorf("Expected %d result(s) from version %d of %s, got %+v", wantResults, wantStart, wantSource, report)
+			continue
+		}
+		last := report.Results[len(report.Results)-1]
+		if len(last.Destructive) != 1 || last.Destructive[0] != "DROP TABLE users" {
+			t.Errorf("Expected DROP TABLE users to be flagged, got %q", last.Destructive)
+		}
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var users int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&users); err != nil || users != 1 {
+		t.Errorf("Expected the real database to be left alone, got %d users (%v)", users, err)
+	}
+}
+
+func TestFindDestructive(t *testing.T) {
+	content := `-- DROP TABLE users; is only mentioned here
+CREATE TABLE accounts (id INTEGER PRIMARY KEY);
+ALTER TABLE users
+    DROP COLUMN legacy_id;
+delete from sessions WHERE expires_at < CURRENT_TIMESTAMP;
+DROP INDEX idx_users_email;`
+
+	got := findDestructive(content)
+	want := []string{"ALTER TABLE users DROP COLUMN legacy_id", "delete from sessions WHERE expires_at < CURRENT_TIMESTAMP"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestCopyDatabaseWAL(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "app.db")
+
+	// Keep the connection open so the change stays in the -wal file
+	db := openDB(t, src)
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`PRAGMA journal_mode = WAL; PRAGMA wal_autocheckpoint = 0;
+		CREATE TABLE users (id INTEGER PRIMARY KEY); INSERT INTO users DEFAULT VALUES;`); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(dir, "copy.db")
+	copied, err := copyDatabase(src, dst)
+	if err != nil || !copied {
+		t.Fatalf("copyDatabase failed: %v", err)
+	}
+
+	var users int
+	if err := openDB(t, dst).QueryRow(`SELECT COUNT(*) FROM users`).Scan(&users); err != nil || users != 1 {
+		t.Errorf("Expected the copy to include the WAL changes, got %d users, %v", users, err)
+	}
+
+	if copied, err := copyDatabase(filepath.Join(dir, "missing.db"), dst); copied || err != nil {
+		t.Errorf("Expected a missing database to be skipped, got %v, %v", copied, err)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"<<!.ProjectName!>>/internal/assets"
+	"<<!.ProjectName!>>/internal/config"
+	"<<!.ProjectName!>>/internal/database"
+	"<<!.ProjectName!>>/internal/database/seeders"
+	"<<!.ProjectName!>>/internal/jobs"
+	"<<!.ProjectName!>>/internal/schedule"
+)
+
+// The cli entrypoint runs project code on behalf of the steamboat CLI,
+// e.g. `steamboat db seed` runs `go run ./cmd/cli seed`.
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "seed":
+		err = seed(os.Args[2:])
+	case "jobs":
+		err = runJobs(os.Args[2:])
+	case "schedule":
+		err = runSchedule(os.Args[2:])
+	case "assets":
+		err = runAssets(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: cli seed [--database name] [name...]")
+	fmt.Fprintln(os.Stderr, "       cli jobs list [--status status]")
+	fmt.Fprintln(os.Stderr, "       cli jobs retry [id...]")
+	fmt.Fprintln(os.Stderr, "       cli jobs purge [--status done,dead]")
+	fmt.Fprintln(os.Stderr, "       cli schedule list")
+	fmt.Fprintln(os.Stderr, "       cli schedule run name")
+	fmt.Fprintln(os.Stderr, "       cli assets build")
+}
+
+func seed(args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	dbName := flags.String("database", database.Primary, "named database connection to seed")
+	flags.Parse(args)
+
+	// The steamboat CLI exports APP_ENV, so --env carries over
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	db := database.New(cfg.Database)
+	defer db.Close()
+
+	conn := db.Connection(*dbName)
+	if conn == nil {
+		return fmt.Errorf("database %q is not configured", *dbName)
+	}
+
+	if err := seeders.Run(context.Background(), conn, *dbName, flags.Args()...); err != nil {
+		return err
+	}
+
+	fmt.Println("Database seeded successfully")
+	return nil
+}
+
+func runJobs(args []string) error {
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("jobs "+args[0], flag.ExitOnError)
+	status := flags.String("status", "", "job status to list or purge")
+	flags.Parse(args[1:])
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	db := database.New(cfg.Database)
+	defer db.Close()
+
+	queue := jobs.New(db.DB())
+	ctx := context.Background()
+
+	switch args[0] {
+	case "list":
+		records, err := queue.List(ctx, jobs.Status(*status))
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			fmt.Println("No jobs found")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tKIND\tSTATUS\tATTEMPTS\tRUN AT\tLAST ERROR")
+		for _, r := range records {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%d/%d\t%s\t%s\n", r.ID, r.Kind, r.Status, r.Attempts, r.MaxAttempts,
+				r.RunAt.Local().Format(time.DateTime), r.LastError.String)
+		}
+		return w.Flush()
+	case "retry":
+		var ids []int64
+		for _, arg := range flags.Args() {
+			id, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid job ID %q", arg)
+			}
+			ids = append(ids, id)
+		}
+
+		n, err := queue.Retry(ctx, ids...)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Retrying %d dead job(s)\n", n)
+	case "purge":
+		statuses := []jobs.Status{jobs.StatusDone}
+		if *status != "" {
+			statuses = nil
+			for _, s := range strings.Split(*status, ",") {
+				statuses = append(statuses, jobs.Status(strings.TrimSpace(s)))
+			}
+		}
+
+		n, err := queue.Purge(ctx, statuses...)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Purged %d job(s)\n", n)
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	return nil
+}
+
+func runSchedule(args []string) error {
+	if len(args) == 0 || (args[0] == "run" && len(args) != 2) {
+		usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	db := database.New(cfg.Database)
+	defer db.Close()
+
+	scheduler := schedule.New(db)
+	ctx := context.Background()
+
+	switch args[0] {
+	case "list":
+		states, err := scheduler.List(ctx)
+		if err != nil {
+			return err
+		}
+		if len(states) == 0 {
+			fmt.Println("No scheduled tasks registered")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSCHEDULE\tNEXT RUN\tLAST RUN\tDURATION\tSTATUS\tLAST ERROR")
+		for _, s := range states {
+			lastRun, duration, status := "never", "", s.LastStatus
+			if !s.LastRun.IsZero() {
+				lastRun = s.LastRun.Local().Format(time.DateTime)
+				duration = s.LastDuration.String()
+			}
+			if s.RunningOn != "" {
+				status = "running on " + s.RunningOn
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.Name, s.Schedule, s.Next.Local().Format(time.DateTime),
+				lastRun, duration, status, s.LastError)
+		}
+		return w.Flush()
+	case "run":
+		if err := scheduler.Run(ctx, args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Task %s finished\n", args[1])
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	return nil
+}
+
+func runAssets(args []string) error {
+	if len(args) != 1 || args[0] != "build" {
+		usage()
+		os.Exit(2)
+	}
+
+	manifest, err := assets.Build(assets.SourceDir, assets.DistDir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Built %d assets into %s\n", len(manifest), assets.DistDir)
+	return nil
+}
@@ -1,12 +1,20 @@
 package main
 
 import (
+	"log"
+
+	"<<!.ProjectName!>>/internal/config"
 	"<<!.ProjectName!>>/internal/server"
 	"<<!.ProjectName!>>/internal/utils"
 )
 
 func main() {
-	srv := server.New()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	srv := server.New(cfg)
 	
 	if err := srv.Start(); err != nil {
 		utils.Logger.Error("Server failed to start", "error", err)
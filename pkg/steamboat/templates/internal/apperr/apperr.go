@@ -0,0 +1,110 @@
+// Package apperr carries errors from handlers to the response. An HTTPError
+// holds the status, the message the client may see and the internal cause,
+// which is only logged:
+//
+//	user, err := h.db.User(ctx, id)
+//	if errors.Is(err, sql.ErrNoRows) {
+//		return apperr.NotFound("No such user")
+//	} else if err != nil {
+//		return apperr.Internal(err)
+//	}
+//
+// Write renders an error as an HTML error page, or as RFC 9457 problem
+// details to API clients. Any other error is a 500 whose cause stays private.
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// HTTPError is an error with the HTTP response it should get
+type HTTPError struct {
+	// Status is the HTTP status code
+	Status int
+	// Message is shown to the client (default: the status text)
+	Message string
+	// Err is the internal cause, logged but never shown
+	Err error
+	// Fields lists problems with single fields, such as binding.Errors. It
+	// is rendered as the "errors" member of problem details.
+	Fields any
+}
+
+// Public is implemented by errors that choose their own response, such as
+// binding.Errors. They are shown to the client as they are.
+type Public interface {
+	error
+	HTTPStatus() int
+	PublicMessage() string
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%d %s: %v", e.Status, e.PublicMessage(), e.Err)
+	}
+	return fmt.Sprintf("%d %s", e.Status, e.PublicMessage())
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// PublicMessage returns the message shown to the client
+func (e *HTTPError) PublicMessage() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return http.StatusText(e.Status)
+}
+
+// New returns an error with status and message, which may be empty
+func New(status int, message string) *HTTPError {
+	return &HTTPError{Status: status, Message: message}
+}
+
+// Wrap returns an error with status and message caused by err
+func Wrap(err error, status int, message string) *HTTPError {
+	return &HTTPError{Status: status, Message: message, Err: err}
+}
+
+func BadRequest(message string) *HTTPError {
+	return New(http.StatusBadRequest, message)
+}
+
+func Unauthorized(message string) *HTTPError {
+	return New(http.StatusUnauthorized, message)
+}
+
+func Forbidden(message string) *HTTPError {
+	return New(http.StatusForbidden, message)
+}
+
+func NotFound(message string) *HTTPError {
+	return New(http.StatusNotFound, message)
+}
+
+// Internal returns a 500 caused by err
+func Internal(err error) *HTTPError {
+	return Wrap(err, http.StatusInternalServerError, "")
+}
+
+// From returns the HTTPError in err's chain, the response a Public error in
+// it chooses, or a 500 caused by err
+func From(err error) *HTTPError {
+	var e *HTTPError
+	var public Public
+	switch {
+	case errors.As(err, &e):
+	case errors.As(err, &public):
+		e = &HTTPError{Status: public.HTTPStatus(), Message: public.PublicMessage(), Fields: public}
+	default:
+		return Internal(err)
+	}
+
+	if e.Status < 400 || e.Status > 599 {
+		return &HTTPError{Status: http.StatusInternalServerError, Message: e.Message, Err: err}
+	}
+	return e
+}
@@ -0,0 +1,145 @@
+package apperr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// captureLogs sends the default logger to a buffer for the test
+func captureLogs(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+func withRequestID(r *http.Request, id string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), middleware.RequestIDKey, id))
+}
+
+func TestFrom(t *testing.T) {
+	cause := errors.New("disk full")
+
+	if e := From(cause); e.Status != http.StatusInternalServerError || e.Err != cause {
+		t.Errorf("Expected a 500 caused by the error, got %+v", e)
+	}
+
+	wrapped := fmt.Errorf("saving user: %w", NotFound("No such user"))
+	if e := From(wrapped); e.Status != http.StatusNotFound || e.PublicMessage() != "No such user" {
+		t.Errorf("Expected the wrapped HTTPError, got %+v", e)
+	}
+
+	if e := From(New(http.StatusOK, "")); e.Status != http.StatusInternalServerError {
+		t.Errorf("Expected a non-error status to become 500, got %d", e.Status)
+	}
+
+	e := Wrap(cause, http.StatusConflict, "Email taken")
+	if !errors.Is(e, cause) || e.Error() != "409 Email taken: disk full" {
+		t.Errorf("Unexpected error %q", e.Error())
+	}
+	if Internal(cause).PublicMessage() != "Internal Server Error" {
+		t.Error("Expected a 500 to show only the status text")
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	logs := captureLogs(t)
+
+	r := withRequestID(httptest.NewRequest(http.MethodGet, "/users/1", nil), "host/req-000001")
+	r.Header.Set("Accept", "text/html,application/xhtml+xml")
+	w := httptest.NewRecorder()
+	Write(w, r, Internal(errors.New("secret cause")))
+
+	if w.Code != http.StatusInternalServerError || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("Expected a 500 page, got %d %v", w.Code, w.Header())
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "Internal Server Error") || !strings.Contains(body, "host/req-000001") {
+		t.Errorf("Expected the status and request ID on the page, got %s", body)
+	}
+	if strings.Contains(body, "secret cause") {
+		t.Error("Expected the cause to stay out of the page")
+	}
+	if !strings.Contains(logs.String(), "secret cause") || !strings.Contains(logs.String(), "host/req-000001") {
+		t.Errorf("Expected the cause logged with the request ID, got %s", logs.String())
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	logs := captureLogs(t)
+
+	r := withRequestID(httptest.NewRequest(http.MethodGet, "/api/users/1", nil), "host/req-000002")
+	w := httptest.NewRecorder()
+	Write(w, r, NotFound("No such user"))
+
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("Expected problem details, got %d %v", w.Code, w.Header())
+	}
+	var problem Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	want := Problem{Type: "about:blank", Title: "Not Found", Status: 404, Detail: "No such user", Instance: "/api/users/1", RequestID: "host/req-000002"}
+	if problem != want {
+		t.Errorf("Expected %+v, got %+v", want, problem)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("Expected client errors without a cause not to be logged, got %s", logs.String())
+	}
+}
+
+func TestWantsJSON(t *testing.T) {
+	cases := []struct {
+		path, accept, contentType string
+		want                      bool
+	}{
+		{"/", "text/html,*/*;q=0.8", "", false},
+		{"/", "*/*", "", false},
+		{"/", "application/json", "", true},
+		{"/", "", "application/json", true},
+		{"/api/users", "text/html", "", true},
+	}
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		r.Header.Set("Accept", tc.accept)
+		r.Header.Set("Content-Type", tc.contentType)
+		if got := WantsJSON(r); got != tc.want {
+			t.Errorf("%s Accept %q Content-Type %q: expected %v, got %v", tc.path, tc.accept, tc.contentType, tc.want, got)
+		}
+	}
+}
+
+// fieldErrors stands in for binding.Errors
+type fieldErrors map[string]string
+
+func (fieldErrors) Error() string         { return "invalid fields" }
+func (fieldErrors) HTTPStatus() int       { return http.StatusUnprocessableEntity }
+func (fieldErrors) PublicMessage() string { return "Some fields are invalid" }
+
+func TestWritePublic(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/api/users", nil)
+	w := httptest.NewRecorder()
+	Write(w, r, fmt.Errorf("binding: %w", fieldErrors{"email": "Is already taken"}))
+
+	var problem struct {
+		Detail string            `json:"detail"`
+		Errors map[string]string `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if w.Code != http.StatusUnprocessableEntity || problem.Detail != "Some fields are invalid" || problem.Errors["email"] != "Is already taken" {
+		t.Errorf("Expected a 422 listing the fields, got %d %s", w.Code, w.Body.String())
+	}
+}
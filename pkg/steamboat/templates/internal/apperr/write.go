@@ -0,0 +1,110 @@
+package apperr
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"<<!.ProjectName!>>/internal/utils"
+	"<<!.ProjectName!>>/internal/views/pages"
+)
+
+// Problem is an RFC 9457 problem details body
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	// Errors lists problems with single fields
+	Errors any `json:"errors,omitempty"`
+}
+
+// Write logs err and writes it as the response: problem details if the
+// client wants JSON, the error page otherwise
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	e := From(err)
+	Log(r, e)
+
+	header := w.Header()
+	header.Set("Cache-Control", "no-store")
+	header.Set("X-Content-Type-Options", "nosniff")
+	requestID := middleware.GetReqID(r.Context())
+	title := http.StatusText(e.Status)
+
+	if WantsJSON(r) {
+		problem := Problem{
+			Type:      "about:blank",
+			Title:     title,
+			Status:    e.Status,
+			Instance:  r.URL.Path,
+			RequestID: requestID,
+			Errors:    e.Fields,
+		}
+		if message := e.PublicMessage(); message != title {
+			problem.Detail = message
+		}
+		header.Set("Content-Type", "application/problem+json")
+		w.WriteHeader(e.Status)
+		json.NewEncoder(w).Encode(problem)
+		return
+	}
+
+	header.Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(e.Status)
+	if err := pages.Error(e.Status, title, e.PublicMessage(), requestID).Render(r.Context(), w); err != nil {
+		logger().ErrorContext(r.Context(), "Failed to render error page", "request_id", requestID, "error", err)
+	}
+}
+
+// Log logs err with the request: server errors always, client errors only
+// when they have a cause. Use it when the response has already started.
+func Log(r *http.Request, err error) {
+	e := From(err)
+	if e.Status < http.StatusInternalServerError && e.Err == nil {
+		return
+	}
+
+	attrs := []any{
+		"status", e.Status,
+		"method", r.Method,
+		"path", r.URL.Path,
+		"request_id", middleware.GetReqID(r.Context()),
+	}
+	if e.Err != nil {
+		attrs = append(attrs, "error", e.Err.Error())
+	}
+
+	if e.Status >= http.StatusInternalServerError {
+		logger().ErrorContext(r.Context(), "Request failed", attrs...)
+	} else {
+		logger().InfoContext(r.Context(), "Request rejected", attrs...)
+	}
+}
+
+// WantsJSON reports whether errors for r should be problem details: requests
+// under /api/, and requests that accept JSON or send it rather than HTML
+func WantsJSON(r *http.Request) bool {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		return true
+	}
+	accept := r.Header.Get("Accept")
+	if strings.Contains(accept, "text/html") {
+		return false
+	}
+	if strings.Contains(accept, "json") {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Content-Type"), "json")
+}
+
+func logger() *slog.Logger {
+	if utils.Logger != nil {
+		return utils.Logger
+	}
+	return slog.Default()
+}
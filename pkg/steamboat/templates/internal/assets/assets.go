@@ -0,0 +1,340 @@
+// Package assets serves the static files in internal/assets/static under
+// /static/ with their content hash in the file name, so browsers can cache
+// them forever and still get changes at once:
+//
+//	<link rel="stylesheet" href={ assets.Path("app.css") }/>
+//
+// renders /static/app.1a2b3c4d.css. `steamboat assets:build` writes the
+// hashed files, gzip variants and a manifest to internal/assets/dist, which
+// is embedded in the binary. Without a build the files in static are hashed
+// at startup, and in development they are read from disk, so edits show up
+// without a restart.
+package assets
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Prefix is the URL path the files are served under
+const Prefix = "/static/"
+
+// SourceDir and DistDir hold the asset sources and the build output,
+// relative to the project root
+const (
+	SourceDir = "internal/assets/static"
+	DistDir   = "internal/assets/dist"
+)
+
+// ManifestFile maps every source file to its hashed name in a build
+const ManifestFile = "manifest.json"
+
+//go:embed static all:dist
+var embedded embed.FS
+
+var current atomic.Pointer[Assets]
+
+func init() {
+	fsys, _ := fs.Sub(embedded, "dist")
+	if _, err := fs.Stat(fsys, ManifestFile); err != nil {
+		fsys, _ = fs.Sub(embedded, "static")
+	}
+
+	a, err := New(fsys)
+	if err != nil {
+		panic(fmt.Sprintf("assets: %v", err))
+	}
+	current.Store(a)
+}
+
+// Path returns the URL of the asset name, e.g. Path("app.css")
+func Path(name string) string {
+	return current.Load().Path(name)
+}
+
+// Handler serves the assets under Prefix
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current.Load().ServeHTTP(w, r)
+	})
+}
+
+// UseDir serves the assets from dir on disk instead of the embedded ones,
+// picking up changes without a rebuild. It is meant for development.
+func UseDir(dir string) {
+	current.Store(NewLive(os.DirFS(dir)))
+}
+
+// Assets serves a tree of static files by hashed name
+type Assets struct {
+	fsys fs.FS
+	// live hashes files as they are requested, so they may change
+	live    bool
+	modTime time.Time
+	// paths maps source names to hashed names and entries hashed names
+	// to the files serving them
+	paths   map[string]string
+	entries map[string]entry
+
+	mu     sync.Mutex
+	hashes map[string]liveHash
+}
+
+type entry struct {
+	file string
+	hash string
+}
+
+type liveHash struct {
+	modTime time.Time
+	size    int64
+	hash    string
+}
+
+// hashPattern splits a hashed name like app.1a2b3c4d.css
+var hashPattern = regexp.MustCompile(`^(.*)\.([0-9a-f]{8})(\.[^./]+)?$`)
+
+// New returns the assets in fsys. With a manifest, fsys is the output of
+// Build; otherwise every file is hashed now.
+func New(fsys fs.FS) (*Assets, error) {
+	a := &Assets{
+		fsys:    fsys,
+		modTime: buildTime(),
+		paths:   make(map[string]string),
+		entries: make(map[string]entry),
+	}
+
+	data, err := fs.ReadFile(fsys, ManifestFile)
+	if err == nil {
+		var manifest map[string]string
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", ManifestFile, err)
+		}
+		for name, hashed := range manifest {
+			match := hashPattern.FindStringSubmatch(hashed)
+			if match == nil {
+				return nil, fmt.Errorf("invalid %s: %s is not a hashed name", ManifestFile, hashed)
+			}
+			a.paths[name] = hashed
+			a.entries[hashed] = entry{file: hashed, hash: match[2]}
+		}
+		return a, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || isVariant(name) {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		hash := Hash(content)
+		hashed := HashedName(name, hash)
+		a.paths[name] = hashed
+		a.entries[hashed] = entry{file: name, hash: hash}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash assets: %w", err)
+	}
+	return a, nil
+}
+
+// NewLive returns the assets in fsys, hashing each file again whenever it
+// changes
+func NewLive(fsys fs.FS) *Assets {
+	return &Assets{fsys: fsys, live: true, hashes: make(map[string]liveHash)}
+}
+
+// Path returns the URL of the asset name. Unknown names get a URL without a
+// hash, which the handler answers with 404.
+func (a *Assets) Path(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if a.live {
+		if hash, _, err := a.liveHash(name); err == nil {
+			return Prefix + HashedName(name, hash)
+		}
+		return Prefix + name
+	}
+
+	if hashed, ok := a.paths[name]; ok {
+		return Prefix + hashed
+	}
+	return Prefix + name
+}
+
+// ServeHTTP serves the asset named by the request path. Hashed names are
+// cached for a year; other names, and everything in live mode, revalidate
+// with the ETag on every use.
+func (a *Assets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, Prefix)
+
+	file, hash, modTime, immutable, ok := a.lookup(name)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	header := w.Header()
+	header.Add("Vary", "Accept-Encoding")
+	contentType := mime.TypeByExtension(path.Ext(file))
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	if immutable {
+		header.Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		header.Set("Cache-Control", "no-cache")
+	}
+
+	// Serve a pre-compressed variant the client accepts, if there is one
+	etag := hash
+	for _, variant := range []struct{ encoding, ext string }{{"br", ".br"}, {"gzip", ".gz"}} {
+		if !accepts(r, variant.encoding) {
+			continue
+		}
+		if _, err := fs.Stat(a.fsys, file+variant.ext); err == nil {
+			file += variant.ext
+			etag += "-" + variant.encoding
+			header.Set("Content-Encoding", variant.encoding)
+			if contentType == "" {
+				header.Set("Content-Type", "application/octet-stream")
+			}
+			break
+		}
+	}
+	header.Set("ETag", `"`+etag+`"`)
+
+	f, err := a.fsys.Open(file)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+	http.ServeContent(w, r, "", modTime, content)
+}
+
+// lookup finds the file serving name and whether its name carries its hash
+func (a *Assets) lookup(name string) (file, hash string, modTime time.Time, immutable, ok bool) {
+	if a.live {
+		// Any hash serves the current content, so pages rendered before an
+		// edit keep working
+		if match := hashPattern.FindStringSubmatch(name); match != nil {
+			name = match[1] + match[3]
+		}
+		hash, modTime, err := a.liveHash(name)
+		if err != nil {
+			return "", "", time.Time{}, false, false
+		}
+		return name, hash, modTime, false, true
+	}
+
+	if e, found := a.entries[name]; found {
+		return e.file, e.hash, a.modTime, true, true
+	}
+	if hashed, found := a.paths[name]; found {
+		e := a.entries[hashed]
+		return e.file, e.hash, a.modTime, false, true
+	}
+	return "", "", time.Time{}, false, false
+}
+
+// liveHash returns the hash of name, computing it again when the file changed
+func (a *Assets) liveHash(name string) (string, time.Time, error) {
+	if !fs.ValidPath(name) || isVariant(name) {
+		return "", time.Time{}, fs.ErrNotExist
+	}
+	info, err := fs.Stat(a.fsys, name)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if info.IsDir() {
+		return "", time.Time{}, fs.ErrNotExist
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if cached, ok := a.hashes[name]; ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.hash, cached.modTime, nil
+	}
+
+	content, err := fs.ReadFile(a.fsys, name)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	hash := Hash(content)
+	a.hashes[name] = liveHash{modTime: info.ModTime(), size: info.Size(), hash: hash}
+	return hash, info.ModTime(), nil
+}
+
+// Hash returns the content hash put in asset names
+func Hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:4])
+}
+
+// HashedName inserts hash before the extension of name: app.css becomes
+// app.1a2b3c4d.css
+func HashedName(name, hash string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
+
+// isVariant reports whether name is a pre-compressed variant of another file
+func isVariant(name string) bool {
+	return strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".br")
+}
+
+// accepts reports whether the request accepts the content encoding
+func accepts(r *http.Request, encoding string) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) {
+			continue
+		}
+		q := strings.ReplaceAll(params, " ", "")
+		return q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+	}
+	return false
+}
+
+// buildTime is when the binary was built, used as the Last-Modified time of
+// embedded files
+func buildTime() time.Time {
+	if executable, err := os.Executable(); err == nil {
+		if info, err := os.Stat(executable); err == nil {
+			return info.ModTime()
+		}
+	}
+	return time.Now()
+}
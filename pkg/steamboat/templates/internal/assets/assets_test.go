@@ -0,0 +1,172 @@
+package assets
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func serve(a *Assets, url string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, req)
+	return w
+}
+
+func TestNew(t *testing.T) {
+	a, err := New(fstest.MapFS{
+		"app.css":       {Data: []byte("body { color: red }")},
+		"js/app.min.js": {Data: []byte("console.log(1)")},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	css := a.Path("app.css")
+	if css != Prefix+"app."+Hash([]byte("body { color: red }"))+".css" {
+		t.Errorf("Unexpected path %q", css)
+	}
+	if js := a.Path("/js/app.min.js"); !strings.HasPrefix(js, Prefix+"js/app.min.") || !strings.HasSuffix(js, ".js") {
+		t.Errorf("Unexpected path %q", js)
+	}
+	if missing := a.Path("missing.css"); missing != Prefix+"missing.css" {
+		t.Errorf("Unexpected path %q", missing)
+	}
+
+	w := serve(a, css, nil)
+	if w.Code != http.StatusOK || w.Body.String() != "body { color: red }" {
+		t.Fatalf("Expected the file, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Header().Get("Cache-Control"), "immutable") || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/css") {
+		t.Errorf("Unexpected headers: %v", w.Header())
+	}
+	if w.Header().Get("Last-Modified") == "" {
+		t.Error("Expected a Last-Modified header")
+	}
+
+	etag := w.Header().Get("ETag")
+	if w := serve(a, css, http.Header{"If-None-Match": {etag}}); w.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for a matching ETag, got %d", w.Code)
+	}
+
+	w = serve(a, Prefix+"app.css", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("Expected the unhashed name to revalidate, got %d %v", w.Code, w.Header())
+	}
+
+	for _, url := range []string{Prefix + "missing.css", Prefix + "app.00000000.css"} {
+		if w := serve(a, url, nil); w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", url, w.Code)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	css := strings.Repeat("body { color: red }\n", 100)
+	files := map[string]string{
+		"app.css":     css,
+		"logo.svg.br": "brotli bytes",
+		"logo.svg":    "<svg></svg>",
+		"small.js":    "1",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(dst, "app.deadbeef.css"), []byte("stale"), 0644)
+	os.WriteFile(filepath.Join(dst, ".gitkeep"), nil, 0644)
+
+	manifest, err := Build(src, dst)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if len(manifest) != 3 || manifest["app.css"] != HashedName("app.css", Hash([]byte(css))) {
+		t.Errorf("Unexpected manifest: %v", manifest)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "app.deadbeef.css")); !os.IsNotExist(err) {
+		t.Error("Expected the stale build output to be removed")
+	}
+	if _, err := os.Stat(filepath.Join(dst, ".gitkeep")); err != nil {
+		t.Error("Expected dotfiles to be kept")
+	}
+	if _, err := os.Stat(filepath.Join(dst, manifest["small.js"]+".gz")); !os.IsNotExist(err) {
+		t.Error("Expected no gzip variant of a tiny file")
+	}
+
+	a, err := New(os.DirFS(dst))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	url := a.Path("app.css")
+	if url != Prefix+manifest["app.css"] {
+		t.Errorf("Expected the manifest path, got %q", url)
+	}
+
+	w := serve(a, url, http.Header{"Accept-Encoding": {"gzip, deflate"}})
+	if w.Header().Get("Content-Encoding") != "gzip" || !strings.HasSuffix(w.Header().Get("ETag"), `-gzip"`) {
+		t.Fatalf("Expected the gzip variant, got %v", w.Header())
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("Invalid gzip body: %v", err)
+	}
+	if body, _ := io.ReadAll(gz); string(body) != css {
+		t.Error("Expected the gzip variant to hold the file")
+	}
+
+	w = serve(a, url, http.Header{"Accept-Encoding": {"gzip;q=0"}})
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != css {
+		t.Errorf("Expected the plain file when gzip is refused, got %v", w.Header())
+	}
+
+	w = serve(a, a.Path("logo.svg"), http.Header{"Accept-Encoding": {"br, gzip"}})
+	if w.Header().Get("Content-Encoding") != "br" || w.Body.String() != "brotli bytes" {
+		t.Errorf("Expected the brotli variant, got %v %q", w.Header(), w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != "image/svg+xml" {
+		t.Errorf("Expected the type of the original file, got %q", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestLive(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.css")
+	os.WriteFile(file, []byte("a {}"), 0644)
+
+	a := NewLive(os.DirFS(dir))
+	before := a.Path("app.css")
+
+	os.WriteFile(file, []byte("a { color: blue }"), 0644)
+	os.Chtimes(file, time.Now(), time.Now().Add(time.Second))
+	after := a.Path("app.css")
+	if before == after {
+		t.Fatal("Expected the path to change with the file")
+	}
+
+	// Pages rendered before the edit still load the stylesheet
+	w := serve(a, before, nil)
+	if w.Code != http.StatusOK || w.Body.String() != "a { color: blue }" || w.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("Expected the current file, got %d %v %q", w.Code, w.Header(), w.Body.String())
+	}
+	if w := serve(a, Prefix+"../secret", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 outside the directory, got %d", w.Code)
+	}
+}
+
+func TestEmbedded(t *testing.T) {
+	if path := Path("app.css"); path == Prefix+"app.css" {
+		t.Errorf("Expected the embedded app.css to be hashed, got %q", path)
+	}
+}
@@ -0,0 +1,112 @@
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// compressible lists the extensions worth a gzip variant
+var compressible = map[string]bool{
+	".css": true, ".js": true, ".mjs": true, ".map": true, ".json": true,
+	".svg": true, ".html": true, ".txt": true, ".xml": true, ".wasm": true,
+}
+
+// minCompressSize is the size below which gzip does not pay off
+const minCompressSize = 1024
+
+// Build writes every file in src to dst under its hashed name, with a gzip
+// variant of text files when that is smaller, and the manifest. Files left
+// in dst from an earlier build are removed; dotfiles such as .gitkeep stay.
+// Pre-compressed .br or .gz files in src are copied with their file.
+func Build(src, dst string) (map[string]string, error) {
+	if err := clean(dst); err != nil {
+		return nil, err
+	}
+
+	manifest := make(map[string]string)
+	srcFS := os.DirFS(src)
+	err := fs.WalkDir(srcFS, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || isVariant(name) || strings.HasPrefix(path.Base(name), ".") {
+			return err
+		}
+		content, err := fs.ReadFile(srcFS, name)
+		if err != nil {
+			return err
+		}
+
+		hashed := HashedName(name, Hash(content))
+		manifest[name] = hashed
+		if err := writeFile(dst, hashed, content); err != nil {
+			return err
+		}
+
+		for _, ext := range []string{".br", ".gz"} {
+			if variant, err := fs.ReadFile(srcFS, name+ext); err == nil {
+				if err := writeFile(dst, hashed+ext, variant); err != nil {
+					return err
+				}
+			}
+		}
+		if _, err := fs.Stat(srcFS, name+".gz"); err == nil || !compressible[path.Ext(name)] || len(content) < minCompressSize {
+			return nil
+		}
+
+		var buf bytes.Buffer
+		gz, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		gz.Write(content)
+		if err := gz.Close(); err != nil {
+			return err
+		}
+		if buf.Len() < len(content) {
+			return writeFile(dst, hashed+".gz", buf.Bytes())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build assets: %w", err)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFile(dst, ManifestFile, append(data, '\n')); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// clean removes everything in dir but dotfiles
+func clean(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, e.Name())); err != nil {
+			return fmt.Errorf("failed to clean %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+func writeFile(dir, name string, content []byte) error {
+	target := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(target, content, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+// Package binding decodes requests into structs and validates them:
+//
+//	type SignupForm struct {
+//		Name     string `form:"name" json:"name" validate:"required,max=100"`
+//		Email    string `form:"email" json:"email" validate:"required,email,unique=users.email"`
+//		Plan     string `form:"plan" json:"plan" validate:"oneof=free pro"`
+//		IsAdmin  bool   // never bound: no form or json tag
+//	}
+//
+//	var form SignupForm
+//	if err := h.binder.Bind(r, &form, "name", "email", "plan"); err != nil {
+//		return err
+//	}
+//
+// Only fields with a form or json tag are bound, and when field names are
+// passed only those, so a request cannot set fields it should not. Query
+// strings, URL-encoded and multipart forms are decoded by the form tag, JSON
+// bodies by the json tag.
+//
+// The validate tag lists rules, run in order until one fails. Rules other
+// than required pass empty values, so optional fields only need checking
+// when they are filled in. Register adds custom rules.
+package binding
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"<<!.ProjectName!>>/internal/database"
+)
+
+// Binder decodes and validates requests. DB-backed rules such as unique run
+// against its database.
+type Binder struct {
+	db database.Service
+	// MaxBodySize limits JSON and URL-encoded bodies in bytes (default: DefaultMaxBodySize)
+	MaxBodySize int64
+	// MaxMultipartSize limits multipart bodies in bytes, files included
+	// (default: DefaultMaxMultipartSize)
+	MaxMultipartSize int64
+}
+
+// New returns a binder whose DB-backed rules use db, which may be nil if
+// none are used
+func New(db database.Service) *Binder {
+	return &Binder{db: db}
+}
+
+// Bind decodes r into dst, a pointer to a struct, and validates the fields
+// it decoded. Only the fields named in allowed are bound, or every tagged
+// field if none are named. It returns Errors for invalid fields and a
+// *RequestError for a request that cannot be decoded.
+func (b *Binder) Bind(r *http.Request, dst any, allowed ...string) error {
+	info, v, err := inspect(dst)
+	if err != nil {
+		return err
+	}
+
+	var fields []field
+	var errs Errors
+	if isJSON(r) {
+		fields, errs, err = b.decodeJSON(r, info, v, allowed)
+	} else {
+		fields, errs, err = b.decodeForm(r, info, v, allowed)
+	}
+	if err != nil {
+		return err
+	}
+
+	// A field that did not decode has its error already
+	var bound []field
+	for _, f := range fields {
+		if !errs.Has(f.key) {
+			bound = append(bound, f)
+		}
+	}
+	verrs, err := b.validate(r.Context(), v, bound)
+	if err != nil {
+		return err
+	}
+	errs = append(errs, verrs...)
+	if len(errs) > 0 {
+		return sortErrors(errs, fields)
+	}
+	return nil
+}
+
+// Validate checks every field of dst, a pointer to a struct, against its
+// rules. It returns Errors for invalid fields, keyed by the form name, the
+// JSON name or else the Go name.
+func (b *Binder) Validate(ctx context.Context, dst any) error {
+	info, v, err := inspect(dst)
+	if err != nil {
+		return err
+	}
+	fields := make([]field, len(info.fields))
+	for i, f := range info.fields {
+		f.key = f.form
+		if f.key == "" {
+			f.key = f.json
+		}
+		if f.key == "" {
+			f.key = f.name
+		}
+		fields[i] = f
+	}
+
+	errs, err := b.validate(ctx, v, fields)
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// field is a struct field that can be bound or validated
+type field struct {
+	index []int
+	name  string
+	form  string
+	json  string
+	rules []rule
+	// key is the name errors are reported under
+	key string
+}
+
+type rule struct {
+	name  string
+	param string
+}
+
+type structInfo struct {
+	fields []field
+}
+
+var structs sync.Map // reflect.Type -> *structInfo
+
+// inspect returns the fields of the struct dst points to
+func inspect(dst any) (*structInfo, reflect.Value, error) {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, reflect.Value{}, fmt.Errorf("binding: dst must be a pointer to a struct, got %T", dst)
+	}
+	v = v.Elem()
+
+	if info, ok := structs.Load(v.Type()); ok {
+		return info.(*structInfo), v, nil
+	}
+	info, err := parseStruct(v.Type(), nil)
+	if err != nil {
+		return nil, reflect.Value{}, err
+	}
+	structs.Store(v.Type(), info)
+	return info, v, nil
+}
+
+func parseStruct(t reflect.Type, index []int) (*structInfo, error) {
+	info := &structInfo{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		// Fields of embedded structs are bound as if declared here
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct && sf.Tag.Get("form") == "" && sf.Tag.Get("json") == "" {
+			embedded, err := parseStruct(sf.Type, fieldIndex)
+			if err != nil {
+				return nil, err
+			}
+			info.fields = append(info.fields, embedded.fields...)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		f := field{index: fieldIndex, name: sf.Name, form: tagName(sf.Tag.Get("form")), json: tagName(sf.Tag.Get("json"))}
+		for _, spec := range strings.Split(sf.Tag.Get("validate"), ",") {
+			if spec = strings.TrimSpace(spec); spec == "" {
+				continue
+			}
+			name, param, _ := strings.Cut(spec, "=")
+			if lookupRule(name) == nil {
+				return nil, fmt.Errorf("binding: unknown rule %q on %s.%s", name, t.Name(), sf.Name)
+			}
+			f.rules = append(f.rules, rule{name: name, param: param})
+		}
+		if f.form != "" || f.json != "" || len(f.rules) > 0 {
+			info.fields = append(info.fields, f)
+		}
+	}
+	return info, nil
+}
+
+// tagName returns the name in a form or json tag, or "" for none and "-"
+func tagName(tag string) string {
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// selectFields returns the fields bound by key from the given tag, limited
+// to allowed if it is not empty
+func selectFields(info *structInfo, byJSON bool, allowed []string) []field {
+	var fields []field
+	for _, f := range info.fields {
+		f.key = f.form
+		if byJSON {
+			f.key = f.json
+		}
+		if f.key == "" {
+			continue
+		}
+		if len(allowed) > 0 && !contains(allowed, f.key) {
+			continue
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// sortErrors orders errs like the fields they belong to
+func sortErrors(errs Errors, fields []field) Errors {
+	sorted := make(Errors, 0, len(errs))
+	for _, f := range fields {
+		for _, fe := range errs {
+			if fe.Field == f.key {
+				sorted = append(sorted, fe)
+			}
+		}
+	}
+	return sorted
+}
@@ -0,0 +1,285 @@
+package binding
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"<<!.ProjectName!>>/internal/apperr"
+	"<<!.ProjectName!>>/internal/config"
+	"<<!.ProjectName!>>/internal/database"
+)
+
+type signup struct {
+	Name     string    `form:"name" json:"name" validate:"required,max=10"`
+	Email    string    `form:"email" json:"email" validate:"required,email"`
+	Age      *int      `form:"age" json:"age" validate:"min=18"`
+	Plan     string    `form:"plan" json:"plan" validate:"oneof=free pro"`
+	Tags     []string  `form:"tag" json:"tags" validate:"max=2"`
+	Terms    bool      `form:"terms" json:"terms"`
+	Birthday time.Time `form:"birthday" json:"birthday"`
+	IsAdmin  bool
+	Role     string `form:"role" json:"role"`
+}
+
+func formRequest(values url.Values) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(values.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+func jsonRequest(body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/api/signup", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+func TestBindForm(t *testing.T) {
+	r := formRequest(url.Values{
+		"name":     {"Ada"},
+		"email":    {"ada@example.com"},
+		"age":      {"36"},
+		"plan":     {"pro"},
+		"tag":      {"math", "engines"},
+		"terms":    {"on"},
+		"birthday": {"1815-12-10"},
+		"IsAdmin":  {"true"},
+		"role":     {"admin"},
+	})
+
+	var form signup
+	if err := New(nil).Bind(r, &form, "name", "email", "age", "plan", "tag", "terms", "birthday"); err != nil {
+		t.Fatalf("Bind failed: %v", err)
+	}
+
+	age := 36
+	want := signup{
+		Name: "Ada", Email: "ada@example.com", Age: &age, Plan: "pro",
+		Tags: []string{"math", "engines"}, Terms: true,
+		Birthday: time.Date(1815, 12, 10, 0, 0, 0, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(form, want) {
+		t.Errorf("Expected %+v, got %+v", want, form)
+	}
+}
+
+func TestBindQuery(t *testing.T) {
+	var query struct {
+		Search string `form:"q"`
+		Page   int    `form:"page" validate:"min=1"`
+	}
+	r := httptest.NewRequest(http.MethodGet, "/search?q=engines&page=2", nil)
+	if err := New(nil).Bind(r, &query); err != nil {
+		t.Fatalf("Bind failed: %v", err)
+	}
+	if query.Search != "engines" || query.Page != 2 {
+		t.Errorf("Unexpected query %+v", query)
+	}
+}
+
+func TestBindJSON(t *testing.T) {
+	var form signup
+	err := New(nil).Bind(jsonRequest(`{"name": "Ada", "email": "ada@example.com", "age": 36, "tags": ["math"], "role": "admin"}`), &form, "name", "email", "age", "tags")
+	if err != nil {
+		t.Fatalf("Bind failed: %v", err)
+	}
+	if form.Name != "Ada" || form.Age == nil || *form.Age != 36 || len(form.Tags) != 1 || form.Role != "" {
+		t.Errorf("Unexpected form %+v", form)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	r := formRequest(url.Values{
+		"name":  {"Ada Lovelace-Byron"},
+		"email": {"ada@"},
+		"age":   {"twelve"},
+		"plan":  {"enterprise"},
+		"tag":   {"a", "b", "c"},
+	})
+
+	var form signup
+	err := New(nil).Bind(r, &form)
+	errs, ok := AsErrors(err)
+	if !ok {
+		t.Fatalf("Expected field errors, got %v", err)
+	}
+
+	want := Errors{
+		{Field: "name", Rule: "max", Message: "Must be at most 10 characters"},
+		{Field: "email", Rule: "email", Message: "Must be a valid email address"},
+		{Field: "age", Rule: "type", Message: "Must be a whole number"},
+		{Field: "plan", Rule: "oneof", Message: "Must be one of: free, pro"},
+		{Field: "tag", Rule: "max", Message: "Must be at most 2 items"},
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("Expected %+v, got %+v", want, errs)
+	}
+	if errs.Get("plan") != "Must be one of: free, pro" || errs.Has("terms") {
+		t.Errorf("Unexpected lookups on %v", errs)
+	}
+
+	// Required fields fail even when absent; optional ones only when filled in
+	form = signup{}
+	err = New(nil).Bind(jsonRequest(`{"age": 12, "plan": ""}`), &form)
+	errs, _ = AsErrors(err)
+	if errs.Get("name") != "This field is required" || errs.Get("age") != "Must be at least 18" || errs.Has("plan") {
+		t.Errorf("Unexpected errors %+v", errs)
+	}
+
+	e := apperr.From(err)
+	if e.Status != http.StatusUnprocessableEntity || !reflect.DeepEqual(e.Fields, errs) {
+		t.Errorf("Expected a 422 with the field errors, got %+v", e)
+	}
+}
+
+func TestBindRequestErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		r      *http.Request
+		status int
+	}{
+		{"malformed JSON", jsonRequest(`{"name": `), http.StatusBadRequest},
+		{"not an object", jsonRequest(`["ada"]`), http.StatusBadRequest},
+		{"too large", jsonRequest(`{"name": "` + strings.Repeat("a", DefaultMaxBodySize) + `"}`), http.StatusRequestEntityTooLarge},
+	}
+
+	xml := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("<ada/>"))
+	xml.Header.Set("Content-Type", "application/xml")
+	cases = append(cases, struct {
+		name   string
+		r      *http.Request
+		status int
+	}{"unsupported type", xml, http.StatusUnsupportedMediaType})
+
+	for _, tc := range cases {
+		var form signup
+		err := New(nil).Bind(tc.r, &form)
+		var reqErr *RequestError
+		if !errors.As(err, &reqErr) || reqErr.Status != tc.status {
+			t.Errorf("%s: expected status %d, got %v", tc.name, tc.status, err)
+		}
+	}
+}
+
+func TestBindMultipart(t *testing.T) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("name", "Ada")
+	fw, _ := mw.CreateFormFile("avatar", "ada.png")
+	fw.Write([]byte("png bytes"))
+	mw.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/profile", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+
+	var form struct {
+		Name   string                `form:"name" validate:"required"`
+		Avatar *multipart.FileHeader `form:"avatar" validate:"required"`
+	}
+	if err := New(nil).Bind(r, &form); err != nil {
+		t.Fatalf("Bind failed: %v", err)
+	}
+	if form.Name != "Ada" || form.Avatar == nil || form.Avatar.Filename != "ada.png" {
+		t.Errorf("Unexpected form %+v", form)
+	}
+
+	// The limit covers the files too
+	body.Reset()
+	mw = multipart.NewWriter(&body)
+	fw, _ = mw.CreateFormFile("avatar", "huge.png")
+	fw.Write(bytes.Repeat([]byte("x"), 2048))
+	mw.Close()
+
+	r = httptest.NewRequest(http.MethodPost, "/profile", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	b := New(nil)
+	b.MaxMultipartSize = 1024
+	var reqErr *RequestError
+	if err := b.Bind(r, &form); !errors.As(err, &reqErr) || reqErr.Status != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected a 413 for a body over the limit, got %v", err)
+	}
+}
+
+func TestUnique(t *testing.T) {
+	db := database.New(config.Database{URL: filepath.Join(t.TempDir(), "test.db")})
+	defer db.Close()
+	if _, err := db.DB().Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT); INSERT INTO users (email) VALUES ('ada@example.com')`); err != nil {
+		t.Fatal(err)
+	}
+
+	type account struct {
+		Email  string `form:"email" validate:"required,email,unique=users.email"`
+		Parent int    `form:"parent" validate:"exists=users.id"`
+	}
+	b := New(db)
+
+	taken := account{Email: "ada@example.com", Parent: 2}
+	errs, _ := AsErrors(b.Validate(context.Background(), &taken))
+	if errs.Get("email") != "Is already taken" || errs.Get("parent") != "Does not exist" {
+		t.Errorf("Unexpected errors %+v", errs)
+	}
+
+	free := account{Email: "grace@example.com", Parent: 1}
+	if err := b.Validate(context.Background(), &free); err != nil {
+		t.Errorf("Expected no errors, got %v", err)
+	}
+
+	// Editing a user keeps its own email
+	type profile struct {
+		ID    int    `db:"id"`
+		Email string `form:"email" validate:"unique=users.email id"`
+	}
+	if err := b.Validate(context.Background(), &profile{ID: 1, Email: "ada@example.com"}); err != nil {
+		t.Errorf("Expected the edited row to be left out, got %v", err)
+	}
+	errs, _ = AsErrors(b.Validate(context.Background(), &profile{ID: 2, Email: "ada@example.com"}))
+	if errs.Get("email") != "Is already taken" {
+		t.Errorf("Expected another user's email to be taken, got %+v", errs)
+	}
+
+	if err := New(nil).Validate(context.Background(), &taken); err == nil || errors.As(err, new(Errors)) {
+		t.Errorf("Expected an error without a database, got %v", err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("even", func(ctx context.Context, c Check) (string, error) {
+		if c.Value.(int)%2 != 0 {
+			return "Must be even", nil
+		}
+		return "", nil
+	})
+
+	var pair struct {
+		Count int `form:"count" validate:"even"`
+	}
+	err := New(nil).Bind(formRequest(url.Values{"count": {"3"}}), &pair)
+	if errs, _ := AsErrors(err); errs.Get("count") != "Must be even" {
+		t.Errorf("Expected the custom rule to fail, got %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a duplicate rule to panic")
+		}
+	}()
+	Register("even", nil)
+}
+
+func TestUnknownRule(t *testing.T) {
+	var form struct {
+		Name string `form:"name" validate:"requird"`
+	}
+	if err := New(nil).Validate(context.Background(), &form); err == nil || !strings.Contains(err.Error(), "requird") {
+		t.Errorf("Expected an unknown rule error, got %v", err)
+	}
+}
@@ -0,0 +1,262 @@
+package binding
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMaxBodySize limits JSON and URL-encoded bodies when Binder.MaxBodySize is 0
+const DefaultMaxBodySize = 1 << 20
+
+// DefaultMaxMultipartSize limits multipart bodies, files included, when
+// Binder.MaxMultipartSize is 0
+const DefaultMaxMultipartSize = 32 << 20
+
+// MaxMemory is how much of a multipart body is kept in memory; the rest of
+// the files go to temporary files
+const MaxMemory = 10 << 20
+
+// timeLayouts are the formats accepted for time.Time fields, as sent by
+// date, datetime-local and JSON inputs
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"}
+
+var (
+	fileHeaderType  = reflect.TypeOf((*multipart.FileHeader)(nil))
+	timeType        = reflect.TypeOf(time.Time{})
+	textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func isJSON(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// decodeForm binds the query string of GET, HEAD and DELETE requests, and the
+// URL-encoded or multipart body of others
+func (b *Binder) decodeForm(r *http.Request, info *structInfo, v reflect.Value, allowed []string) ([]field, Errors, error) {
+	values := r.URL.Query()
+	var files map[string][]*multipart.FileHeader
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodDelete {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "multipart/form-data":
+			r.Body = http.MaxBytesReader(nil, r.Body, limit(b.MaxMultipartSize, DefaultMaxMultipartSize))
+			if err := r.ParseMultipartForm(MaxMemory); err != nil {
+				return nil, nil, bodyError(err, "Malformed multipart form")
+			}
+			values, files = r.MultipartForm.Value, r.MultipartForm.File
+		case "application/x-www-form-urlencoded":
+			r.Body = http.MaxBytesReader(nil, r.Body, limit(b.MaxBodySize, DefaultMaxBodySize))
+			if err := r.ParseForm(); err != nil {
+				return nil, nil, bodyError(err, "Malformed form")
+			}
+			values = r.PostForm
+		case "":
+			if r.ContentLength > 0 {
+				return nil, nil, &RequestError{Status: http.StatusUnsupportedMediaType, Message: "Missing Content-Type"}
+			}
+		default:
+			return nil, nil, &RequestError{Status: http.StatusUnsupportedMediaType, Message: "Unsupported Content-Type " + mediaType}
+		}
+	}
+
+	fields := selectFields(info, false, allowed)
+	var errs Errors
+	for _, f := range fields {
+		fv := v.FieldByIndex(f.index)
+		if fv.Type() == fileHeaderType {
+			if fhs := files[f.key]; len(fhs) > 0 {
+				fv.Set(reflect.ValueOf(fhs[0]))
+			}
+			continue
+		}
+		if fv.Type() == reflect.SliceOf(fileHeaderType) {
+			if fhs := files[f.key]; len(fhs) > 0 {
+				fv.Set(reflect.ValueOf(fhs))
+			}
+			continue
+		}
+
+		raw, ok := values[f.key]
+		if !ok {
+			continue
+		}
+		if message := setValues(fv, raw); message != "" {
+			errs = append(errs, FieldError{Field: f.key, Rule: "type", Message: message})
+		}
+	}
+	return fields, errs, nil
+}
+
+// decodeJSON binds the members of a JSON object body
+func (b *Binder) decodeJSON(r *http.Request, info *structInfo, v reflect.Value, allowed []string) ([]field, Errors, error) {
+	var members map[string]json.RawMessage
+	body := http.MaxBytesReader(nil, r.Body, limit(b.MaxBodySize, DefaultMaxBodySize))
+	if err := json.NewDecoder(body).Decode(&members); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, &RequestError{Status: http.StatusBadRequest, Message: "Empty JSON body"}
+		}
+		return nil, nil, bodyError(err, "Malformed JSON")
+	}
+
+	fields := selectFields(info, true, allowed)
+	var errs Errors
+	for _, f := range fields {
+		raw, ok := members[f.key]
+		if !ok {
+			continue
+		}
+		fv := v.FieldByIndex(f.index)
+		if err := json.Unmarshal(raw, fv.Addr().Interface()); err != nil {
+			errs = append(errs, FieldError{Field: f.key, Rule: "type", Message: typeMessage(fv.Type())})
+		}
+	}
+	return fields, errs, nil
+}
+
+// bodyError turns a failure to read the body into a RequestError
+func bodyError(err error, message string) *RequestError {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) || errors.Is(err, multipart.ErrMessageTooLarge) {
+		return &RequestError{Status: http.StatusRequestEntityTooLarge, Message: "Request body too large", Err: err}
+	}
+	return &RequestError{Status: http.StatusBadRequest, Message: message, Err: err}
+}
+
+// limit returns max, or fallback when max is not set
+func limit(max, fallback int64) int64 {
+	if max <= 0 {
+		return fallback
+	}
+	return max
+}
+
+// setValues sets fv from form values and returns a message if they do not fit
+func setValues(fv reflect.Value, raw []string) string {
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 && !fv.Addr().Type().Implements(textUnmarshaler) {
+		slice := reflect.MakeSlice(fv.Type(), 0, len(raw))
+		for _, s := range raw {
+			elem := reflect.New(fv.Type().Elem()).Elem()
+			if message := setValue(elem, s); message != "" {
+				return message
+			}
+			slice = reflect.Append(slice, elem)
+		}
+		fv.Set(slice)
+		return ""
+	}
+	if len(raw) == 0 {
+		return ""
+	}
+	return setValue(fv, raw[0])
+}
+
+// setValue sets fv from one form value. Empty values leave numbers, times
+// and pointers unset, as an empty input means the field was not filled in.
+func setValue(fv reflect.Value, s string) string {
+	if fv.Kind() == reflect.Pointer {
+		if s == "" {
+			return ""
+		}
+		elem := reflect.New(fv.Type().Elem())
+		if message := setValue(elem.Elem(), s); message != "" {
+			return message
+		}
+		fv.Set(elem)
+		return ""
+	}
+
+	if fv.Type() == timeType {
+		if s == "" {
+			return ""
+		}
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				fv.Set(reflect.ValueOf(t))
+				return ""
+			}
+		}
+		return typeMessage(fv.Type())
+	}
+	if u, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(s)); err != nil {
+			return typeMessage(fv.Type())
+		}
+		return ""
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		switch strings.ToLower(s) {
+		case "on", "true", "1", "yes":
+			fv.SetBool(true)
+		case "", "off", "false", "0", "no":
+			fv.SetBool(false)
+		default:
+			return typeMessage(fv.Type())
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s == "" {
+			return ""
+		}
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return typeMessage(fv.Type())
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s == "" {
+			return ""
+		}
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return typeMessage(fv.Type())
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if s == "" {
+			return ""
+		}
+		n, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return typeMessage(fv.Type())
+		}
+		fv.SetFloat(n)
+	default:
+		return typeMessage(fv.Type())
+	}
+	return ""
+}
+
+// typeMessage describes the values a field of type t accepts
+func typeMessage(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return "Must be a date"
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "Must be a whole number"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "Must be a positive whole number"
+	case reflect.Float32, reflect.Float64:
+		return "Must be a number"
+	case reflect.Bool:
+		return "Must be true or false"
+	}
+	return "Is not valid"
+}
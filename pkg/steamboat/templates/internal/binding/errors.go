@@ -0,0 +1,95 @@
+package binding
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// FieldError is a problem with one field, keyed by its form or JSON name
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Errors lists the fields that failed to bind or validate, in field order.
+// In a template, show a field's error with errs.Get("email"); returned from
+// a handler it becomes a 422 with the list in the problem details.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		parts[i] = fe.Field + ": " + fe.Message
+	}
+	return "invalid fields: " + strings.Join(parts, "; ")
+}
+
+// Get returns the message for field, or "" if it is valid
+func (e Errors) Get(field string) string {
+	for _, fe := range e {
+		if fe.Field == field {
+			return fe.Message
+		}
+	}
+	return ""
+}
+
+// Has reports whether field has an error
+func (e Errors) Has(field string) bool {
+	return e.Get(field) != ""
+}
+
+// Map returns the messages keyed by field
+func (e Errors) Map() map[string]string {
+	m := make(map[string]string, len(e))
+	for _, fe := range e {
+		if _, ok := m[fe.Field]; !ok {
+			m[fe.Field] = fe.Message
+		}
+	}
+	return m
+}
+
+func (e Errors) HTTPStatus() int {
+	return http.StatusUnprocessableEntity
+}
+
+func (e Errors) PublicMessage() string {
+	return "Some fields are invalid"
+}
+
+// AsErrors returns the field errors in err's chain
+func AsErrors(err error) (Errors, bool) {
+	var errs Errors
+	ok := errors.As(err, &errs)
+	return errs, ok
+}
+
+// RequestError is a request that cannot be decoded at all, such as malformed
+// JSON or an unsupported content type
+type RequestError struct {
+	Status  int
+	Message string
+	Err     error
+}
+
+func (e *RequestError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+func (e *RequestError) HTTPStatus() int {
+	return e.Status
+}
+
+func (e *RequestError) PublicMessage() string {
+	return e.Message
+}
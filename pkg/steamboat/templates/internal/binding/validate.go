@@ -0,0 +1,292 @@
+package binding
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"<<!.ProjectName!>>/internal/database"
+)
+
+// Check is what a rule checks: one field of a struct being validated
+type Check struct {
+	// Field is the name errors are reported under, e.g. "email"
+	Field string
+	// Value is the field value, with pointers followed
+	Value any
+	// Param is the text after = in the rule, e.g. "users.email"
+	Param string
+	// Struct is the whole struct, for rules that compare fields
+	Struct any
+	// DB is the database of the binder, or nil
+	DB database.Service
+}
+
+// Rule checks a field and returns the message to show if it is invalid, or
+// "" if it is valid. err is for failures to check, such as a lost database
+// connection.
+type Rule func(ctx context.Context, c Check) (message string, err error)
+
+var (
+	rulesMu sync.RWMutex
+	rules   = make(map[string]Rule)
+)
+
+func init() {
+	Register("required", required)
+	Register("email", email)
+	Register("min", minRule)
+	Register("max", maxRule)
+	Register("oneof", oneOf)
+	Register("unique", unique)
+	Register("exists", exists)
+}
+
+// Register adds a rule for validate tags, e.g. in an init function:
+//
+//	binding.Register("slug", func(ctx context.Context, c binding.Check) (string, error) {
+//		if !slugPattern.MatchString(c.Value.(string)) {
+//			return "Must contain only lowercase letters, digits and dashes", nil
+//		}
+//		return "", nil
+//	})
+//
+// Rules do not run for empty values. It panics if name is already taken.
+func Register(name string, rule Rule) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	if _, exists := rules[name]; exists {
+		panic(fmt.Sprintf("binding: rule %q registered twice", name))
+	}
+	rules[name] = rule
+}
+
+func lookupRule(name string) Rule {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	return rules[name]
+}
+
+// validate runs the rules of fields and collects the first failure of each
+func (b *Binder) validate(ctx context.Context, v reflect.Value, fields []field) (Errors, error) {
+	var errs Errors
+	for _, f := range fields {
+		fv := v.FieldByIndex(f.index)
+		for fv.Kind() == reflect.Pointer && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		empty := isEmpty(fv)
+
+		for _, rl := range f.rules {
+			if empty && rl.name != "required" {
+				continue
+			}
+			c := Check{Field: f.key, Param: rl.param, Struct: v.Addr().Interface(), DB: b.db}
+			if fv.Kind() != reflect.Pointer {
+				c.Value = fv.Interface()
+			}
+			message, err := lookupRule(rl.name)(ctx, c)
+			if err != nil {
+				return nil, fmt.Errorf("binding: rule %s on %s: %w", rl.name, f.key, err)
+			}
+			if message != "" {
+				errs = append(errs, FieldError{Field: f.key, Rule: rl.name, Message: message})
+				break
+			}
+		}
+	}
+	return errs, nil
+}
+
+// isEmpty reports whether a value counts as not filled in: nil, zero, or an
+// empty or blank string, slice or map
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	case reflect.String:
+		return strings.TrimSpace(v.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+func required(ctx context.Context, c Check) (string, error) {
+	if c.Value == nil || isEmpty(reflect.ValueOf(c.Value)) {
+		return "This field is required", nil
+	}
+	return "", nil
+}
+
+func email(ctx context.Context, c Check) (string, error) {
+	s, ok := c.Value.(string)
+	if !ok {
+		return "", fmt.Errorf("email needs a string, got %T", c.Value)
+	}
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s || !strings.Contains(s[strings.LastIndex(s, "@"):], ".") {
+		return "Must be a valid email address", nil
+	}
+	return "", nil
+}
+
+func minRule(ctx context.Context, c Check) (string, error) {
+	return compare(c, func(n, limit float64) bool { return n >= limit }, "at least")
+}
+
+func maxRule(ctx context.Context, c Check) (string, error) {
+	return compare(c, func(n, limit float64) bool { return n <= limit }, "at most")
+}
+
+// compare checks the length of strings, slices and maps, or the value of
+// numbers, against the rule's parameter
+func compare(c Check, ok func(n, limit float64) bool, bound string) (string, error) {
+	limit, err := strconv.ParseFloat(c.Param, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid limit %q", c.Param)
+	}
+
+	v := reflect.ValueOf(c.Value)
+	var n float64
+	var unit string
+	switch v.Kind() {
+	case reflect.String:
+		n, unit = float64(utf8.RuneCountInString(v.String())), " characters"
+	case reflect.Slice, reflect.Map:
+		n, unit = float64(v.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = v.Float()
+	default:
+		return "", fmt.Errorf("cannot compare %T", c.Value)
+	}
+
+	if !ok(n, limit) {
+		if unit == " characters" && limit == 1 {
+			unit = " character"
+		} else if unit == " items" && limit == 1 {
+			unit = " item"
+		}
+		return fmt.Sprintf("Must be %s %s%s", bound, c.Param, unit), nil
+	}
+	return "", nil
+}
+
+func oneOf(ctx context.Context, c Check) (string, error) {
+	options := strings.Fields(c.Param)
+	value := fmt.Sprint(c.Value)
+	for _, option := range options {
+		if option == value {
+			return "", nil
+		}
+	}
+	return "Must be one of: " + strings.Join(options, ", "), nil
+}
+
+// identifier matches the table and column names DB-backed rules accept
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// unique fails when a row of the table already has the value, e.g.
+// unique=users.email. A second word names the key column of the row being
+// edited, which is left out so it does not clash with itself: with
+// unique=users.email id, the row whose id equals the struct's id field.
+func unique(ctx context.Context, c Check) (string, error) {
+	target, key, _ := strings.Cut(c.Param, " ")
+	var except any
+	if key != "" {
+		value, ok := fieldByColumn(c.Struct, key)
+		if !ok {
+			return "", fmt.Errorf("no field for key column %q", key)
+		}
+		except = value
+	}
+
+	found, err := rowExists(ctx, c.DB, target, c.Value, key, except)
+	if err != nil || !found {
+		return "", err
+	}
+	return "Is already taken", nil
+}
+
+// exists fails when no row of the table has the value, e.g. exists=users.id
+func exists(ctx context.Context, c Check) (string, error) {
+	found, err := rowExists(ctx, c.DB, c.Param, c.Value, "", nil)
+	if err != nil || found {
+		return "", err
+	}
+	return "Does not exist", nil
+}
+
+// rowExists reports whether a row has value in target, a table.column,
+// leaving out the row whose key column is except when key is set
+func rowExists(ctx context.Context, db database.Service, target string, value any, key string, except any) (bool, error) {
+	if db == nil {
+		return false, fmt.Errorf("no database, create the binder with binding.New(db)")
+	}
+	table, column, ok := strings.Cut(target, ".")
+	if !ok || !identifier.MatchString(table) || !identifier.MatchString(column) {
+		return false, fmt.Errorf("invalid column %q, expected table.column", target)
+	}
+
+	query := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM "%s" WHERE "%s" = ?`, table, column)
+	args := []any{value}
+	if key != "" {
+		if !identifier.MatchString(key) {
+			return false, fmt.Errorf("invalid key column %q", key)
+		}
+		query += fmt.Sprintf(` AND "%s" IS NOT ?`, key)
+		args = append(args, except)
+	}
+	query += ")"
+
+	var found bool
+	if err := db.DB().GetContext(ctx, &found, query, args...); err != nil {
+		return false, fmt.Errorf("failed to query %s: %w", target, err)
+	}
+	return found, nil
+}
+
+// fieldByColumn returns the field of s, a pointer to a struct, for column:
+// the field whose db, form or json name is column, or whose Go name matches
+// it ignoring case
+func fieldByColumn(s any, column string) (any, bool) {
+	v := reflect.Indirect(reflect.ValueOf(s))
+	if v.Kind() != reflect.Struct {
+		return nil, false
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() || !matchesColumn(sf, column) {
+			continue
+		}
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				return nil, true
+			}
+			fv = fv.Elem()
+		}
+		return fv.Interface(), true
+	}
+	return nil, false
+}
+
+func matchesColumn(sf reflect.StructField, column string) bool {
+	for _, tag := range []string{"db", "form", "json"} {
+		if name, _, _ := strings.Cut(sf.Tag.Get(tag), ","); name == column {
+			return true
+		}
+	}
+	return strings.EqualFold(sf.Name, column)
+}
@@ -0,0 +1,427 @@
+// Package config loads the typed application configuration from the
+// environment.
+//
+// Every field is read from the variable named by its env tag. Empty or unset
+// variables fall back to the default tag, fields tagged required:"true" must be
+// set, and oneof:"a b c" restricts the allowed values. Supported field types
+// are string, bool, int, float64, time.Duration and []string (comma-separated).
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/mail"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"<<!.ProjectName!>>/internal/env"
+)
+
+// Config is the application configuration
+type Config struct {
+	Env      string `env:"APP_ENV" default:"development"`
+	Server   Server
+	TLS      TLS
+	Database Database
+	Session  Session
+	CORS     CORS
+	Log      Log
+	Metrics  Metrics
+	Tracing  Tracing
+	Jobs     Jobs
+	Schedule Schedule
+	Mail     Mail
+	Storage  Storage
+}
+
+// ProductionDrainPeriod is the drain period in production when
+// SERVER_DRAIN_PERIOD is unset, long enough for load balancers polling /readyz
+// every few seconds to stop routing new requests
+const ProductionDrainPeriod = 5 * time.Second
+
+// Server configures the HTTP server
+type Server struct {
+	Port            int           `env:"PORT" default:"8080"`
+	ReadTimeout     time.Duration `env:"SERVER_READ_TIMEOUT" default:"10s"`
+	WriteTimeout    time.Duration `env:"SERVER_WRITE_TIMEOUT" default:"30s"`
+	IdleTimeout     time.Duration `env:"SERVER_IDLE_TIMEOUT" default:"1m"`
+	ShutdownTimeout time.Duration `env:"SERVER_SHUTDOWN_TIMEOUT" default:"5s"`
+	// DrainPeriod is how long the server keeps serving after readiness
+	// fails, so load balancers notice before connections are refused. Only
+	// production, where an unset value means ProductionDrainPeriod, drains
+	// by default.
+	DrainPeriod time.Duration `env:"SERVER_DRAIN_PERIOD" default:"0s"`
+	// HookTimeout bounds each OnStart and OnShutdown hook without a timeout
+	HookTimeout time.Duration `env:"SERVER_HOOK_TIMEOUT" default:"10s"`
+}
+
+// TLS configures HTTPS. It is on when a certificate and key are set;
+// `steamboat dev:cert` creates a pair for local development.
+type TLS struct {
+	CertFile   string `env:"TLS_CERT_FILE"`
+	KeyFile    string `env:"TLS_KEY_FILE"`
+	MinVersion string `env:"TLS_MIN_VERSION" default:"1.2" oneof:"1.2 1.3"`
+	// CipherSuites restricts the TLS 1.2 cipher suites by crypto/tls name,
+	// e.g. TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256. TLS 1.3 suites are not
+	// configurable. Empty uses the Go defaults.
+	CipherSuites []string `env:"TLS_CIPHER_SUITES"`
+	// RedirectPort serves a plain HTTP listener that redirects to HTTPS
+	RedirectPort int `env:"TLS_REDIRECT_PORT" default:"0"`
+	// H2C serves cleartext HTTP/2 when TLS is off, for proxies that
+	// terminate TLS and forward HTTP/2
+	H2C bool `env:"H2C_ENABLED" default:"false"`
+}
+
+// Enabled reports whether the server uses TLS
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+// Version returns the crypto/tls constant of MinVersion
+func (t TLS) Version() uint16 {
+	if t.MinVersion == "1.3" {
+		return tls.VersionTLS13
+	}
+	return tls.VersionTLS12
+}
+
+// CipherSuiteIDs returns the crypto/tls IDs of CipherSuites. Suites Go
+// considers insecure are rejected.
+func (t TLS) CipherSuiteIDs() ([]uint16, error) {
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	var ids []uint16
+	for _, name := range t.CipherSuites {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Database configures the database connections
+type Database struct {
+	URL string `env:"DB_URL" required:"true"`
+	// Named holds the URL of every DB_<NAME>_URL variable keyed by lower-case name
+	Named map[string]string
+}
+
+// Session configures the session cookie
+type Session struct {
+	// Key encrypts the session cookie. It is required in production; in
+	// other environments a random key is generated, which logs everyone out
+	// on restart.
+	Key    string        `env:"SESSION_KEY"`
+	Cookie string        `env:"SESSION_COOKIE" default:"steamboat_session"`
+	MaxAge time.Duration `env:"SESSION_MAX_AGE" default:"168h"`
+	// Secure defaults to true when TLS is enabled
+	Secure   bool   `env:"SESSION_SECURE"`
+	SameSite string `env:"SESSION_SAME_SITE" default:"lax" oneof:"lax strict none"`
+}
+
+// CORS configures cross-origin requests
+type CORS struct {
+	AllowedOrigins   []string `env:"CORS_ALLOWED_ORIGINS" default:"https://*,http://*"`
+	AllowedMethods   []string `env:"CORS_ALLOWED_METHODS" default:"GET,POST,PUT,DELETE,OPTIONS,PATCH"`
+	AllowedHeaders   []string `env:"CORS_ALLOWED_HEADERS" default:"Accept,Authorization,Content-Type"`
+	AllowCredentials bool     `env:"CORS_ALLOW_CREDENTIALS" default:"true"`
+	MaxAge           int      `env:"CORS_MAX_AGE" default:"300"`
+}
+
+// Log configures the application logger
+type Log struct {
+	Level string `env:"LOG_LEVEL" default:"info" oneof:"debug info warn error"`
+	File  string `env:"LOG_FILE" default:"logs/app.log"`
+}
+
+// Metrics configures the Prometheus endpoint
+type Metrics struct {
+	Enabled bool   `env:"METRICS_ENABLED" default:"true"`
+	Path    string `env:"METRICS_PATH" default:"/metrics"`
+	// Port serves the metrics on a separate listener, e.g. to keep them off
+	// the public port. 0 serves them on the application port.
+	Port int `env:"METRICS_PORT" default:"0"`
+}
+
+// Tracing configures OpenTelemetry tracing
+type Tracing struct {
+	// Exporter is none, stdout, file (JSON lines in File) or otlp
+	Exporter string `env:"TRACING_EXPORTER" default:"none" oneof:"none stdout file otlp"`
+	File     string `env:"TRACING_FILE" default:"logs/traces.json"`
+	// Endpoint is the OTLP/HTTP collector URL, e.g. http://localhost:4318.
+	// Empty falls back to OTEL_EXPORTER_OTLP_ENDPOINT.
+	Endpoint    string  `env:"TRACING_ENDPOINT"`
+	ServiceName string  `env:"OTEL_SERVICE_NAME" default:"<<!.ProjectName!>>"`
+	SampleRatio float64 `env:"TRACING_SAMPLE_RATIO" default:"1"`
+}
+
+// Jobs configures the background job workers
+type Jobs struct {
+	// Workers is how many jobs run at the same time; 0 disables the workers
+	Workers int `env:"JOBS_WORKERS" default:"2"`
+	// PollInterval is how often idle workers look for due jobs
+	PollInterval time.Duration `env:"JOBS_POLL_INTERVAL" default:"1s"`
+	// Timeout bounds a single attempt. A job still marked running after
+	// twice as long, e.g. because the process crashed, is picked up again.
+	Timeout time.Duration `env:"JOBS_TIMEOUT" default:"5m"`
+}
+
+// Schedule configures the periodic task scheduler
+type Schedule struct {
+	// Enabled runs the tasks in this process. Instances sharing a database
+	// take turns, so it can stay on everywhere.
+	Enabled bool `env:"SCHEDULE_ENABLED" default:"true"`
+}
+
+// Mail configures outgoing email
+type Mail struct {
+	// Driver is smtp, log (write to the application log), file (.eml files
+	// in Dir) or memory. The file and memory drivers keep the messages for
+	// /_dev/mail.
+	Driver string `env:"MAIL_DRIVER" default:"log" oneof:"smtp log file memory"`
+	From   string `env:"MAIL_FROM" default:"<<!.ProjectName!>> <noreply@localhost>"`
+	// Queue sends through the job queue, so failed deliveries are retried
+	Queue bool   `env:"MAIL_QUEUE" default:"true"`
+	Dir   string `env:"MAIL_DIR" default:"tmp/mail"`
+	SMTP  SMTP
+}
+
+// Storage configures where uploaded files are kept
+type Storage struct {
+	// Driver is local (files under Dir) or memory
+	Driver string `env:"STORAGE_DRIVER" default:"local" oneof:"local memory"`
+	Dir    string `env:"STORAGE_DIR" default:"storage"`
+	// Key signs download URLs. Empty falls back to SESSION_KEY, and without
+	// that to a random key, which expires every URL on restart.
+	Key string `env:"STORAGE_KEY"`
+}
+
+// SMTP configures the smtp mail driver
+type SMTP struct {
+	Host     string `env:"SMTP_HOST"`
+	Port     int    `env:"SMTP_PORT" default:"587"`
+	Username string `env:"SMTP_USERNAME"`
+	Password string `env:"SMTP_PASSWORD"`
+	// Encryption is starttls (upgrade a plain connection), tls (implicit,
+	// usually port 465) or none
+	Encryption string `env:"SMTP_ENCRYPTION" default:"starttls" oneof:"starttls tls none"`
+}
+
+// Error lists every missing or invalid variable
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// namedURLPattern matches the URL variable of a named connection
+var namedURLPattern = regexp.MustCompile(`^DB_([A-Z0-9_]+)_URL$`)
+
+// Load loads the .env files of the current environment and parses the
+// configuration from the process environment
+func Load() (*Config, error) {
+	if _, err := env.Load(""); err != nil {
+		return nil, err
+	}
+
+	vars := make(map[string]string)
+	for _, entry := range os.Environ() {
+		key, value, _ := strings.Cut(entry, "=")
+		vars[key] = value
+	}
+	return Parse(vars)
+}
+
+// Parse builds the configuration from vars. All problems are collected and
+// returned together as an *Error.
+func Parse(vars map[string]string) (*Config, error) {
+	cfg := &Config{}
+	var problems []string
+
+	parseStruct(reflect.ValueOf(cfg).Elem(), vars, &problems)
+	parsed := len(problems) == 0
+
+	if vars["SERVER_DRAIN_PERIOD"] == "" && cfg.IsProduction() {
+		cfg.Server.DrainPeriod = ProductionDrainPeriod
+	}
+	if vars["SESSION_SECURE"] == "" {
+		cfg.Session.Secure = cfg.TLS.Enabled()
+	}
+	if cfg.Storage.Key == "" {
+		cfg.Storage.Key = cfg.Session.Key
+	}
+
+	cfg.Database.Named = make(map[string]string)
+	for key, value := range vars {
+		if matches := namedURLPattern.FindStringSubmatch(key); matches != nil && value != "" {
+			cfg.Database.Named[strings.ToLower(matches[1])] = value
+		}
+	}
+
+	problems = append(problems, cfg.validate(parsed)...)
+	if len(problems) > 0 {
+		return nil, &Error{Problems: problems}
+	}
+	return cfg, nil
+}
+
+// IsProduction reports whether the app runs in production
+func (c *Config) IsProduction() bool {
+	return c.Env == "production"
+}
+
+// validate checks the rules struct tags cannot express. Value checks are
+// skipped when parsing already failed, so a bad value is reported once.
+func (c *Config) validate(parsed bool) []string {
+	var problems []string
+	if parsed && (c.Server.Port < 1 || c.Server.Port > 65535) {
+		problems = append(problems, fmt.Sprintf("PORT must be between 1 and 65535, got %d", c.Server.Port))
+	}
+	if parsed && c.Metrics.Port != 0 && (c.Metrics.Port < 1 || c.Metrics.Port > 65535 || c.Metrics.Port == c.Server.Port) {
+		problems = append(problems, fmt.Sprintf("METRICS_PORT must be between 1 and 65535 and differ from PORT, got %d", c.Metrics.Port))
+	}
+	if !strings.HasPrefix(c.Metrics.Path, "/") {
+		problems = append(problems, fmt.Sprintf("METRICS_PATH must start with /, got %q", c.Metrics.Path))
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		problems = append(problems, "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	for _, file := range []struct{ key, path string }{{"TLS_CERT_FILE", c.TLS.CertFile}, {"TLS_KEY_FILE", c.TLS.KeyFile}} {
+		if _, err := os.Stat(file.path); file.path != "" && err != nil {
+			problems = append(problems, fmt.Sprintf("%s is invalid: %v", file.key, err))
+		}
+	}
+	if _, err := c.TLS.CipherSuiteIDs(); err != nil {
+		problems = append(problems, "TLS_CIPHER_SUITES is invalid: "+err.Error())
+	}
+	if c.TLS.RedirectPort != 0 && !c.TLS.Enabled() {
+		problems = append(problems, "TLS_REDIRECT_PORT requires TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+	if parsed && c.TLS.RedirectPort != 0 && (c.TLS.RedirectPort < 1 || c.TLS.RedirectPort > 65535 || c.TLS.RedirectPort == c.Server.Port) {
+		problems = append(problems, fmt.Sprintf("TLS_REDIRECT_PORT must be between 1 and 65535 and differ from PORT, got %d", c.TLS.RedirectPort))
+	}
+	if parsed && (c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1) {
+		problems = append(problems, fmt.Sprintf("TRACING_SAMPLE_RATIO must be between 0 and 1, got %g", c.Tracing.SampleRatio))
+	}
+	if parsed && c.Jobs.Workers < 0 {
+		problems = append(problems, fmt.Sprintf("JOBS_WORKERS must not be negative, got %d", c.Jobs.Workers))
+	}
+	if parsed && (c.Jobs.PollInterval <= 0 || c.Jobs.Timeout <= 0) {
+		problems = append(problems, "JOBS_POLL_INTERVAL and JOBS_TIMEOUT must be positive")
+	}
+	if _, err := mail.ParseAddress(c.Mail.From); err != nil {
+		problems = append(problems, fmt.Sprintf("MAIL_FROM is invalid: %v", err))
+	}
+	if c.Mail.Driver == "smtp" && c.Mail.SMTP.Host == "" {
+		problems = append(problems, "SMTP_HOST is required by the smtp mail driver")
+	}
+	if c.IsProduction() && c.Session.Key == "" {
+		problems = append(problems, "SESSION_KEY is required in production")
+	}
+	return problems
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func parseStruct(v reflect.Value, vars map[string]string, problems *[]string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+
+		key, ok := field.Tag.Lookup("env")
+		if !ok {
+			if field.Type.Kind() == reflect.Struct {
+				parseStruct(value, vars, problems)
+			}
+			continue
+		}
+
+		raw := vars[key]
+		if raw == "" {
+			raw = field.Tag.Get("default")
+		}
+		if raw == "" {
+			if field.Tag.Get("required") == "true" {
+				*problems = append(*problems, key+" is required")
+			}
+			continue
+		}
+
+		if options := field.Tag.Get("oneof"); options != "" && !contains(strings.Fields(options), raw) {
+			*problems = append(*problems, fmt.Sprintf("%s must be one of %s, got %q", key, strings.Join(strings.Fields(options), ", "), raw))
+			continue
+		}
+
+		if err := setField(value, raw); err != nil {
+			*problems = append(*problems, fmt.Sprintf("%s is invalid: %v", key, err))
+		}
+	}
+}
+
+func setField(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("expected a duration like 30s, got %q", raw)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %q", raw)
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number, got %q", raw)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported field type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
+
+func contains(options []string, value string) bool {
+	for _, option := range options {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDefaults(t *testing.T) {
+	cfg, err := Parse(map[string]string{"DB_URL": "app.db"})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if cfg.Env != "development" || cfg.IsProduction() {
+		t.Errorf("Expected development, got %q", cfg.Env)
+	}
+	if cfg.Server.Port != 8080 || cfg.Server.ReadTimeout != 10*time.Second || cfg.Server.IdleTimeout != time.Minute || cfg.Server.DrainPeriod != 0 {
+		t.Errorf("Unexpected server defaults: %+v", cfg.Server)
+	}
+
+	for _, tc := range []struct {
+		vars  map[string]string
+		drain time.Duration
+	}{
+		{map[string]string{"APP_ENV": "test"}, 0},
+		{map[string]string{"APP_ENV": "staging"}, 0},
+		{map[string]string{"APP_ENV": "production"}, ProductionDrainPeriod},
+		{map[string]string{"APP_ENV": "production", "SERVER_DRAIN_PERIOD": "0s"}, 0},
+		{map[string]string{"APP_ENV": "staging", "SERVER_DRAIN_PERIOD": "2s"}, 2 * time.Second},
+	} {
+		tc.vars["DB_URL"] = "app.db"
+		tc.vars["SESSION_KEY"] = "0123456789abcdef0123456789abcdef"
+		env, err := Parse(tc.vars)
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if env.Server.DrainPeriod != tc.drain {
+			t.Errorf("Expected a drain period of %s with %v, got %s", tc.drain, tc.vars, env.Server.DrainPeriod)
+		}
+	}
+	if cfg.Session.Cookie != "steamboat_session" || cfg.Session.MaxAge != 7*24*time.Hour {
+		t.Errorf("Unexpected session defaults: %+v", cfg.Session)
+	}
+	if len(cfg.CORS.AllowedOrigins) != 2 || !cfg.CORS.AllowCredentials || cfg.CORS.MaxAge != 300 {
+		t.Errorf("Unexpected CORS defaults: %+v", cfg.CORS)
+	}
+	if cfg.Log.Level != "info" || cfg.Log.File != "logs/app.log" {
+		t.Errorf("Unexpected log defaults: %+v", cfg.Log)
+	}
+	if cfg.Jobs.Workers != 2 || cfg.Jobs.PollInterval != time.Second || cfg.Jobs.Timeout != 5*time.Minute {
+		t.Errorf("Unexpected jobs defaults: %+v", cfg.Jobs)
+	}
+	if !cfg.Schedule.Enabled {
+		t.Error("Expected the scheduler to be enabled by default")
+	}
+	if cfg.Mail.Driver != "log" || !cfg.Mail.Queue || cfg.Mail.SMTP.Port != 587 || cfg.Mail.SMTP.Encryption != "starttls" {
+		t.Errorf("Unexpected mail defaults: %+v", cfg.Mail)
+	}
+	if cfg.Storage.Driver != "local" || cfg.Storage.Dir != "storage" || cfg.Storage.Key != "" {
+		t.Errorf("Unexpected storage defaults: %+v", cfg.Storage)
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	cfg, err := Parse(map[string]string{
+		"APP_ENV":              "production",
+		"PORT":                 "3000",
+		"DB_URL":               "app.db",
+		"DB_ANALYTICS_URL":     "analytics.db",
+		"SESSION_KEY":          "secret",
+		"SESSION_SECURE":       "true",
+		"SERVER_WRITE_TIMEOUT": "1m30s",
+		"CORS_ALLOWED_ORIGINS": "https://example.com, https://app.example.com",
+		"LOG_LEVEL":            "debug",
+	})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if !cfg.IsProduction() || cfg.Server.Port != 3000 || cfg.Server.WriteTimeout != 90*time.Second {
+		t.Errorf("Unexpected values: %+v", cfg)
+	}
+	if cfg.Database.Named["analytics"] != "analytics.db" {
+		t.Errorf("Expected named connection, got %v", cfg.Database.Named)
+	}
+	if !cfg.Session.Secure || cfg.Session.Key != "secret" {
+		t.Errorf("Unexpected session config: %+v", cfg.Session)
+	}
+	if cfg.Storage.Key != "secret" {
+		t.Errorf("Expected the storage key to fall back to SESSION_KEY, got %q", cfg.Storage.Key)
+	}
+	if strings.Join(cfg.CORS.AllowedOrigins, " ") != "https://example.com https://app.example.com" {
+		t.Errorf("Unexpected origins: %q", cfg.CORS.AllowedOrigins)
+	}
+	if cfg.Log.Level != "debug" {
+		t.Errorf("Expected debug log level, got %q", cfg.Log.Level)
+	}
+}
+
+func TestParseAggregatesErrors(t *testing.T) {
+	_, err := Parse(map[string]string{
+		"APP_ENV":         "production",
+		"PORT":            "http",
+		"SESSION_SECURE":  "maybe",
+		"SESSION_MAX_AGE": "7d",
+		"LOG_LEVEL":       "verbose",
+		"MAIL_DRIVER":     "smtp",
+		"MAIL_FROM":       "not an address",
+	})
+
+	var cfgErr *Error
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("Expected *Error, got %v", err)
+	}
+
+	for _, want := range []string{"DB_URL is required", "PORT is invalid", "SESSION_SECURE is invalid", "SESSION_MAX_AGE is invalid", "LOG_LEVEL must be one of", "MAIL_FROM is invalid", "SMTP_HOST is required", "SESSION_KEY is required in production"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %q, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestParsePortRange(t *testing.T) {
+	if _, err := Parse(map[string]string{"DB_URL": "app.db", "PORT": "70000"}); err == nil || !strings.Contains(err.Error(), "PORT must be between") {
+		t.Errorf("Expected port range error, got %v", err)
+	}
+	if _, err := Parse(map[string]string{"DB_URL": "app.db", "METRICS_PORT": "8080"}); err == nil || !strings.Contains(err.Error(), "METRICS_PORT must") {
+		t.Errorf("Expected metrics port to differ from PORT, got %v", err)
+	}
+	if _, err := Parse(map[string]string{"DB_URL": "app.db", "METRICS_PATH": "metrics"}); err == nil || !strings.Contains(err.Error(), "METRICS_PATH must") {
+		t.Errorf("Expected metrics path error, got %v", err)
+	}
+}
+
+func TestParseTLS(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := filepath.Join(dir, "dev.crt"), filepath.Join(dir, "dev.key")
+	for _, path := range []string{cert, key} {
+		if err := os.WriteFile(path, []byte("test"), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	cfg, err := Parse(map[string]string{
+		"DB_URL":            "app.db",
+		"TLS_CERT_FILE":     cert,
+		"TLS_KEY_FILE":      key,
+		"TLS_CIPHER_SUITES": "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+	})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if !cfg.TLS.Enabled() || !cfg.Session.Secure {
+		t.Errorf("Expected TLS to make session cookies secure by default, got %+v", cfg.Session)
+	}
+	if ids, _ := cfg.TLS.CipherSuiteIDs(); len(ids) != 2 {
+		t.Errorf("Expected 2 cipher suites, got %v", ids)
+	}
+
+	cfg, err = Parse(map[string]string{"DB_URL": "app.db", "TLS_CERT_FILE": cert, "TLS_KEY_FILE": key, "SESSION_SECURE": "false"})
+	if err != nil || cfg.Session.Secure {
+		t.Errorf("Expected SESSION_SECURE=false to win over the TLS default, got %v (%v)", cfg, err)
+	}
+
+	_, err = Parse(map[string]string{
+		"DB_URL":            "app.db",
+		"TLS_CERT_FILE":     filepath.Join(dir, "missing.crt"),
+		"TLS_CIPHER_SUITES": "TLS_RSA_WITH_RC4_128_SHA",
+		"TLS_REDIRECT_PORT": "8081",
+	})
+	for _, want := range []string{"must be set together", "TLS_CERT_FILE is invalid", "TLS_CIPHER_SUITES is invalid", "TLS_REDIRECT_PORT requires"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %q, got %v", want, err)
+		}
+	}
+}
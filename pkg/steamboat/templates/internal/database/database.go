@@ -1,61 +1,144 @@
 package database
 
 import (
-	"os"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/joho/godotenv/autoload"
 	_ "github.com/mattn/go-sqlite3"
 
+	"<<!.ProjectName!>>/internal/config"
+	"<<!.ProjectName!>>/internal/tracing"
 	"<<!.ProjectName!>>/internal/utils"
 )
 
+// Primary is the name of the connection configured by DB_URL.
+const Primary = "primary"
+
 // Service represents a service that interacts with a database.
 type Service interface {
+	// DB returns the primary connection pool, e.g. to begin transactions.
+	DB() *sqlx.DB
+	// Connection returns the named connection configured by DB_<NAME>_URL,
+	// e.g. "analytics" for DB_ANALYTICS_URL, or nil if it is not configured.
+	Connection(name string) *sqlx.DB
+	// Connections returns every connection keyed by name, including Primary.
+	Connections() map[string]*sqlx.DB
+	// Health pings every connection and returns its latency and pool
+	// statistics keyed by name. The error joins the failed pings.
+	Health(ctx context.Context) (map[string]Health, error)
 	Close() error
 	// STEAMBOAT:QUERIES_START - Auto-generated query methods
 	// STEAMBOAT:QUERIES_END
 }
 
+// Health is the state of one connection pool
+type Health struct {
+	Latency time.Duration
+	Stats   sql.DBStats
+	Err     error
+}
+
 type service struct {
-	db *sqlx.DB
+	db          *sqlx.DB
+	connections map[string]*sqlx.DB
+	// traced wraps every connection so the generated queries record spans
+	traced map[string]*tracing.DB
 	// STEAMBOAT:FIELDS_START - Auto-generated query fields
 	// STEAMBOAT:FIELDS_END
 }
 
-var (
-	dburl      = os.Getenv("DB_URL")
-	dbInstance *service
-)
+var dbInstance *service
 
-func New() Service {
+// New opens the primary connection and every named connection in cfg
+func New(cfg config.Database) Service {
 	if dbInstance != nil {
 		return dbInstance
 	}
 
-	db, err := sqlx.Open("sqlite3", dburl)
-	if err != nil {
-		if utils.Logger != nil {
-			utils.Logger.Error("Failed to open database", "error", err)
-		}
-		panic(err)
+	connections := map[string]*sqlx.DB{
+		Primary: open(cfg.URL),
+	}
+	for name, url := range cfg.Named {
+		connections[name] = open(url)
+	}
+	db := connections[Primary]
+
+	traced := make(map[string]*tracing.DB, len(connections))
+	for name, conn := range connections {
+		traced[name] = tracing.WrapDB(conn, name)
 	}
 
 	dbInstance = &service{
-		db: db,
+		db:          db,
+		connections: connections,
+		traced:      traced,
 		// STEAMBOAT:INIT_START - Auto-generated query initialization
 		// STEAMBOAT:INIT_END
 	}
 	return dbInstance
 }
 
+func open(url string) *sqlx.DB {
+	db, err := sqlx.Open("sqlite3", url)
+	if err != nil {
+		if utils.Logger != nil {
+			utils.Logger.Error("Failed to open database", "error", err)
+		}
+		panic(err)
+	}
+	return db
+}
+
 // STEAMBOAT:GETTERS_START - Auto-generated getter methods
 // STEAMBOAT:GETTERS_END
 
-func (s *service) Close() error {
-	if utils.Logger != nil {
-		utils.Logger.Info("Disconnected from database", "url", dburl)
+func (s *service) DB() *sqlx.DB {
+	return s.db
+}
+
+func (s *service) Connection(name string) *sqlx.DB {
+	return s.connections[strings.ToLower(name)]
+}
+
+func (s *service) Connections() map[string]*sqlx.DB {
+	connections := make(map[string]*sqlx.DB, len(s.connections))
+	for name, db := range s.connections {
+		connections[name] = db
+	}
+	return connections
+}
+
+func (s *service) Health(ctx context.Context) (map[string]Health, error) {
+	health := make(map[string]Health, len(s.connections))
+	var errs []error
+	for name, db := range s.connections {
+		start := time.Now()
+		err := db.PingContext(ctx)
+		health[name] = Health{Latency: time.Since(start), Stats: db.Stats(), Err: err}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("database %s: %w", name, err))
+		}
 	}
-	return s.db.Close()
+	return health, errors.Join(errs...)
 }
 
+// Close closes every connection. The next New opens fresh ones.
+func (s *service) Close() error {
+	if dbInstance == s {
+		dbInstance = nil
+	}
+
+	var errs []error
+	for name, db := range s.connections {
+		if utils.Logger != nil {
+			utils.Logger.Info("Disconnected from database", "name", name)
+		}
+		errs = append(errs, db.Close())
+	}
+	return errors.Join(errs...)
+}
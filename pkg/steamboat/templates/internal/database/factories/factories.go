@@ -0,0 +1,157 @@
+package factories
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"<<!.ProjectName!>>/internal/database/models"
+)
+
+// Creator persists a fresh record for a table and returns its ID. Generated
+// factories register one so that foreign keys can be filled automatically.
+type Creator func(t testing.TB, db models.DBTX) int
+
+var (
+	sequence int64
+
+	mu       sync.RWMutex
+	creators = make(map[string]Creator)
+)
+
+// Sequence returns a process-wide increasing number, useful for unique values
+// in overrides, e.g. fmt.Sprintf("user%d@example.com", factories.Sequence()).
+func Sequence() int {
+	return int(atomic.AddInt64(&sequence, 1))
+}
+
+// Register makes a table's factory available for related-record creation.
+func Register(table string, create Creator) {
+	mu.Lock()
+	defer mu.Unlock()
+	creators[table] = create
+}
+
+func creatorFor(table string) (Creator, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	create, ok := creators[table]
+	return create, ok
+}
+
+var (
+	firstNames = []string{"Ada", "Grace", "Alan", "Linus", "Barbara", "Ken", "Margaret", "Dennis"}
+	lastNames  = []string{"Lovelace", "Hopper", "Turing", "Torvalds", "Liskov", "Thompson", "Hamilton", "Ritchie"}
+	words      = []string{"steam", "river", "paddle", "harbor", "current", "deck", "anchor", "voyage", "captain", "wheel"}
+)
+
+// Fill sets every zero-valued field of the struct pointed to by record to a
+// fake value chosen from its db tag and type. Fields named id are left alone,
+// and fields ending in _id are filled by creating a related record when a
+// factory for that table has been registered.
+func Fill(t testing.TB, db models.DBTX, record interface{}) {
+	t.Helper()
+
+	value := reflect.ValueOf(record)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		t.Fatalf("factories: Fill expects a pointer to a struct, got %T", record)
+	}
+	value = value.Elem()
+
+	n := Sequence()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		column := strings.Split(field.Tag.Get("db"), ",")[0]
+		if column == "" || column == "-" || column == "id" || !field.IsExported() {
+			continue
+		}
+
+		fieldValue := value.Field(i)
+		if !fieldValue.IsZero() {
+			continue
+		}
+
+		fillField(t, db, fieldValue, column, n)
+	}
+}
+
+func fillField(t testing.TB, db models.DBTX, field reflect.Value, column string, n int) {
+	t.Helper()
+
+	if field.Type() == reflect.TypeOf(time.Time{}) {
+		field.Set(reflect.ValueOf(time.Now().UTC().Truncate(time.Second)))
+		return
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(fakeString(column, n))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if strings.HasSuffix(column, "_id") {
+			if create, ok := creatorFor(pluralize(strings.TrimSuffix(column, "_id"))); ok {
+				field.SetInt(int64(create(t, db)))
+				return
+			}
+		}
+		field.SetInt(int64(n))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		field.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		field.SetFloat(float64(n) * 1.5)
+	case reflect.Bool:
+		field.SetBool(n%2 == 0)
+	}
+}
+
+func fakeString(column string, n int) string {
+	first := firstNames[n%len(firstNames)]
+	last := lastNames[n%len(lastNames)]
+	word := words[n%len(words)]
+
+	switch {
+	case strings.Contains(column, "email"):
+		return fmt.Sprintf("%s.%s%d@example.com", strings.ToLower(first), strings.ToLower(last), n)
+	case column == "first_name":
+		return first
+	case column == "last_name":
+		return last
+	case column == "name" || column == "full_name" || column == "username":
+		return fmt.Sprintf("%s %s %d", first, last, n)
+	case strings.Contains(column, "password"):
+		return "password"
+	case strings.Contains(column, "phone"):
+		return fmt.Sprintf("+1555%07d", n)
+	case strings.Contains(column, "url"):
+		return fmt.Sprintf("https://example.com/%s-%d", word, n)
+	case strings.Contains(column, "slug"):
+		return fmt.Sprintf("%s-%d", word, n)
+	case column == "title":
+		return fmt.Sprintf("The %s %s %d", capitalize(word), capitalize(words[(n+1)%len(words)]), n)
+	case column == "body" || column == "content" || column == "description":
+		return fmt.Sprintf("%s %s %s %s.", capitalize(word), words[(n+3)%len(words)], words[(n+5)%len(words)], words[(n+7)%len(words)])
+	}
+
+	return fmt.Sprintf("%s %d", strings.ReplaceAll(column, "_", " "), n)
+}
+
+// capitalize upper-cases the first letter of a fake word
+func capitalize(word string) string {
+	if word == "" {
+		return word
+	}
+	return strings.ToUpper(word[:1]) + word[1:]
+}
+
+func pluralize(s string) string {
+	if strings.HasSuffix(s, "y") {
+		return s[:len(s)-1] + "ies"
+	}
+	if strings.HasSuffix(s, "s") || strings.HasSuffix(s, "x") || strings.HasSuffix(s, "ch") {
+		return s + "es"
+	}
+	return s + "s"
+}
@@ -0,0 +1,104 @@
+package factories
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"<<!.ProjectName!>>/internal/database/models"
+)
+
+type fakeRecord struct {
+	ID        int       `db:"id"`
+	Email     string    `db:"email"`
+	Name      string    `db:"name"`
+	Age       int       `db:"age"`
+	Active    bool      `db:"active"`
+	AuthorID  int       `db:"author_id"`
+	CreatedAt time.Time `db:"created_at"`
+	Ignored   string
+}
+
+func TestFillSetsZeroFields(t *testing.T) {
+	var record fakeRecord
+	Fill(t, nil, &record)
+
+	if record.ID != 0 {
+		t.Errorf("Expected ID to be left alone, got %d", record.ID)
+	}
+	if !strings.Contains(record.Email, "@example.com") {
+		t.Errorf("Expected fake email, got %q", record.Email)
+	}
+	if record.Name == "" {
+		t.Error("Expected name to be filled")
+	}
+	if record.Age == 0 {
+		t.Error("Expected age to be filled")
+	}
+	if record.CreatedAt.IsZero() {
+		t.Error("Expected created_at to be filled")
+	}
+	if record.Ignored != "" {
+		t.Errorf("Expected untagged field to be left alone, got %q", record.Ignored)
+	}
+}
+
+func TestFakeStringCapitalizesText(t *testing.T) {
+	if got := fakeString("title", 0); got != "The Steam River 0" {
+		t.Errorf("Expected a capitalized title, got %q", got)
+	}
+	if got := fakeString("body", 0); got != "Steam harbor deck voyage." {
+		t.Errorf("Expected a sentence, got %q", got)
+	}
+}
+
+func TestFillKeepsExistingValues(t *testing.T) {
+	record := fakeRecord{Email: "fixed@example.com"}
+	Fill(t, nil, &record)
+
+	if record.Email != "fixed@example.com" {
+		t.Errorf("Expected existing email to be kept, got %q", record.Email)
+	}
+}
+
+func TestFillProducesUniqueValues(t *testing.T) {
+	var a, b fakeRecord
+	Fill(t, nil, &a)
+	Fill(t, nil, &b)
+
+	if a.Email == b.Email {
+		t.Errorf("Expected unique emails, both were %q", a.Email)
+	}
+}
+
+func TestFillCreatesRelatedRecords(t *testing.T) {
+	created := 0
+	Register("authors", func(t testing.TB, db models.DBTX) int {
+		created++
+		return 42
+	})
+	defer func() {
+		mu.Lock()
+		delete(creators, "authors")
+		mu.Unlock()
+	}()
+
+	var record fakeRecord
+	Fill(t, nil, &record)
+
+	if created != 1 {
+		t.Errorf("Expected one related record to be created, got %d", created)
+	}
+	if record.AuthorID != 42 {
+		t.Errorf("Expected author_id 42, got %d", record.AuthorID)
+	}
+}
+
+func TestSequence(t *testing.T) {
+	first := Sequence()
+	second := Sequence()
+
+	if second <= first {
+		t.Errorf("Expected increasing sequence, got %d then %d", first, second)
+	}
+}
@@ -0,0 +1,4 @@
+-- Rollback: Create Jobs Table
+-- Created: 000001
+
+DROP TABLE jobs;
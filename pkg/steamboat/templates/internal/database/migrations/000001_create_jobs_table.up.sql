@@ -0,0 +1,26 @@
+-- Migration: Create Jobs Table
+-- Created: 000001
+
+-- Background jobs, see internal/jobs. The table has no model and
+-- `steamboat make migration --auto` leaves it alone.
+CREATE TABLE jobs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    priority INTEGER NOT NULL DEFAULT 0,
+    unique_key TEXT,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL,
+    last_error TEXT,
+    run_at DATETIME NOT NULL,
+    locked_at DATETIME,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX jobs_due ON jobs (status, priority, run_at);
+
+-- A unique key is only held while the job is waiting or running
+CREATE UNIQUE INDEX jobs_unique_key ON jobs (unique_key)
+    WHERE unique_key IS NOT NULL AND status IN ('pending', 'running');
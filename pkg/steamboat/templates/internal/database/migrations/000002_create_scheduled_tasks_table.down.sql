@@ -0,0 +1,4 @@
+-- Rollback: Create Scheduled Tasks Table
+-- Created: 000002
+
+DROP TABLE scheduled_tasks;
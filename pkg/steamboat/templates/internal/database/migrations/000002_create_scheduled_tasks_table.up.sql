@@ -0,0 +1,16 @@
+-- Migration: Create Scheduled Tasks Table
+-- Created: 000002
+
+-- Leases and last outcomes of the tasks in internal/schedule. The table has
+-- no model and `steamboat make migration --auto` leaves it alone.
+CREATE TABLE scheduled_tasks (
+    name TEXT PRIMARY KEY,
+    locked_by TEXT,
+    locked_until DATETIME,
+    last_due_at DATETIME,
+    last_run_at DATETIME,
+    last_duration_ms INTEGER,
+    last_status TEXT,
+    last_error TEXT,
+    updated_at DATETIME NOT NULL
+);
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DBTX is implemented by both *sqlx.DB and *sqlx.Tx, so generated queries
+// can run directly against the database or inside a transaction.
+type DBTX interface {
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
+}
@@ -0,0 +1,137 @@
+package seeders
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"<<!.ProjectName!>>/internal/database"
+)
+
+// Seeder loads development or demo data. Seeders register themselves from
+// init() in this package, usually generated by `steamboat make seeder`.
+type Seeder struct {
+	Name      string
+	DependsOn []string
+	// Database is the connection the seeder targets; empty means the primary one
+	Database string
+	Run      func(ctx context.Context, tx *sqlx.Tx) error
+}
+
+func (s Seeder) database() string {
+	if s.Database == "" {
+		return database.Primary
+	}
+	return s.Database
+}
+
+var registry = make(map[string]Seeder)
+
+// Register adds a seeder to the registry. It panics on duplicate names.
+func Register(s Seeder) {
+	if _, exists := registry[s.Name]; exists {
+		panic(fmt.Sprintf("seeders: duplicate seeder %q", s.Name))
+	}
+	registry[s.Name] = s
+}
+
+// Names returns the registered seeder names in alphabetical order.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Run executes the named seeders, or every seeder of the given database when
+// no name is given, together with their dependencies. Dependencies always run
+// first and everything runs in a single transaction on db.
+func Run(ctx context.Context, db *sqlx.DB, dbName string, names ...string) error {
+	ordered, err := resolve(dbName, names)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, s := range ordered {
+		if err := s.Run(ctx, tx); err != nil {
+			return fmt.Errorf("seeder %s failed: %w", s.Name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit seeders: %w", err)
+	}
+
+	return nil
+}
+
+// resolve returns the seeders to run in dependency order
+func resolve(dbName string, names []string) ([]Seeder, error) {
+	if dbName == "" {
+		dbName = database.Primary
+	}
+
+	if len(names) == 0 {
+		for _, name := range Names() {
+			if registry[name].database() == dbName {
+				names = append(names, name)
+			}
+		}
+	}
+
+	var ordered []Seeder
+	visited := make(map[string]bool)
+	visiting := make(map[string]bool)
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		if visited[name] {
+			return nil
+		}
+		if visiting[name] {
+			return fmt.Errorf("seeders: dependency cycle: %s", strings.Join(append(path, name), " -> "))
+		}
+
+		s, ok := registry[name]
+		if !ok {
+			if len(path) > 0 {
+				return fmt.Errorf("seeders: %s depends on unknown seeder %q", path[len(path)-1], name)
+			}
+			return fmt.Errorf("seeders: unknown seeder %q", name)
+		}
+		if s.database() != dbName {
+			return fmt.Errorf("seeders: %s belongs to database %q, not %q", name, s.database(), dbName)
+		}
+
+		visiting[name] = true
+		for _, dep := range s.DependsOn {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		visiting[name] = false
+		visited[name] = true
+
+		ordered = append(ordered, s)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
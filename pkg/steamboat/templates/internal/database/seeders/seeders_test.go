@@ -0,0 +1,147 @@
+package seeders
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupSeedersTest(t *testing.T) *sqlx.DB {
+	// Start every test from an empty registry
+	saved := registry
+	registry = make(map[string]Seeder)
+	t.Cleanup(func() { registry = saved })
+
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(`CREATE TABLE seeded (name TEXT NOT NULL)`); err != nil {
+		t.Fatalf("Failed to create test table: %v", err)
+	}
+
+	return db
+}
+
+func recordSeeder(name string, deps ...string) Seeder {
+	return Seeder{
+		Name:      name,
+		DependsOn: deps,
+		Run: func(ctx context.Context, tx *sqlx.Tx) error {
+			_, err := tx.ExecContext(ctx, `INSERT INTO seeded (name) VALUES (?)`, name)
+			return err
+		},
+	}
+}
+
+func seededNames(t *testing.T, db *sqlx.DB) []string {
+	var names []string
+	if err := db.Select(&names, `SELECT name FROM seeded ORDER BY rowid`); err != nil {
+		t.Fatalf("Failed to read seeded rows: %v", err)
+	}
+	return names
+}
+
+func TestRunAllInDependencyOrder(t *testing.T) {
+	db := setupSeedersTest(t)
+
+	Register(recordSeeder("comments", "posts", "users"))
+	Register(recordSeeder("posts", "users"))
+	Register(recordSeeder("users"))
+
+	if err := Run(context.Background(), db, ""); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	got := strings.Join(seededNames(t, db), ",")
+	if got != "users,posts,comments" {
+		t.Errorf("Expected users,posts,comments, got %s", got)
+	}
+}
+
+func TestRunNamedIncludesDependencies(t *testing.T) {
+	db := setupSeedersTest(t)
+
+	Register(recordSeeder("users"))
+	Register(recordSeeder("posts", "users"))
+	Register(recordSeeder("tags"))
+
+	if err := Run(context.Background(), db, "", "posts"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	got := strings.Join(seededNames(t, db), ",")
+	if got != "users,posts" {
+		t.Errorf("Expected users,posts, got %s", got)
+	}
+}
+
+func TestRunRollsBackOnError(t *testing.T) {
+	db := setupSeedersTest(t)
+
+	Register(recordSeeder("users"))
+	Register(Seeder{
+		Name:      "broken",
+		DependsOn: []string{"users"},
+		Run: func(ctx context.Context, tx *sqlx.Tx) error {
+			return errors.New("boom")
+		},
+	})
+
+	if err := Run(context.Background(), db, ""); err == nil {
+		t.Fatal("Expected error from failing seeder")
+	}
+
+	if names := seededNames(t, db); len(names) != 0 {
+		t.Errorf("Expected transaction to be rolled back, got %v", names)
+	}
+}
+
+func TestRunUnknownSeeder(t *testing.T) {
+	db := setupSeedersTest(t)
+
+	if err := Run(context.Background(), db, "", "missing"); err == nil {
+		t.Error("Expected error for unknown seeder")
+	}
+}
+
+func TestRunDetectsCycles(t *testing.T) {
+	db := setupSeedersTest(t)
+
+	Register(recordSeeder("a", "b"))
+	Register(recordSeeder("b", "a"))
+
+	err := Run(context.Background(), db, "")
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("Expected dependency cycle error, got %v", err)
+	}
+}
+
+func TestRunFiltersByDatabase(t *testing.T) {
+	db := setupSeedersTest(t)
+
+	Register(recordSeeder("users"))
+	events := recordSeeder("events")
+	events.Database = "analytics"
+	Register(events)
+
+	if err := Run(context.Background(), db, "analytics"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	got := strings.Join(seededNames(t, db), ",")
+	if got != "events" {
+		t.Errorf("Expected only events, got %s", got)
+	}
+
+	if err := Run(context.Background(), db, "", "events"); err == nil {
+		t.Error("Expected error when running a seeder against another database")
+	}
+}
@@ -0,0 +1,97 @@
+// Package env loads the .env files for the current environment.
+//
+// Files are loaded in this order, later files overriding earlier ones:
+//
+//	.env          shared defaults, committed
+//	.env.{env}    per environment, e.g. .env.test or .env.production
+//	.env.local    machine-specific overrides, not committed
+//
+// Variables already set in the process environment always win over every
+// file. The environment is named by APP_ENV and defaults to development.
+package env
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// Default is the environment used when APP_ENV is not set
+const Default = "development"
+
+// Load loads the .env files of the named environment into the process
+// environment and returns the name. An empty name falls back to APP_ENV
+// from the process, then APP_ENV from .env, then Default. Missing files
+// are skipped.
+func Load(name string) (string, error) {
+	process := make(map[string]bool)
+	for _, entry := range os.Environ() {
+		key, _, _ := strings.Cut(entry, "=")
+		process[key] = true
+	}
+
+	base, err := read(".env")
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case name != "":
+	case os.Getenv("APP_ENV") != "":
+		name = os.Getenv("APP_ENV")
+	case base["APP_ENV"] != "":
+		name = base["APP_ENV"]
+	default:
+		name = Default
+	}
+
+	values := base
+	if values == nil {
+		values = make(map[string]string)
+	}
+	for _, file := range []string{".env." + name, ".env.local"} {
+		vars, err := read(file)
+		if err != nil {
+			return "", err
+		}
+		for key, value := range vars {
+			values[key] = value
+		}
+	}
+
+	for key, value := range values {
+		if !process[key] {
+			os.Setenv(key, value)
+		}
+	}
+	os.Setenv("APP_ENV", name)
+
+	return name, nil
+}
+
+// Name returns the current environment
+func Name() string {
+	if name := os.Getenv("APP_ENV"); name != "" {
+		return name
+	}
+	return Default
+}
+
+// IsProduction reports whether the app runs in production
+func IsProduction() bool {
+	return Name() == "production"
+}
+
+func read(path string) (map[string]string, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	vars, err := godotenv.Read(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+	return vars, nil
+}
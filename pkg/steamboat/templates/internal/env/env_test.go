@@ -0,0 +1,83 @@
+package env
+
+import (
+	"os"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) {
+	t.Chdir(t.TempDir())
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	t.Setenv("APP_ENV", "")
+	t.Setenv("PORT", "")
+	t.Setenv("DB_URL", "")
+	t.Setenv("SESSION_KEY", "from-process")
+	os.Unsetenv("APP_ENV")
+	os.Unsetenv("PORT")
+	os.Unsetenv("DB_URL")
+
+	writeFiles(t, map[string]string{
+		".env":       "PORT=8080\nDB_URL=app.db\nSESSION_KEY=from-file\n",
+		".env.test":  "DB_URL=test.db\n",
+		".env.local": "PORT=9090\n",
+	})
+
+	name, err := Load("test")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if name != "test" || os.Getenv("APP_ENV") != "test" {
+		t.Errorf("Expected environment test, got %q (APP_ENV=%q)", name, os.Getenv("APP_ENV"))
+	}
+	if got := os.Getenv("DB_URL"); got != "test.db" {
+		t.Errorf("Expected .env.test to override .env, got DB_URL=%q", got)
+	}
+	if got := os.Getenv("PORT"); got != "9090" {
+		t.Errorf("Expected .env.local to override .env, got PORT=%q", got)
+	}
+	if got := os.Getenv("SESSION_KEY"); got != "from-process" {
+		t.Errorf("Expected the process environment to win, got SESSION_KEY=%q", got)
+	}
+}
+
+func TestLoadEnvironmentName(t *testing.T) {
+	t.Setenv("APP_ENV", "")
+	os.Unsetenv("APP_ENV")
+
+	writeFiles(t, map[string]string{".env": "APP_ENV=staging\n"})
+
+	name, err := Load("")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if name != "staging" {
+		t.Errorf("Expected APP_ENV from .env, got %q", name)
+	}
+
+	t.Setenv("APP_ENV", "production")
+	if name, _ := Load(""); name != "production" || !IsProduction() {
+		t.Errorf("Expected APP_ENV from the process, got %q", name)
+	}
+}
+
+func TestLoadWithoutFiles(t *testing.T) {
+	t.Setenv("APP_ENV", "")
+	os.Unsetenv("APP_ENV")
+	t.Chdir(t.TempDir())
+
+	name, err := Load("")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if name != Default || Name() != Default {
+		t.Errorf("Expected %s, got %q", Default, name)
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"<<!.ProjectName!>>/internal/apperr"
+	"<<!.ProjectName!>>/internal/mail"
+	"<<!.ProjectName!>>/internal/views/pages"
+)
+
+// DevMail lists the mail sent in development
+func (h *Handlers) DevMail(w http.ResponseWriter, r *http.Request) error {
+	var messages []mail.Message
+	inbox := mail.Captured(h.mailer)
+	if inbox != nil {
+		var err error
+		if messages, err = inbox.Messages(); err != nil {
+			return apperr.Internal(err)
+		}
+	}
+	return pages.DevMail(messages, inbox != nil).Render(r.Context(), w)
+}
+
+// DevMailMessage shows one message sent in development
+func (h *Handlers) DevMailMessage(w http.ResponseWriter, r *http.Request) error {
+	msg, err := h.devMailMessage(r)
+	if err != nil {
+		return err
+	}
+	return pages.DevMailMessage(msg).Render(r.Context(), w)
+}
+
+// DevMailHTML serves the HTML body of a message for the preview iframe. The
+// sandbox keeps scripts in the email from running on the app's origin.
+func (h *Handlers) DevMailHTML(w http.ResponseWriter, r *http.Request) error {
+	msg, err := h.devMailMessage(r)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, err = w.Write([]byte(msg.HTML))
+	return err
+}
+
+func (h *Handlers) devMailMessage(r *http.Request) (mail.Message, error) {
+	inbox := mail.Captured(h.mailer)
+	if inbox == nil {
+		return mail.Message{}, apperr.NotFound("")
+	}
+	messages, err := inbox.Messages()
+	if err != nil {
+		return mail.Message{}, apperr.Internal(err)
+	}
+
+	id := chi.URLParam(r, "id")
+	for _, msg := range messages {
+		if msg.ID == id {
+			return msg, nil
+		}
+	}
+	return mail.Message{}, apperr.NotFound("No message with this ID")
+}
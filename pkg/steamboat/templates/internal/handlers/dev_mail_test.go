@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"<<!.ProjectName!>>/internal/config"
+	"<<!.ProjectName!>>/internal/database"
+	"<<!.ProjectName!>>/internal/mail"
+	"<<!.ProjectName!>>/internal/storage"
+)
+
+func TestDevMail(t *testing.T) {
+	db := database.New(config.Database{URL: ":memory:"})
+	defer db.Close()
+
+	mailer := mail.NewMemory("App <noreply@example.com>")
+	h := New(db, mailer, storage.New(config.Storage{Driver: "memory"}))
+
+	msg := &mail.Message{To: []string{"ada@example.com"}, Subject: "Welcome", HTML: "<p>Hello <script>alert(1)</script></p>"}
+	if err := mailer.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	router := chi.NewRouter()
+	router.Get("/_dev/mail", Wrap(h.DevMail))
+	router.Get("/_dev/mail/{id}", Wrap(h.DevMailMessage))
+	router.Get("/_dev/mail/{id}/html", Wrap(h.DevMailHTML))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/_dev/mail", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "/_dev/mail/"+msg.ID) {
+		t.Errorf("expected the list to link the message, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/_dev/mail/"+msg.ID, nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Welcome") {
+		t.Errorf("expected the message page, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/_dev/mail/"+msg.ID+"/html", nil))
+	if w.Header().Get("Content-Security-Policy") != "sandbox" {
+		t.Errorf("expected a sandboxed preview, got headers %v", w.Header())
+	}
+	if w.Body.String() != msg.HTML {
+		t.Errorf("expected the HTML body, got %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/_dev/mail/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestDevMailNotCaptured(t *testing.T) {
+	db := database.New(config.Database{URL: ":memory:"})
+	defer db.Close()
+
+	h := New(db, mail.NewLog("App <noreply@example.com>"), storage.New(config.Storage{Driver: "memory"}))
+
+	w := httptest.NewRecorder()
+	Wrap(h.DevMail)(w, httptest.NewRequest(http.MethodGet, "/_dev/mail", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "MAIL_DRIVER") {
+		t.Errorf("expected a note about MAIL_DRIVER, got %d: %s", w.Code, w.Body.String())
+	}
+}
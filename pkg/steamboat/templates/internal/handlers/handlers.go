@@ -1,15 +1,52 @@
 package handlers
 
 import (
+	"net/http"
+	"sync/atomic"
+
+	"<<!.ProjectName!>>/internal/binding"
 	"<<!.ProjectName!>>/internal/database"
+	"<<!.ProjectName!>>/internal/jobs"
+	"<<!.ProjectName!>>/internal/mail"
+	"<<!.ProjectName!>>/internal/storage"
 )
 
 type Handlers struct {
 	db database.Service
+	// jobs enqueues background work, e.g. h.jobs.Enqueue(ctx, jobs.SendWelcomeEmail{}, jobs.Options{})
+	jobs *jobs.Queue
+	// mailer sends email, e.g. h.mailer.Send(ctx, msg)
+	mailer mail.Mailer
+	// storage keeps uploads, e.g. storage.Upload(r, h.storage, storage.UploadOptions{})
+	storage *storage.Storage
+	// binder decodes and validates requests, e.g. h.binder.Bind(r, &form, "name", "email")
+	binder *binding.Binder
+	ready  atomic.Bool
 }
 
-func New(db database.Service) *Handlers {
-	return &Handlers{
-		db: db,
+func New(db database.Service, mailer mail.Mailer, files *storage.Storage) *Handlers {
+	h := &Handlers{
+		db:      db,
+		jobs:    jobs.New(db.DB()),
+		mailer:  mailer,
+		storage: files,
+		binder:  binding.New(db),
 	}
+	h.ready.Store(true)
+	return h
+}
+
+// StorageFile serves the stored file behind a signed URL from h.storage.URL
+func (h *Handlers) StorageFile(w http.ResponseWriter, r *http.Request) {
+	h.storage.ServeHTTP(w, r)
+}
+
+// SetReady sets whether /readyz reports the app as ready to take traffic
+func (h *Handlers) SetReady(ready bool) {
+	h.ready.Store(ready)
+}
+
+// Ready reports whether /readyz reports the app as ready
+func (h *Handlers) Ready() bool {
+	return h.ready.Load()
 }
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// readinessTimeout bounds the database pings of a readiness check
+const readinessTimeout = 2 * time.Second
+
+type healthResponse struct {
+	Status    string                        `json:"status"`
+	Databases map[string]databaseHealthJSON `json:"databases,omitempty"`
+}
+
+type databaseHealthJSON struct {
+	Status             string `json:"status"`
+	Error              string `json:"error,omitempty"`
+	Latency            string `json:"latency"`
+	OpenConnections    int    `json:"open_connections"`
+	InUse              int    `json:"in_use"`
+	Idle               int    `json:"idle"`
+	MaxOpenConnections int    `json:"max_open_connections"`
+	WaitCount          int64  `json:"wait_count"`
+	WaitDuration       string `json:"wait_duration"`
+}
+
+// Healthz reports that the process is alive. It does not touch dependencies,
+// so a slow database does not get the app restarted.
+func (h *Handlers) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// Readyz reports whether the app can take traffic: it is not shutting down
+// and every database connection answers a ping
+func (h *Handlers) Readyz(w http.ResponseWriter, r *http.Request) {
+	if !h.ready.Load() {
+		writeHealth(w, http.StatusServiceUnavailable, healthResponse{Status: "shutting down"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	health, err := h.db.Health(ctx)
+
+	response := healthResponse{Status: "ok", Databases: make(map[string]databaseHealthJSON, len(health))}
+	for name, db := range health {
+		status := databaseHealthJSON{
+			Status:             "up",
+			Latency:            db.Latency.String(),
+			OpenConnections:    db.Stats.OpenConnections,
+			InUse:              db.Stats.InUse,
+			Idle:               db.Stats.Idle,
+			MaxOpenConnections: db.Stats.MaxOpenConnections,
+			WaitCount:          db.Stats.WaitCount,
+			WaitDuration:       db.Stats.WaitDuration.String(),
+		}
+		if db.Err != nil {
+			status.Status, status.Error = "down", db.Err.Error()
+		}
+		response.Databases[name] = status
+	}
+
+	code := http.StatusOK
+	if err != nil {
+		response.Status, code = "unavailable", http.StatusServiceUnavailable
+	}
+	writeHealth(w, code, response)
+}
+
+func writeHealth(w http.ResponseWriter, code int, response healthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	"<<!.ProjectName!>>/internal/config"
+	"<<!.ProjectName!>>/internal/database"
+	"<<!.ProjectName!>>/internal/mail"
+	"<<!.ProjectName!>>/internal/storage"
+)
+
+func TestHealthz(t *testing.T) {
+	h := New(database.New(config.Database{URL: ":memory:"}), mail.NewMemory(""), storage.New(config.Storage{Driver: "memory"}))
+
+	w := httptest.NewRecorder()
+	h.Healthz(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	h := New(database.New(config.Database{URL: ":memory:"}), mail.NewMemory(""), storage.New(config.Storage{Driver: "memory"}))
+
+	w := httptest.NewRecorder()
+	h.Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response healthResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Databases[database.Primary].Status != "up" {
+		t.Errorf("expected primary database to be up, got %+v", response.Databases)
+	}
+
+	h.SetReady(false)
+	w = httptest.NewRecorder()
+	h.Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d while shutting down, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
+
+type downService struct {
+	database.Service
+}
+
+func (downService) DB() *sqlx.DB { return nil }
+
+func (downService) Health(ctx context.Context) (map[string]database.Health, error) {
+	err := errors.New("database is locked")
+	return map[string]database.Health{database.Primary: {Err: err}}, err
+}
+
+func TestReadyzDatabaseDown(t *testing.T) {
+	h := New(downService{}, mail.NewMemory(""), storage.New(config.Storage{Driver: "memory"}))
+
+	w := httptest.NewRecorder()
+	h.Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "database is locked") {
+		t.Errorf("expected the ping error in the response, got %s", body)
+	}
+}
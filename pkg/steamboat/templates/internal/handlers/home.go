@@ -2,14 +2,12 @@ package handlers
 
 import (
 	"net/http"
+
+	"<<!.ProjectName!>>/internal/tracing"
 	"<<!.ProjectName!>>/internal/views/pages"
 )
 
-func (h *Handlers) HomeHandler(w http.ResponseWriter, r *http.Request) {
-	component := pages.Home()
-	err := component.Render(r.Context(), w)
-	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
-	}
+func (h *Handlers) HomeHandler(w http.ResponseWriter, r *http.Request) error {
+	component := tracing.Component("pages.Home", pages.Home())
+	return component.Render(r.Context(), w)
 }
@@ -6,20 +6,23 @@ import (
 	"strings"
 	"testing"
 	
+	"<<!.ProjectName!>>/internal/config"
 	"<<!.ProjectName!>>/internal/database"
+	"<<!.ProjectName!>>/internal/mail"
+	"<<!.ProjectName!>>/internal/storage"
 )
 
 func TestHomeHandler(t *testing.T) {
 	// Use a temporary in-memory database for testing
-	db := database.New()
+	db := database.New(config.Database{URL: ":memory:"})
 	defer db.Close()
 	
-	h := New(db)
+	h := New(db, mail.NewMemory(""), storage.New(config.Storage{Driver: "memory"}))
 
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
 	w := httptest.NewRecorder()
 
-	h.HomeHandler(w, req)
+	Wrap(h.HomeHandler)(w, req)
 
 	if w.Code != http.StatusOK {
 		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"<<!.ProjectName!>>/internal/apperr"
+)
+
+// Wrap adapts a handler that returns its error. The error is logged with the
+// request ID and rendered by apperr.Write, as the error page or as problem
+// details; return an *apperr.HTTPError to choose the status and the message
+// shown. Once the handler has started the response the error is only logged.
+func Wrap(fn func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		err := fn(ww, r)
+		if err == nil {
+			return
+		}
+		if ww.Status() != 0 {
+			apperr.Log(r, err)
+			return
+		}
+		apperr.Write(w, r, err)
+	}
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"<<!.ProjectName!>>/internal/apperr"
+)
+
+func TestWrap(t *testing.T) {
+	handler := Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		return apperr.Forbidden("Admins only")
+	})
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "Admins only") {
+		t.Errorf("expected the error page, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// An error after the response started cannot change it
+	handler = Wrap(func(w http.ResponseWriter, r *http.Request) error {
+		w.Write([]byte("partial"))
+		return errors.New("connection reset")
+	})
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Errorf("expected the started response to be left alone, got %d: %s", w.Code, w.Body.String())
+	}
+}
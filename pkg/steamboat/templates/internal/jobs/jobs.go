@@ -0,0 +1,294 @@
+// Package jobs runs work outside the request cycle. Jobs are stored in the
+// jobs table of the primary database and run by a worker Pool that the server
+// starts, with exponential backoff between attempts. A job that fails on its
+// last attempt is marked dead and kept until it is retried or purged.
+//
+// Job types live in this package and register their handler from init(),
+// usually generated by `steamboat make job`. Handlers get the services they
+// need through Deps rather than importing the packages that build them:
+//
+//	type SendWelcomeEmail struct {
+//		UserID int `json:"user_id"`
+//	}
+//
+//	func (SendWelcomeEmail) Kind() string { return "send_welcome_email" }
+//
+//	func init() { Register(handleSendWelcomeEmail) }
+//
+//	func handleSendWelcomeEmail(ctx context.Context, deps Deps, job SendWelcomeEmail) error {
+//		return deps.Mailer.Send(ctx, &mail.Message{...})
+//	}
+//
+// and are enqueued with their payload:
+//
+//	queue.Enqueue(ctx, jobs.SendWelcomeEmail{UserID: user.ID}, jobs.Options{Delay: time.Minute})
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+
+	"<<!.ProjectName!>>/internal/database"
+	"<<!.ProjectName!>>/internal/mail"
+	"<<!.ProjectName!>>/internal/storage"
+)
+
+// DefaultMaxAttempts is the number of attempts of a job enqueued without MaxAttempts
+const DefaultMaxAttempts = 5
+
+// Job is the payload of a background job. It is stored as JSON, so only
+// exported fields survive the round trip. Kind must not depend on the fields.
+type Job interface {
+	Kind() string
+}
+
+// Status is the state of a stored job
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	// StatusDead jobs failed their last attempt and wait for `steamboat jobs retry`
+	StatusDead Status = "dead"
+)
+
+// ErrDuplicate is returned by Enqueue when a pending or running job holds the unique key
+var ErrDuplicate = errors.New("jobs: a job with this unique key is already queued")
+
+// Options controls how a job is enqueued. The zero value runs the job as
+// soon as a worker is free.
+type Options struct {
+	// Delay postpones the first attempt
+	Delay time.Duration
+	// Priority orders due jobs, highest first
+	Priority int
+	// UniqueKey rejects the job with ErrDuplicate while another pending or
+	// running job has the same key, e.g. "welcome:42"
+	UniqueKey string
+	// MaxAttempts is the number of attempts before the job is dead (default: DefaultMaxAttempts)
+	MaxAttempts int
+}
+
+// Record is a job as stored in the jobs table
+type Record struct {
+	ID          int64          `db:"id"`
+	Kind        string         `db:"kind"`
+	Payload     string         `db:"payload"`
+	Status      Status         `db:"status"`
+	Priority    int            `db:"priority"`
+	UniqueKey   sql.NullString `db:"unique_key"`
+	Attempts    int            `db:"attempts"`
+	MaxAttempts int            `db:"max_attempts"`
+	LastError   sql.NullString `db:"last_error"`
+	RunAt       time.Time      `db:"run_at"`
+	LockedAt    sql.NullTime   `db:"locked_at"`
+	CreatedAt   time.Time      `db:"created_at"`
+	UpdatedAt   time.Time      `db:"updated_at"`
+}
+
+// Deps are the services the pool hands to every job handler
+type Deps struct {
+	DB database.Service
+	// Mailer delivers right away. It is the driver behind the queued
+	// mailer, so mail sent from a job is not queued again.
+	Mailer  mail.Mailer
+	Storage *storage.Storage
+}
+
+type handler func(ctx context.Context, deps Deps, payload []byte) error
+
+var registry = make(map[string]handler)
+
+// Register adds the handler of the job type T. It panics when the kind is
+// already registered.
+func Register[T Job](handle func(ctx context.Context, deps Deps, job T) error) {
+	var zero T
+	kind := zero.Kind()
+	if _, exists := registry[kind]; exists {
+		panic(fmt.Sprintf("jobs: duplicate job %q", kind))
+	}
+
+	registry[kind] = func(ctx context.Context, deps Deps, payload []byte) error {
+		var job T
+		if err := json.Unmarshal(payload, &job); err != nil {
+			return Permanent(fmt.Errorf("failed to decode %s payload: %w", kind, err))
+		}
+		return handle(ctx, deps, job)
+	}
+}
+
+// permanentError marks an error that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the job is marked dead right away instead of being retried
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Queue stores jobs in the jobs table
+type Queue struct {
+	db *sqlx.DB
+}
+
+// New returns a queue backed by the jobs table of db
+func New(db *sqlx.DB) *Queue {
+	return &Queue{db: db}
+}
+
+// Enqueue stores job and returns its ID
+func (q *Queue) Enqueue(ctx context.Context, job Job, opts Options) (int64, error) {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode %s payload: %w", job.Kind(), err)
+	}
+
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	var uniqueKey sql.NullString
+	if opts.UniqueKey != "" {
+		uniqueKey = sql.NullString{String: opts.UniqueKey, Valid: true}
+	}
+
+	now := time.Now().UTC()
+	result, err := q.db.ExecContext(ctx, `INSERT INTO jobs
+		(kind, payload, status, priority, unique_key, max_attempts, run_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		job.Kind(), string(payload), StatusPending, opts.Priority, uniqueKey, maxAttempts, now.Add(opts.Delay), now, now)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, ErrDuplicate
+		}
+		return 0, fmt.Errorf("failed to enqueue %s: %w", job.Kind(), err)
+	}
+	return result.LastInsertId()
+}
+
+// List returns the jobs with the given status, or every job when status is
+// empty, newest first
+func (q *Queue) List(ctx context.Context, status Status) ([]Record, error) {
+	query := `SELECT * FROM jobs`
+	var args []interface{}
+	if status != "" {
+		query += ` WHERE status = ?`
+		args = append(args, status)
+	}
+
+	var records []Record
+	if err := q.db.SelectContext(ctx, &records, query+` ORDER BY id DESC`, args...); err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	return records, nil
+}
+
+// Retry makes the given dead jobs, or every dead job when no ID is given,
+// pending again with a fresh set of attempts. It returns how many were retried.
+func (q *Queue) Retry(ctx context.Context, ids ...int64) (int64, error) {
+	now := time.Now().UTC()
+	query := `UPDATE jobs SET status = ?, attempts = 0, run_at = ?, updated_at = ? WHERE status = ?`
+	args := []interface{}{StatusPending, now, now, StatusDead}
+	if len(ids) > 0 {
+		var err error
+		if query, args, err = sqlx.In(query+` AND id IN (?)`, append(args, ids)...); err != nil {
+			return 0, err
+		}
+	}
+
+	result, err := q.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to retry jobs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// Purge deletes the jobs with the given statuses and returns how many were
+// deleted. Pending and running jobs cannot be purged.
+func (q *Queue) Purge(ctx context.Context, statuses ...Status) (int64, error) {
+	for _, status := range statuses {
+		if status != StatusDone && status != StatusDead {
+			return 0, fmt.Errorf("cannot purge %s jobs, only %s and %s", status, StatusDone, StatusDead)
+		}
+	}
+	if len(statuses) == 0 {
+		return 0, nil
+	}
+
+	query, args, err := sqlx.In(`DELETE FROM jobs WHERE status IN (?)`, statuses)
+	if err != nil {
+		return 0, err
+	}
+	result, err := q.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge jobs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// claim marks the most urgent due job as running and returns it, or nil when
+// no job is due. Running jobs locked before stale are due again.
+func (q *Queue) claim(ctx context.Context, now, stale time.Time) (*Record, error) {
+	for {
+		var id int64
+		err := q.db.GetContext(ctx, &id, `SELECT id FROM jobs
+			WHERE (status = ? AND run_at <= ?) OR (status = ? AND locked_at < ?)
+			ORDER BY priority DESC, run_at, id LIMIT 1`,
+			StatusPending, now, StatusRunning, stale)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to find due job: %w", err)
+		}
+
+		// Another worker may have claimed the job in between, then look again
+		var record Record
+		err = q.db.GetContext(ctx, &record, `UPDATE jobs
+			SET status = ?, attempts = attempts + 1, locked_at = ?, updated_at = ?
+			WHERE id = ? AND ((status = ? AND run_at <= ?) OR (status = ? AND locked_at < ?))
+			RETURNING *`,
+			StatusRunning, now, now, id, StatusPending, now, StatusRunning, stale)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim job %d: %w", id, err)
+		}
+		return &record, nil
+	}
+}
+
+// complete marks a job as done
+func (q *Queue) complete(ctx context.Context, id int64) error {
+	now := time.Now().UTC()
+	_, err := q.db.ExecContext(ctx, `UPDATE jobs SET status = ?, last_error = NULL, locked_at = NULL, updated_at = ? WHERE id = ?`,
+		StatusDone, now, id)
+	return err
+}
+
+// fail records the error of an attempt and schedules the next one at
+// retryAt, or marks the job dead when retryAt is zero
+func (q *Queue) fail(ctx context.Context, id int64, cause error, retryAt time.Time) error {
+	now := time.Now().UTC()
+	status := StatusPending
+	if retryAt.IsZero() {
+		status, retryAt = StatusDead, now
+	}
+	_, err := q.db.ExecContext(ctx, `UPDATE jobs SET status = ?, last_error = ?, run_at = ?, locked_at = NULL, updated_at = ? WHERE id = ?`,
+		status, cause.Error(), retryAt.UTC(), now, id)
+	return err
+}
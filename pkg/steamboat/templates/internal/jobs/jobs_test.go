@@ -0,0 +1,266 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+
+	"<<!.ProjectName!>>/internal/config"
+)
+
+type testJob struct {
+	Name string `json:"name"`
+}
+
+func (testJob) Kind() string { return "test" }
+
+func setupJobsTest(t *testing.T) *Queue {
+	// Start every test from an empty registry
+	saved := registry
+	registry = make(map[string]handler)
+	t.Cleanup(func() { registry = saved })
+
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	migration, err := os.ReadFile(filepath.Join("..", "database", "migrations", "000001_create_jobs_table.up.sql"))
+	if err != nil {
+		t.Fatalf("Failed to read jobs migration: %v", err)
+	}
+	if _, err := db.Exec(string(migration)); err != nil {
+		t.Fatalf("Failed to create jobs table: %v", err)
+	}
+
+	return New(db)
+}
+
+func startPool(t *testing.T, q *Queue, deps Deps) *Pool {
+	pool := NewPool(q, config.Jobs{Workers: 2, PollInterval: 10 * time.Millisecond, Timeout: time.Second}, deps)
+	pool.backoff = func(int) time.Duration { return 0 }
+	if err := pool.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { pool.Stop(context.Background()) })
+	return pool
+}
+
+func waitForStatus(t *testing.T, q *Queue, id int64, status Status) Record {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var record Record
+		if err := q.db.Get(&record, `SELECT * FROM jobs WHERE id = ?`, id); err != nil {
+			t.Fatalf("Failed to read job %d: %v", id, err)
+		}
+		if record.Status == status {
+			return record
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Job %d did not reach status %s", id, status)
+	return Record{}
+}
+
+func TestEnqueueAndRun(t *testing.T) {
+	q := setupJobsTest(t)
+
+	got := make(chan string, 1)
+	Register(func(ctx context.Context, deps Deps, job testJob) error {
+		got <- job.Name
+		return nil
+	})
+
+	id, err := q.Enqueue(context.Background(), testJob{Name: "welcome"}, Options{})
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	startPool(t, q, Deps{})
+
+	if name := <-got; name != "welcome" {
+		t.Errorf("Expected payload to round-trip, got %q", name)
+	}
+	if record := waitForStatus(t, q, id, StatusDone); record.Attempts != 1 {
+		t.Errorf("Expected 1 attempt, got %d", record.Attempts)
+	}
+}
+
+func TestRetryUntilDead(t *testing.T) {
+	q := setupJobsTest(t)
+
+	var calls atomic.Int32
+	Register(func(ctx context.Context, deps Deps, job testJob) error {
+		if calls.Add(1) == 2 {
+			panic("boom")
+		}
+		return errors.New("smtp unavailable")
+	})
+
+	id, err := q.Enqueue(context.Background(), testJob{}, Options{MaxAttempts: 3})
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	startPool(t, q, Deps{})
+
+	record := waitForStatus(t, q, id, StatusDead)
+	if record.Attempts != 3 || calls.Load() != 3 {
+		t.Errorf("Expected 3 attempts, got %d with %d calls", record.Attempts, calls.Load())
+	}
+	if record.LastError.String != "smtp unavailable" {
+		t.Errorf("Expected the last error to be kept, got %q", record.LastError.String)
+	}
+
+	if n, err := q.Retry(context.Background(), id); err != nil || n != 1 {
+		t.Fatalf("Expected 1 retried job, got %d, %v", n, err)
+	}
+	waitForStatus(t, q, id, StatusDead)
+	if calls.Load() != 6 {
+		t.Errorf("Expected a fresh set of attempts after retry, got %d calls", calls.Load())
+	}
+}
+
+func TestPermanentError(t *testing.T) {
+	q := setupJobsTest(t)
+
+	Register(func(ctx context.Context, deps Deps, job testJob) error {
+		return Permanent(errors.New("user deleted"))
+	})
+
+	id, _ := q.Enqueue(context.Background(), testJob{}, Options{})
+	unknown, _ := q.Enqueue(context.Background(), unknownJob{}, Options{})
+	startPool(t, q, Deps{})
+
+	if record := waitForStatus(t, q, id, StatusDead); record.Attempts != 1 {
+		t.Errorf("Expected a permanent error to skip retries, got %d attempts", record.Attempts)
+	}
+	if record := waitForStatus(t, q, unknown, StatusDead); record.LastError.String != `no handler registered for job "unknown"` {
+		t.Errorf("Unexpected error for unknown job: %q", record.LastError.String)
+	}
+}
+
+type unknownJob struct{}
+
+func (unknownJob) Kind() string { return "unknown" }
+
+func TestUniqueKey(t *testing.T) {
+	q := setupJobsTest(t)
+	ctx := context.Background()
+
+	id, err := q.Enqueue(ctx, testJob{}, Options{UniqueKey: "welcome:1"})
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if _, err := q.Enqueue(ctx, testJob{}, Options{UniqueKey: "welcome:1"}); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("Expected ErrDuplicate, got %v", err)
+	}
+
+	if err := q.complete(ctx, id); err != nil {
+		t.Fatalf("complete failed: %v", err)
+	}
+	if _, err := q.Enqueue(ctx, testJob{}, Options{UniqueKey: "welcome:1"}); err != nil {
+		t.Errorf("Expected the key to be free once the job is done, got %v", err)
+	}
+}
+
+func TestClaimOrder(t *testing.T) {
+	q := setupJobsTest(t)
+	ctx := context.Background()
+
+	low, _ := q.Enqueue(ctx, testJob{}, Options{})
+	high, _ := q.Enqueue(ctx, testJob{}, Options{Priority: 10})
+	q.Enqueue(ctx, testJob{}, Options{Priority: 20, Delay: time.Hour})
+
+	now := time.Now().UTC()
+	for _, want := range []int64{high, low} {
+		job, err := q.claim(ctx, now, now.Add(-time.Minute))
+		if err != nil || job == nil || job.ID != want {
+			t.Fatalf("Expected job %d, got %+v, %v", want, job, err)
+		}
+	}
+	if job, err := q.claim(ctx, now, now.Add(-time.Minute)); job != nil || err != nil {
+		t.Errorf("Expected the delayed job to wait, got %+v, %v", job, err)
+	}
+
+	// A running job locked before the stale cutoff is claimed again
+	later := now.Add(time.Hour)
+	job, err := q.claim(ctx, now, later)
+	if err != nil || job == nil || job.ID != high || job.Attempts != 2 {
+		t.Errorf("Expected stale job %d on its second attempt, got %+v, %v", high, job, err)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	q := setupJobsTest(t)
+	ctx := context.Background()
+
+	done, _ := q.Enqueue(ctx, testJob{}, Options{})
+	dead, _ := q.Enqueue(ctx, testJob{}, Options{})
+	q.Enqueue(ctx, testJob{}, Options{})
+	q.complete(ctx, done)
+	q.fail(ctx, dead, errors.New("failed"), time.Time{})
+
+	if _, err := q.Purge(ctx, StatusPending); err == nil {
+		t.Error("Expected pending jobs to be protected from purge")
+	}
+	if n, err := q.Purge(ctx, StatusDone, StatusDead); err != nil || n != 2 {
+		t.Errorf("Expected 2 purged jobs, got %d, %v", n, err)
+	}
+	if records, _ := q.List(ctx, ""); len(records) != 1 || records[0].Status != StatusPending {
+		t.Errorf("Expected only the pending job to remain, got %+v", records)
+	}
+}
+
+func TestStartWithoutTable(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	pool := NewPool(New(db), config.Jobs{Workers: 1, PollInterval: time.Second, Timeout: time.Second}, Deps{})
+	if err := pool.Start(context.Background()); err != nil {
+		t.Fatalf("Expected a missing table to only be logged, got %v", err)
+	}
+	if err := pool.Stop(context.Background()); err != nil {
+		t.Errorf("Stop failed: %v", err)
+	}
+}
+
+func TestStopWaitsForCancelledJobs(t *testing.T) {
+	q := setupJobsTest(t)
+
+	started := make(chan struct{})
+	Register(func(ctx context.Context, deps Deps, job testJob) error {
+		close(started)
+		<-ctx.Done()
+		time.Sleep(50 * time.Millisecond)
+		return ctx.Err()
+	})
+	id, _ := q.Enqueue(context.Background(), testJob{}, Options{})
+	pool := startPool(t, q, Deps{})
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := pool.Stop(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected Stop to report the cancellation, got %v", err)
+	}
+
+	var record Record
+	if err := q.db.Get(&record, `SELECT * FROM jobs WHERE id = ?`, id); err != nil {
+		t.Fatal(err)
+	}
+	if record.Status != StatusPending || record.LastError.String != "context canceled" {
+		t.Errorf("Expected the cancelled attempt to be recorded before Stop returned, got %s %q", record.Status, record.LastError.String)
+	}
+}
@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+
+	"<<!.ProjectName!>>/internal/mail"
+)
+
+// Mailer sends messages through the queue. A worker delivers them with
+// Deps.Mailer, retrying failed deliveries with backoff.
+type Mailer struct {
+	queue  *Queue
+	driver mail.Mailer
+}
+
+// NewMailer returns a mailer that enqueues messages for the workers. driver
+// is the mailer the server hands to the pool in Deps; it is kept so
+// mail.Captured can reach its inbox.
+func NewMailer(queue *Queue, driver mail.Mailer) *Mailer {
+	return &Mailer{queue: queue, driver: driver}
+}
+
+func (m *Mailer) Send(ctx context.Context, msg *mail.Message) error {
+	// Report bad addresses now rather than from a worker
+	if err := msg.CheckRecipients(); err != nil {
+		return err
+	}
+	_, err := m.queue.Enqueue(ctx, SendMail{Message: *msg}, Options{})
+	return err
+}
+
+// Driver returns the mailer that delivers the queued messages
+func (m *Mailer) Driver() mail.Mailer {
+	return m.driver
+}
+
+// SendMail is the job that delivers a queued message
+type SendMail struct {
+	Message mail.Message `json:"message"`
+}
+
+func (SendMail) Kind() string { return "send_mail" }
+
+func init() {
+	Register(handleSendMail)
+}
+
+func handleSendMail(ctx context.Context, deps Deps, job SendMail) error {
+	if deps.Mailer == nil {
+		return errors.New("jobs: no mailer set up to deliver queued messages")
+	}
+	return deps.Mailer.Send(ctx, &job.Message)
+}
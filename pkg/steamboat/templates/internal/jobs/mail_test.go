@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"<<!.ProjectName!>>/internal/mail"
+)
+
+func TestMailer(t *testing.T) {
+	q := setupJobsTest(t)
+	Register(handleSendMail)
+
+	memory := mail.NewMemory("App <noreply@example.com>")
+	mailer := NewMailer(q, memory)
+	if mail.Captured(mailer) != memory {
+		t.Error("Expected the inbox of the driver")
+	}
+
+	if err := mailer.Send(context.Background(), &mail.Message{Subject: "Hi"}); err == nil {
+		t.Error("Expected a message without recipients to be rejected")
+	}
+	if err := mailer.Send(context.Background(), &mail.Message{To: []string{"ada@example.com"}, Subject: "Héllo", Text: "Hi"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if messages, _ := memory.Messages(); len(messages) != 0 {
+		t.Fatal("Expected the message to wait in the queue")
+	}
+
+	startPool(t, q, Deps{Mailer: memory})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if messages, _ := memory.Messages(); len(messages) == 1 {
+			if messages[0].Subject != "Héllo" {
+				t.Errorf("Expected the queued message, got %+v", messages[0])
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Queued message was not delivered")
+}
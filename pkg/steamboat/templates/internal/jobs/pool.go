@@ -0,0 +1,208 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"<<!.ProjectName!>>/internal/config"
+	"<<!.ProjectName!>>/internal/tracing"
+	"<<!.ProjectName!>>/internal/utils"
+)
+
+// cancelGrace bounds the wait for cancelled jobs in Stop
+const cancelGrace = 5 * time.Second
+
+// Pool runs due jobs on up to cfg.Workers goroutines
+type Pool struct {
+	queue   *Queue
+	cfg     config.Jobs
+	deps    Deps
+	backoff func(attempt int) time.Duration
+
+	// ctx is the parent of every running job, cancel aborts them
+	ctx    context.Context
+	cancel context.CancelFunc
+	stop   chan struct{}
+	once   sync.Once
+	wg     sync.WaitGroup
+}
+
+// NewPool returns a pool that runs the jobs of queue, handing deps to their handlers
+func NewPool(queue *Queue, cfg config.Jobs, deps Deps) *Pool {
+	return &Pool{queue: queue, cfg: cfg, deps: deps, backoff: Backoff}
+}
+
+// Backoff returns the wait after the given failed attempt: 2s, 4s, 8s and so
+// on with up to 10% jitter, capped at one hour
+func Backoff(attempt int) time.Duration {
+	wait := time.Hour
+	if attempt < 12 {
+		wait = time.Duration(1<<attempt) * time.Second
+	}
+	return wait + time.Duration(rand.Int64N(int64(wait)/10+1))
+}
+
+// Start starts the workers. Without the jobs table, e.g. before the first
+// `steamboat migrate`, it logs a warning and runs nothing.
+func (p *Pool) Start(ctx context.Context) error {
+	var tables int
+	if err := p.queue.db.GetContext(ctx, &tables, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'jobs'`); err != nil {
+		return fmt.Errorf("failed to look up jobs table: %w", err)
+	}
+	if tables == 0 {
+		logger().Warn("Jobs table not found, run `steamboat migrate` to start the job workers")
+		return nil
+	}
+
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	p.stop = make(chan struct{})
+
+	p.wg.Add(1)
+	go p.loop()
+
+	logger().Info("Job workers started", "workers", p.cfg.Workers)
+	return nil
+}
+
+// Stop stops claiming jobs and waits for the running ones. When ctx is done
+// first the running jobs are cancelled and Stop waits up to cancelGrace for
+// them to record their outcome; they fail and are retried later.
+func (p *Pool) Stop(ctx context.Context) error {
+	if p.stop == nil {
+		return nil
+	}
+	p.once.Do(func() { close(p.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		// Let the cancelled jobs record their outcome before the database closes
+		select {
+		case <-done:
+		case <-time.After(cancelGrace):
+			logger().Warn("Cancelled jobs did not return in time", "grace", cancelGrace)
+		}
+		return fmt.Errorf("cancelled running jobs: %w", ctx.Err())
+	}
+}
+
+// loop claims a due job whenever a worker is free and polls while none is due
+func (p *Pool) loop() {
+	defer p.wg.Done()
+
+	slots := make(chan struct{}, p.cfg.Workers)
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-p.stop:
+			return
+		}
+
+		now := time.Now().UTC()
+		job, err := p.queue.claim(p.ctx, now, now.Add(-2*p.cfg.Timeout))
+		if err != nil || job == nil {
+			<-slots
+			if err != nil {
+				logger().Error("Failed to claim job", "error", err)
+			}
+
+			select {
+			case <-time.After(p.cfg.PollInterval):
+			case <-p.stop:
+				return
+			}
+			continue
+		}
+
+		p.wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				p.wg.Done()
+			}()
+			p.run(job)
+		}()
+	}
+}
+
+// run runs one attempt of job and records its outcome
+func (p *Pool) run(job *Record) {
+	ctx, cancel := context.WithTimeout(p.ctx, p.cfg.Timeout)
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "job "+job.Kind,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.Int64("job.id", job.ID),
+			attribute.Int("job.attempt", job.Attempts),
+		))
+	defer span.End()
+
+	log := logger().With("job", job.Kind, "id", job.ID, "attempt", job.Attempts)
+
+	var err error
+	if job.Attempts > job.MaxAttempts {
+		// The last attempt never finished, e.g. because the process crashed
+		err = Permanent(fmt.Errorf("attempt %d did not finish within %s", job.MaxAttempts, p.cfg.Timeout))
+	} else {
+		err = p.handle(ctx, job)
+	}
+	tracing.RecordError(span, err)
+
+	// The outcome is recorded even when Stop cancelled the job
+	var permanent *permanentError
+	switch {
+	case err == nil:
+		err = p.queue.complete(context.Background(), job.ID)
+		log.Debug("Job done")
+	case job.Attempts >= job.MaxAttempts || errors.As(err, &permanent):
+		log.Error("Job failed, marked dead", "error", err)
+		err = p.queue.fail(context.Background(), job.ID, err, time.Time{})
+	default:
+		retryAt := time.Now().Add(p.backoff(job.Attempts))
+		log.Warn("Job failed, retrying", "error", err, "retry_at", retryAt)
+		err = p.queue.fail(context.Background(), job.ID, err, retryAt)
+	}
+	if err != nil {
+		log.Error("Failed to record job outcome", "error", err)
+	}
+}
+
+// handle runs the registered handler of job, turning a panic into an error
+func (p *Pool) handle(ctx context.Context, job *Record) (err error) {
+	handle, ok := registry[job.Kind]
+	if !ok {
+		return Permanent(fmt.Errorf("no handler registered for job %q", job.Kind))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handle(ctx, p.deps, []byte(job.Payload))
+}
+
+func logger() *slog.Logger {
+	if utils.Logger != nil {
+		return utils.Logger
+	}
+	return slog.Default()
+}
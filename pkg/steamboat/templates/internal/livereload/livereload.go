@@ -0,0 +1,155 @@
+// Package livereload reloads the browser in development. Pages rendered
+// through layouts.Base get a small script that listens on an event stream at
+// Path. The stream greets every connection with the ID of the running
+// server; when `steamboat serve` restarts the app the stream drops, the
+// script reconnects and, seeing a new ID, reloads the page. Stylesheet edits
+// under internal/assets/static only swap the stylesheets.
+//
+// It is mounted only outside production.
+package livereload
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Path is the URL of the event stream
+const Path = "/_dev/livereload"
+
+// Events sent on the stream
+const (
+	// EventHello carries the server ID, sent on every connection
+	EventHello = "hello"
+	// EventReload asks for a full page reload
+	EventReload = "reload"
+	// EventCSS asks for the stylesheets to be fetched again
+	EventCSS = "css"
+)
+
+// keepAlive is how often a comment is sent so proxies keep the stream open
+const keepAlive = 15 * time.Second
+
+// ID identifies this run of the server
+var ID = newID()
+
+var clients = struct {
+	sync.Mutex
+	subs   map[chan string]struct{}
+	closed bool
+}{subs: make(map[chan string]struct{})}
+
+type contextKey struct{}
+
+// Middleware marks requests so layouts.Base renders the reload script
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), contextKey{}, true)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Enabled reports whether the reload script belongs in the page being rendered
+func Enabled(ctx context.Context) bool {
+	enabled, _ := ctx.Value(contextKey{}).(bool)
+	return enabled
+}
+
+// Handler serves the event stream
+func Handler(w http.ResponseWriter, r *http.Request) {
+	events, ok := subscribe()
+	if !ok {
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	defer unsubscribe(events)
+
+	// The stream outlives the server write timeout
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+
+	// Retry quickly so the page reloads as soon as the new build is up
+	fmt.Fprintf(w, "retry: 500\nevent: %s\ndata: %s\n\n", EventHello, ID)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, ID)
+		case <-ticker.C:
+			fmt.Fprint(w, ": ping\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// Notify sends event to every connected browser
+func Notify(event string) {
+	clients.Lock()
+	defer clients.Unlock()
+	for events := range clients.subs {
+		select {
+		case events <- event:
+		default:
+			// The browser is behind; it will get the next event
+		}
+	}
+}
+
+// Close ends every stream and refuses new ones, so a shutting down server
+// does not wait on open browser tabs
+func Close() {
+	clients.Lock()
+	defer clients.Unlock()
+	clients.closed = true
+	for events := range clients.subs {
+		close(events)
+		delete(clients.subs, events)
+	}
+}
+
+func subscribe() (chan string, bool) {
+	clients.Lock()
+	defer clients.Unlock()
+	if clients.closed {
+		return nil, false
+	}
+	events := make(chan string, 1)
+	clients.subs[events] = struct{}{}
+	return events, true
+}
+
+func unsubscribe(events chan string) {
+	clients.Lock()
+	defer clients.Unlock()
+	if _, ok := clients.subs[events]; ok {
+		delete(clients.subs, events)
+		close(events)
+	}
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
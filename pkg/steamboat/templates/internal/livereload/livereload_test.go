@@ -0,0 +1,138 @@
+package livereload
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readEvent reads the next event name and data from an event stream
+func readEvent(t *testing.T, r *bufio.Reader) (string, string) {
+	t.Helper()
+	var event, data string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Stream ended: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "" && event != "":
+			return event, data
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(Handler))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %q", ct)
+	}
+
+	body := bufio.NewReader(resp.Body)
+	if event, data := readEvent(t, body); event != EventHello || data != ID {
+		t.Fatalf("Expected hello with the server ID, got %s %q", event, data)
+	}
+
+	Notify(EventCSS)
+	if event, _ := readEvent(t, body); event != EventCSS {
+		t.Errorf("Expected the css event, got %s", event)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	if Enabled(context.Background()) {
+		t.Error("Expected the script to be off without the middleware")
+	}
+
+	var enabled bool
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enabled = Enabled(r.Context())
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !enabled {
+		t.Error("Expected the middleware to enable the script")
+	}
+}
+
+func TestChanges(t *testing.T) {
+	now := time.Now()
+	before := map[string]fileState{"app.css": {now, 1}, "app.js": {now, 1}}
+
+	cases := []struct {
+		name  string
+		after map[string]fileState
+		want  string
+	}{
+		{"unchanged", map[string]fileState{"app.css": {now, 1}, "app.js": {now, 1}}, ""},
+		{"stylesheet", map[string]fileState{"app.css": {now, 2}, "app.js": {now, 1}}, EventCSS},
+		{"script", map[string]fileState{"app.css": {now, 2}, "app.js": {now, 2}}, EventReload},
+		{"removed", map[string]fileState{"app.css": {now, 1}}, EventReload},
+		{"added stylesheet", map[string]fileState{"app.css": {now, 1}, "app.js": {now, 1}, "admin.css": {now, 1}}, EventCSS},
+	}
+	for _, tc := range cases {
+		if got := changes(before, tc.after); got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.css")
+	os.WriteFile(file, []byte("a {}"), 0644)
+
+	events, _ := subscribe()
+	defer unsubscribe(events)
+
+	w := NewWatcher(dir)
+	w.interval = 10 * time.Millisecond
+	w.Start(context.Background())
+	defer w.Stop(context.Background())
+
+	os.WriteFile(file, []byte("a { color: red }"), 0644)
+	select {
+	case event := <-events:
+		if event != EventCSS {
+			t.Errorf("Expected the css event, got %s", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected an event for the changed stylesheet")
+	}
+}
+
+func TestClose(t *testing.T) {
+	events, _ := subscribe()
+	Close()
+	defer func() {
+		clients.Lock()
+		clients.closed = false
+		clients.Unlock()
+	}()
+
+	if _, ok := <-events; ok {
+		t.Error("Expected Close to end open streams")
+	}
+	w := httptest.NewRecorder()
+	Handler(w, httptest.NewRequest(http.MethodGet, Path, nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected new streams to be refused, got %d", w.Code)
+	}
+}
@@ -0,0 +1,106 @@
+package livereload
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+// Watcher polls a directory of assets and notifies the browsers when a file
+// changes: EventCSS if only stylesheets changed, EventReload otherwise. Go
+// and template changes need no watcher, the restart reloads the page.
+type Watcher struct {
+	fsys     fs.FS
+	interval time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewWatcher returns a watcher of dir on disk
+func NewWatcher(dir string) *Watcher {
+	return &Watcher{fsys: os.DirFS(dir), interval: 300 * time.Millisecond}
+}
+
+// Start begins polling in the background
+func (w *Watcher) Start(context.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	files := snapshot(w.fsys)
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			current := snapshot(w.fsys)
+			if event := changes(files, current); event != "" {
+				Notify(event)
+			}
+			files = current
+		}
+	}()
+	return nil
+}
+
+// Stop ends polling
+func (w *Watcher) Stop(context.Context) error {
+	if w.cancel != nil {
+		w.cancel()
+		w.wg.Wait()
+	}
+	return nil
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func snapshot(fsys fs.FS) map[string]fileState {
+	files := make(map[string]fileState)
+	fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			files[name] = fileState{modTime: info.ModTime(), size: info.Size()}
+		}
+		return nil
+	})
+	return files
+}
+
+// changes returns the event for the difference between two snapshots, or ""
+func changes(before, after map[string]fileState) string {
+	event := ""
+	check := func(name string) {
+		if path.Ext(name) == ".css" {
+			if event == "" {
+				event = EventCSS
+			}
+		} else {
+			event = EventReload
+		}
+	}
+	for name, state := range after {
+		if old, ok := before[name]; !ok || old != state {
+			check(name)
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			check(name)
+		}
+	}
+	return event
+}
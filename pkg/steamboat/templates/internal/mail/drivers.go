@@ -0,0 +1,141 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Log writes messages to the application log instead of sending them
+type Log struct {
+	from string
+}
+
+// NewLog returns a driver that logs messages sent from the address from
+func NewLog(from string) *Log {
+	return &Log{from: from}
+}
+
+func (l *Log) Send(ctx context.Context, msg *Message) error {
+	if err := msg.prepare(l.from); err != nil {
+		return err
+	}
+
+	logger().InfoContext(ctx, "Mail sent",
+		"id", msg.ID,
+		"from", msg.From,
+		"to", strings.Join(msg.To, ", "),
+		"subject", msg.Subject,
+		"attachments", len(msg.Attachments),
+		"text", msg.Text,
+	)
+	return nil
+}
+
+// Memory keeps messages in memory, for tests and /_dev/mail
+type Memory struct {
+	from     string
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemory returns a driver that keeps messages sent from the address from
+func NewMemory(from string) *Memory {
+	return &Memory{from: from}
+}
+
+func (m *Memory) Send(ctx context.Context, msg *Message) error {
+	if err := msg.prepare(m.from); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, *msg)
+	return nil
+}
+
+func (m *Memory) Messages() ([]Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	messages := make([]Message, len(m.messages))
+	for i, msg := range m.messages {
+		messages[len(messages)-1-i] = msg
+	}
+	return messages, nil
+}
+
+// Reset forgets every message
+func (m *Memory) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = nil
+}
+
+// File writes every message to a directory as <id>.eml, to open in a mail
+// client, and as <id>.json, which /_dev/mail reads. Unlike Memory it keeps
+// messages sent by other processes, e.g. `steamboat schedule:run`.
+type File struct {
+	dir  string
+	from string
+}
+
+// NewFile returns a driver that writes messages sent from the address from to dir
+func NewFile(dir, from string) *File {
+	return &File{dir: dir, from: from}
+}
+
+func (f *File) Send(ctx context.Context, msg *Message) error {
+	if err := msg.prepare(f.from); err != nil {
+		return err
+	}
+
+	eml, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	if err := os.MkdirAll(f.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(f.dir, msg.ID+".eml"), eml, 0644); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(f.dir, msg.ID+".json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+func (f *File) Messages() ([]Message, error) {
+	paths, err := filepath.Glob(filepath.Join(f.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	// IDs start with the time they were sent
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+
+	messages := make([]Message, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message: %w", err)
+		}
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
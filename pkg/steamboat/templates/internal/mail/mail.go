@@ -0,0 +1,175 @@
+// Package mail sends email. Messages are rendered from templ components, get
+// a plain-text alternative derived from the HTML and may carry attachments:
+//
+//	msg := &mail.Message{To: []string{user.Email}, Subject: "Welcome"}
+//	if err := msg.Render(ctx, emails.Welcome(user.Name)); err != nil {
+//		return err
+//	}
+//	msg.Attach("terms.pdf", terms)
+//	err := h.mailer.Send(ctx, msg)
+//
+// The driver is picked by MAIL_DRIVER. With MAIL_QUEUE the mailer hands the
+// message to the job queue and a worker delivers it, retrying failures.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/mail"
+	"path/filepath"
+	"time"
+
+	"github.com/a-h/templ"
+
+	"<<!.ProjectName!>>/internal/config"
+	"<<!.ProjectName!>>/internal/utils"
+)
+
+// Mailer sends messages
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Inbox is implemented by the drivers that keep what they send, which
+// /_dev/mail lists
+type Inbox interface {
+	// Messages returns the sent messages, newest first
+	Messages() ([]Message, error)
+}
+
+// Message is an email. Addresses are either plain (ada@example.com) or with
+// a name (Ada Lovelace <ada@example.com>).
+type Message struct {
+	// ID and Date are set when the message is sent
+	ID   string    `json:"id"`
+	Date time.Time `json:"date"`
+	// From defaults to MAIL_FROM
+	From    string   `json:"from"`
+	To      []string `json:"to"`
+	Cc      []string `json:"cc,omitempty"`
+	Bcc     []string `json:"bcc,omitempty"`
+	ReplyTo string   `json:"reply_to,omitempty"`
+	Subject string   `json:"subject"`
+	HTML    string   `json:"html,omitempty"`
+	// Text is the plain-text alternative. Render derives it from the HTML
+	// unless it is already set.
+	Text        string       `json:"text,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// Attachment is a file sent with a message
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+}
+
+// Render renders the HTML body from component
+func (m *Message) Render(ctx context.Context, component templ.Component) error {
+	var buf bytes.Buffer
+	if err := component.Render(ctx, &buf); err != nil {
+		return fmt.Errorf("failed to render email: %w", err)
+	}
+
+	m.HTML = buf.String()
+	if m.Text == "" {
+		m.Text = HTMLToText(m.HTML)
+	}
+	return nil
+}
+
+// Attach adds a file. The content type is guessed from the file extension,
+// or from the data when the extension is unknown.
+func (m *Message) Attach(filename string, data []byte) {
+	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	m.Attachments = append(m.Attachments, Attachment{Filename: filename, ContentType: contentType, Data: data})
+}
+
+// Recipients returns the envelope addresses of To, Cc and Bcc
+func (m *Message) Recipients() ([]string, error) {
+	var recipients []string
+	for _, list := range [][]string{m.To, m.Cc, m.Bcc} {
+		for _, raw := range list {
+			addr, err := mail.ParseAddress(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid recipient %q: %w", raw, err)
+			}
+			recipients = append(recipients, addr.Address)
+		}
+	}
+	return recipients, nil
+}
+
+// CheckRecipients reports a message without recipients or with an invalid address
+func (m *Message) CheckRecipients() error {
+	if len(m.To)+len(m.Cc)+len(m.Bcc) == 0 {
+		return errors.New("mail: message has no recipients")
+	}
+	_, err := m.Recipients()
+	return err
+}
+
+// prepare fills in the fields set on sending and checks the addresses
+func (m *Message) prepare(from string) error {
+	if m.From == "" {
+		m.From = from
+	}
+	if _, err := mail.ParseAddress(m.From); err != nil {
+		return fmt.Errorf("invalid sender %q: %w", m.From, err)
+	}
+	if err := m.CheckRecipients(); err != nil {
+		return err
+	}
+
+	if m.ID == "" {
+		suffix := make([]byte, 4)
+		rand.Read(suffix)
+		m.ID = time.Now().UTC().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+	}
+	if m.Date.IsZero() {
+		m.Date = time.Now()
+	}
+	return nil
+}
+
+// New returns the driver configured by cfg. The server wraps it in
+// jobs.NewMailer to send through the job queue when cfg.Queue is set.
+func New(cfg config.Mail) Mailer {
+	switch cfg.Driver {
+	case "smtp":
+		return NewSMTP(cfg.SMTP, cfg.From)
+	case "file":
+		return NewFile(cfg.Dir, cfg.From)
+	case "memory":
+		return NewMemory(cfg.From)
+	default:
+		return NewLog(cfg.From)
+	}
+}
+
+// Captured returns the inbox behind m, or nil when its driver does not keep
+// messages. Mailers wrapping a driver, such as jobs.Mailer, expose it with Driver.
+func Captured(m Mailer) Inbox {
+	if wrapper, ok := m.(interface{ Driver() Mailer }); ok {
+		m = wrapper.Driver()
+	}
+	inbox, _ := m.(Inbox)
+	return inbox
+}
+
+func logger() *slog.Logger {
+	if utils.Logger != nil {
+		return utils.Logger
+	}
+	return slog.Default()
+}
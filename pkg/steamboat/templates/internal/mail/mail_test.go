@@ -0,0 +1,292 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"<<!.ProjectName!>>/internal/config"
+)
+
+const testFrom = "App <noreply@example.com>"
+
+func testMessage() *Message {
+	return &Message{
+		To:      []string{"Ada Lovelace <ada@example.com>"},
+		Bcc:     []string{"audit@example.com"},
+		Subject: "Héllo",
+		HTML:    "<p>Hi <b>Ada</b></p>",
+		Text:    "Hi Ada",
+	}
+}
+
+func TestBytes(t *testing.T) {
+	msg := testMessage()
+	msg.Attach("notes.txt", []byte("some notes"))
+	if err := msg.prepare(testFrom); err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+
+	if got := parsed.Header.Get("To"); got != `"Ada Lovelace" <ada@example.com>` {
+		t.Errorf("Expected To header, got %q", got)
+	}
+	if got := parsed.Header.Get("Bcc"); got != "" {
+		t.Errorf("Expected no Bcc header, got %q", got)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if subject != "Héllo" {
+		t.Errorf("Expected subject Héllo, got %q", subject)
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if mediaType != "multipart/mixed" {
+		t.Fatalf("Expected multipart/mixed, got %s", mediaType)
+	}
+	mixed := multipart.NewReader(parsed.Body, params["boundary"])
+
+	body, err := mixed.NextPart()
+	if err != nil {
+		t.Fatalf("Failed to read body part: %v", err)
+	}
+	mediaType, params, _ = mime.ParseMediaType(body.Header.Get("Content-Type"))
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("Expected multipart/alternative, got %s", mediaType)
+	}
+	alternative := multipart.NewReader(body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", "Hi Ada"},
+		{"text/html; charset=utf-8", "<p>Hi <b>Ada</b></p>"},
+	} {
+		part, err := alternative.NextPart()
+		if err != nil {
+			t.Fatalf("Failed to read %s part: %v", want.contentType, err)
+		}
+		content, _ := io.ReadAll(part)
+		if part.Header.Get("Content-Type") != want.contentType || string(content) != want.body {
+			t.Errorf("Expected %s part %q, got %s %q", want.contentType, want.body, part.Header.Get("Content-Type"), content)
+		}
+	}
+
+	attachment, err := mixed.NextPart()
+	if err != nil {
+		t.Fatalf("Failed to read attachment: %v", err)
+	}
+	if attachment.FileName() != "notes.txt" {
+		t.Errorf("Expected attachment notes.txt, got %q", attachment.FileName())
+	}
+}
+
+func TestBytesSingleBody(t *testing.T) {
+	msg := &Message{To: []string{"ada@example.com"}, Subject: "Plain", Text: "Just text"}
+	if err := msg.prepare(testFrom); err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+	if got := parsed.Header.Get("Content-Type"); got != "text/plain; charset=utf-8" {
+		t.Errorf("Expected text/plain, got %q", got)
+	}
+	if got := parsed.Header.Get("Content-Transfer-Encoding"); got != "quoted-printable" {
+		t.Errorf("Expected quoted-printable, got %q", got)
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	msg := &Message{Subject: "Nobody"}
+	if err := msg.prepare(testFrom); err == nil {
+		t.Error("Expected an error for a message without recipients")
+	}
+
+	msg = &Message{To: []string{"not an address"}}
+	if err := msg.prepare(testFrom); err == nil {
+		t.Error("Expected an error for an invalid recipient")
+	}
+
+	msg = &Message{To: []string{"ada@example.com"}}
+	if err := msg.prepare(testFrom); err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	if msg.From != testFrom || msg.ID == "" || msg.Date.IsZero() {
+		t.Errorf("Expected From, ID and Date to be set, got %+v", msg)
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	html := `<html><head><title>Ignored</title><style>p { color: red }</style></head>
+<body>
+	<h1>Welcome,   Ada</h1>
+	<p>Thanks for signing up.<br>Confirm your
+	address <a href="https://example.com/confirm?a=1&amp;b=2">here</a>.</p>
+	<ul><li>One</li><li>Two &amp; three</li></ul>
+	<p><a href="https://example.com">https://example.com</a></p>
+</body></html>`
+
+	want := "Welcome, Ada\n\n" +
+		"Thanks for signing up.\nConfirm your address here (https://example.com/confirm?a=1&b=2).\n\n" +
+		"- One\n- Two & three\n\n" +
+		"https://example.com"
+	if got := HTMLToText(html); got != want {
+		t.Errorf("Unexpected text:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory(testFrom)
+	for _, subject := range []string{"first", "second"} {
+		if err := m.Send(context.Background(), &Message{To: []string{"ada@example.com"}, Subject: subject}); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+
+	messages, _ := Captured(m).Messages()
+	if len(messages) != 2 || messages[0].Subject != "second" {
+		t.Fatalf("Expected 2 messages newest first, got %+v", messages)
+	}
+
+	m.Reset()
+	if messages, _ := m.Messages(); len(messages) != 0 {
+		t.Errorf("Expected no messages after Reset, got %d", len(messages))
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	f := NewFile(dir, testFrom)
+
+	first := testMessage()
+	first.ID = "20240101-000000-aaaaaaaa"
+	second := testMessage()
+	second.ID = "20240101-000001-bbbbbbbb"
+	for _, msg := range []*Message{first, second} {
+		if err := f.Send(context.Background(), msg); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, first.ID+".eml")); err != nil {
+		t.Errorf("Expected an .eml file: %v", err)
+	}
+
+	// A fresh driver sees what another process wrote
+	messages, err := NewFile(dir, testFrom).Messages()
+	if err != nil {
+		t.Fatalf("Messages failed: %v", err)
+	}
+	if len(messages) != 2 || messages[0].ID != second.ID || messages[1].HTML != first.HTML {
+		t.Errorf("Expected both messages newest first, got %+v", messages)
+	}
+}
+
+func TestNew(t *testing.T) {
+	cfg := config.Mail{Driver: "memory", From: testFrom, Queue: true}
+	if _, ok := New(cfg).(*Memory); !ok {
+		t.Error("Expected the memory driver")
+	}
+
+	cfg.Driver = "log"
+	if Captured(New(cfg)) != nil {
+		t.Error("Expected the log driver not to capture messages")
+	}
+}
+
+func TestSMTP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	received := make(chan []string, 1)
+	go serveSMTP(listener, received)
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	driver := NewSMTP(config.SMTP{Host: "127.0.0.1", Port: port, Encryption: "none"}, testFrom)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := driver.Send(ctx, testMessage()); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	commands := <-received
+	for _, want := range []string{"MAIL FROM:<noreply@example.com>", "RCPT TO:<ada@example.com>", "RCPT TO:<audit@example.com>", "DATA"} {
+		found := false
+		for _, command := range commands {
+			if strings.HasPrefix(command, want) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected command %q, got %v", want, commands)
+		}
+	}
+}
+
+// serveSMTP accepts one connection and answers just enough SMTP to take a
+// message, reporting the commands it received
+func serveSMTP(listener net.Listener, received chan<- []string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var commands []string
+	r := bufio.NewReader(conn)
+	reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			break
+		}
+		line = strings.TrimRight(line, "\r\n")
+		commands = append(commands, line)
+
+		switch {
+		case strings.HasPrefix(line, "EHLO"), strings.HasPrefix(line, "HELO"):
+			reply("250 localhost")
+		case line == "DATA":
+			reply("354 go ahead")
+			for {
+				data, err := r.ReadString('\n')
+				if err != nil || data == ".\r\n" {
+					break
+				}
+			}
+			reply("250 queued")
+		case line == "QUIT":
+			reply("221 bye")
+			received <- commands
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+	received <- commands
+}
@@ -0,0 +1,190 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+)
+
+// Bytes returns the message in RFC 5322 format: a multipart/alternative body
+// with the text and HTML versions, wrapped in multipart/mixed when there are
+// attachments. Bcc recipients are left out of the headers.
+func (m *Message) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", m.From, err)
+	}
+	writeHeader(&buf, "From", from.String())
+	for _, field := range []struct {
+		name      string
+		addresses []string
+	}{{"To", m.To}, {"Cc", m.Cc}, {"Reply-To", []string{m.ReplyTo}}} {
+		var list []string
+		for _, raw := range field.addresses {
+			if raw == "" {
+				continue
+			}
+			addr, err := mail.ParseAddress(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s address %q: %w", field.name, raw, err)
+			}
+			list = append(list, addr.String())
+		}
+		if len(list) > 0 {
+			writeHeader(&buf, field.name, strings.Join(list, ", "))
+		}
+	}
+
+	domain := from.Address[strings.LastIndex(from.Address, "@")+1:]
+	writeHeader(&buf, "Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	writeHeader(&buf, "Date", m.Date.Format("Mon, 02 Jan 2006 15:04:05 -0700"))
+	writeHeader(&buf, "Message-ID", fmt.Sprintf("<%s@%s>", m.ID, domain))
+	writeHeader(&buf, "MIME-Version", "1.0")
+
+	var body bytes.Buffer
+	header, err := m.writeAlternative(&body)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(m.Attachments) == 0 {
+		for _, name := range []string{"Content-Type", "Content-Transfer-Encoding"} {
+			if value := header.Get(name); value != "" {
+				writeHeader(&buf, name, value)
+			}
+		}
+		buf.WriteString("\r\n")
+		buf.Write(body.Bytes())
+		return buf.Bytes(), nil
+	}
+
+	mixed := multipart.NewWriter(&buf)
+	writeHeader(&buf, "Content-Type", "multipart/mixed; boundary="+mixed.Boundary())
+	buf.WriteString("\r\n")
+
+	part, err := mixed.CreatePart(header)
+	if err != nil {
+		return nil, err
+	}
+	part.Write(body.Bytes())
+
+	for _, a := range m.Attachments {
+		part, err := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(a.ContentType, map[string]string{"name": a.Filename})},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		writeBase64(part, a.Data)
+	}
+	if err := mixed.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeAlternative writes the text and HTML bodies and returns the headers
+// of the entity they form
+func (m *Message) writeAlternative(w io.Writer) (textproto.MIMEHeader, error) {
+	parts := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	}
+	if m.HTML == "" {
+		parts = parts[:1]
+	} else if m.Text == "" {
+		parts = parts[1:]
+	}
+
+	if len(parts) == 1 {
+		// A single body is written without a multipart wrapper
+		qp := quotedprintable.NewWriter(w)
+		qp.Write([]byte(parts[0].body))
+		return textproto.MIMEHeader{
+			"Content-Type":              {parts[0].contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		}, qp.Close()
+	}
+
+	alternative := multipart.NewWriter(w)
+	for _, p := range parts {
+		part, err := alternative.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(part)
+		qp.Write([]byte(p.body))
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	header := textproto.MIMEHeader{"Content-Type": {"multipart/alternative; boundary=" + alternative.Boundary()}}
+	return header, alternative.Close()
+}
+
+func writeHeader(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name + ": " + value + "\r\n")
+}
+
+// writeBase64 writes data base64-encoded in lines of 76 characters
+func writeBase64(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		io.WriteString(w, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	io.WriteString(w, encoded+"\r\n")
+}
+
+var (
+	hiddenPattern    = regexp.MustCompile(`(?is)<(head|style|script)\b.*?</(head|style|script)>`)
+	linkPattern      = regexp.MustCompile(`(?is)<a\b[^>]*\bhref="([^"]*)"[^>]*>(.*?)</a>`)
+	whitespace       = regexp.MustCompile(`\s+`)
+	paragraphPattern = regexp.MustCompile(`(?i)</(p|div|h[1-6]|table|ul|ol|blockquote)>`)
+	linePattern      = regexp.MustCompile(`(?i)<br\s*/?>|</(tr|li)>`)
+	itemPattern      = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+	tagPattern       = regexp.MustCompile(`<[^>]*>`)
+	blankLines       = regexp.MustCompile(`\n{3,}`)
+)
+
+// HTMLToText turns an HTML email into readable plain text: paragraphs and
+// line breaks are kept, list items get a dash and links show their URL
+func HTMLToText(s string) string {
+	s = hiddenPattern.ReplaceAllString(s, "")
+	s = linkPattern.ReplaceAllStringFunc(s, func(link string) string {
+		match := linkPattern.FindStringSubmatch(link)
+		href, text := html.UnescapeString(match[1]), strings.TrimSpace(tagPattern.ReplaceAllString(match[2], ""))
+		if text == "" || html.UnescapeString(text) == href {
+			return href
+		}
+		return text + " (" + href + ")"
+	})
+	s = whitespace.ReplaceAllString(s, " ")
+	s = paragraphPattern.ReplaceAllString(s, "\n\n")
+	s = linePattern.ReplaceAllString(s, "\n")
+	s = itemPattern.ReplaceAllString(s, "- ")
+	s = html.UnescapeString(tagPattern.ReplaceAllString(s, ""))
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
@@ -0,0 +1,67 @@
+// Package metrics holds the Prometheus collectors of the application and
+// serves them in the Prometheus text format.
+//
+// Collectors are registered on Registry rather than the global default
+// registry, so tests can create servers repeatedly without duplicate
+// registration panics.
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every application metric
+var Registry = prometheus.NewRegistry()
+
+var (
+	// RequestsTotal counts finished requests by method, route pattern and status
+	RequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Total number of HTTP requests.",
+	}, []string{"method", "route", "status"})
+
+	// RequestDuration observes request latency by method, route pattern and status
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Duration of HTTP requests in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// RequestsInFlight is the number of requests being served
+	RequestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "Number of HTTP requests currently being served.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		RequestsTotal,
+		RequestDuration,
+		RequestsInFlight,
+	)
+}
+
+// RegisterDatabase exports the pool statistics of db as go_sql_* metrics
+// labeled with db_name. Registering the same name twice is a no-op.
+func RegisterDatabase(name string, db *sql.DB) error {
+	err := Registry.Register(collectors.NewDBStatsCollector(db, name))
+	var registered prometheus.AlreadyRegisteredError
+	if errors.As(err, &registered) {
+		return nil
+	}
+	return err
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
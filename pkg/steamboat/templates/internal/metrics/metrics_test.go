@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestHandler(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := RegisterDatabase("primary", db.DB); err != nil {
+		t.Fatalf("RegisterDatabase failed: %v", err)
+	}
+	if err := RegisterDatabase("primary", db.DB); err != nil {
+		t.Errorf("registering a database twice should be a no-op, got %v", err)
+	}
+
+	RequestsTotal.WithLabelValues("GET", "/", "200").Inc()
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	body := w.Body.String()
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/",status="200"}`,
+		`go_sql_open_connections{db_name="primary"}`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics to contain %s", want)
+		}
+	}
+}
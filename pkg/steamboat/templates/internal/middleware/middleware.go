@@ -1,29 +1,116 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
+	"runtime/debug"
+	"strconv"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 
+	"<<!.ProjectName!>>/internal/apperr"
+	"<<!.ProjectName!>>/internal/config"
+	"<<!.ProjectName!>>/internal/livereload"
+	"<<!.ProjectName!>>/internal/metrics"
 	"<<!.ProjectName!>>/internal/middleware/session"
+	"<<!.ProjectName!>>/internal/tracing"
 )
 
 func Logger() func(http.Handler) http.Handler {
 	return middleware.Logger
 }
 
-func CORS() func(http.Handler) http.Handler {
+func CORS(cfg config.CORS) func(http.Handler) http.Handler {
 	return cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"https://*", "http://*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
-		AllowCredentials: true,
-		MaxAge:           300,
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedMethods:   cfg.AllowedMethods,
+		AllowedHeaders:   cfg.AllowedHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           cfg.MaxAge,
 	})
 }
 
+// Metrics records request counts, durations and in-flight requests. Requests
+// are labeled by chi route pattern, e.g. /users/{id}, so paths with IDs do not
+// create a series each; requests that match no route are labeled "unmatched".
+func Metrics() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			metrics.RequestsInFlight.Inc()
+			defer metrics.RequestsInFlight.Dec()
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			route := routePattern(r)
+			if route == "" {
+				route = "unmatched"
+			}
+
+			labels := []string{r.Method, route, strconv.Itoa(status(ww))}
+			metrics.RequestsTotal.WithLabelValues(labels...).Inc()
+			metrics.RequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// Tracing starts a server span per request, continuing the trace of an
+// incoming traceparent header. Once the request has been routed the span is
+// named after the chi route pattern, e.g. "GET /users/{id}".
+func Tracing() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := tracing.Propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracing.Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("url.path", r.URL.Path),
+					attribute.String("user_agent.original", r.UserAgent()),
+				),
+			)
+			defer span.End()
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			if route := routePattern(r); route != "" {
+				span.SetName(r.Method + " " + route)
+				span.SetAttributes(attribute.String("http.route", route))
+			}
+			code := status(ww)
+			span.SetAttributes(attribute.Int("http.response.status_code", code))
+			if code >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(code))
+			}
+		})
+	}
+}
+
+// routePattern returns the chi route pattern r matched, or "" if none
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return rctx.RoutePattern()
+	}
+	return ""
+}
+
+// status returns the response status, which is 200 if none was written
+func status(ww middleware.WrapResponseWriter) int {
+	if ww.Status() == 0 {
+		return http.StatusOK
+	}
+	return ww.Status()
+}
+
 func Compress() func(http.Handler) http.Handler {
 	return middleware.Compress(5)
 }
@@ -32,8 +119,33 @@ func RequestID() func(http.Handler) http.Handler {
 	return middleware.RequestID
 }
 
+// Recoverer turns a panic in a handler into a 500 that is logged with the
+// stack and rendered like any other error. http.ErrAbortHandler is passed on
+// so the response is aborted.
 func Recoverer() func(http.Handler) http.Handler {
-	return middleware.Recoverer
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			defer func() {
+				rvr := recover()
+				if rvr == nil {
+					return
+				}
+				if rvr == http.ErrAbortHandler {
+					panic(rvr)
+				}
+
+				err := apperr.Internal(fmt.Errorf("panic: %v\n%s", rvr, debug.Stack()))
+				if ww.Status() != 0 || r.Header.Get("Connection") == "Upgrade" {
+					apperr.Log(r, err)
+					return
+				}
+				apperr.Write(w, r, err)
+			}()
+
+			next.ServeHTTP(ww, r)
+		})
+	}
 }
 
 func RateLimiter(requestsPerMinute int) func(http.Handler) http.Handler {
@@ -44,6 +156,12 @@ func Timeout(timeout time.Duration) func(http.Handler) http.Handler {
 	return middleware.Timeout(timeout)
 }
 
+// LiveReload adds the browser reload script to pages rendered through
+// layouts.Base. It is meant for development.
+func LiveReload() func(http.Handler) http.Handler {
+	return livereload.Middleware
+}
+
 func Session(config *session.Config) func(http.Handler) http.Handler {
 	if config == nil {
 		config = session.DefaultConfig()
@@ -3,8 +3,17 @@ package middleware
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"<<!.ProjectName!>>/internal/config"
+	"<<!.ProjectName!>>/internal/metrics"
 	"<<!.ProjectName!>>/internal/middleware/session"
 )
 
@@ -16,7 +25,10 @@ func TestLogger(t *testing.T) {
 }
 
 func TestCORS(t *testing.T) {
-	handler := CORS()
+	handler := CORS(config.CORS{
+		AllowedOrigins: []string{"https://example.com"},
+		AllowedMethods: []string{"GET"},
+	})
 	if handler == nil {
 		t.Error("CORS() returned nil")
 	}
@@ -39,6 +51,65 @@ func TestCORS(t *testing.T) {
 	}
 }
 
+func TestMetrics(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Metrics())
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	matched := metrics.RequestsTotal.WithLabelValues("GET", "/users/{id}", "202")
+	unmatched := metrics.RequestsTotal.WithLabelValues("GET", "unmatched", "404")
+	matchedBefore, unmatchedBefore := testutil.ToFloat64(matched), testutil.ToFloat64(unmatched)
+
+	for _, path := range []string{"/users/1", "/users/2", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := testutil.ToFloat64(matched) - matchedBefore; got != 2 {
+		t.Errorf("expected 2 requests labeled by route pattern, got %v", got)
+	}
+	if got := testutil.ToFloat64(unmatched) - unmatchedBefore; got != 1 {
+		t.Errorf("expected 1 unmatched request, got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.RequestsInFlight); got != 0 {
+		t.Errorf("expected no requests in flight, got %v", got)
+	}
+}
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	r := chi.NewRouter()
+	r.Use(Tracing())
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "GET /users/{id}" {
+		t.Errorf("expected span named after the route pattern, got %q", span.Name)
+	}
+	if span.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected the incoming trace to be continued, got %s", span.SpanContext.TraceID())
+	}
+	if span.Status.Code.String() != "Error" {
+		t.Errorf("expected a 500 to mark the span as failed, got %v", span.Status)
+	}
+}
+
 func TestSessionMiddleware(t *testing.T) {
 	// Test with default config
 	middleware := Session(nil)
@@ -176,6 +247,29 @@ func TestRequestID(t *testing.T) {
 func TestRecoverer(t *testing.T) {
 	handler := Recoverer()
 	if handler == nil {
-		t.Error("Recoverer() returned nil")
+		t.Fatal("Recoverer() returned nil")
 	}
+
+	panicking := handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/things", nil)
+	w := httptest.NewRecorder()
+	panicking.ServeHTTP(w, req)
+	if w.Code != http.StatusInternalServerError || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Errorf("expected 500 problem details, got %d %v", w.Code, w.Header())
+	}
+	if strings.Contains(w.Body.String(), "boom") {
+		t.Error("expected the panic value to stay out of the response")
+	}
+
+	defer func() {
+		if rvr := recover(); rvr != http.ErrAbortHandler {
+			t.Errorf("expected http.ErrAbortHandler to be passed on, got %v", rvr)
+		}
+	}()
+	handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})).ServeHTTP(httptest.NewRecorder(), req)
 }
\ No newline at end of file
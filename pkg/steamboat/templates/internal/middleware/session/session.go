@@ -10,10 +10,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"time"
 
-	_ "github.com/joho/godotenv/autoload"
+	"<<!.ProjectName!>>/internal/apperr"
+	"<<!.ProjectName!>>/internal/config"
 )
 
 type contextKey string
@@ -38,15 +38,11 @@ type Config struct {
 	Path       string
 }
 
+// DefaultConfig returns the default cookie settings with a random key
 func DefaultConfig() *Config {
-	secretKey := os.Getenv("SESSION_KEY")
-	if secretKey == "" {
-		secretKey = generateRandomKey()
-	}
-
 	return &Config{
 		CookieName: "steamboat_session",
-		SecretKey:  secretKey,
+		SecretKey:  generateRandomKey(),
 		MaxAge:     86400 * 7, // 7 days
 		HttpOnly:   true,
 		Secure:     false, // Set to true in production with HTTPS
@@ -56,6 +52,31 @@ func DefaultConfig() *Config {
 	}
 }
 
+// NewConfig returns the cookie settings of the application configuration.
+// Without a key a random one is generated, so sessions do not survive a restart.
+func NewConfig(cfg config.Session) *Config {
+	c := DefaultConfig()
+	if cfg.Key != "" {
+		c.SecretKey = cfg.Key
+	}
+	if cfg.Cookie != "" {
+		c.CookieName = cfg.Cookie
+	}
+	if cfg.MaxAge > 0 {
+		c.MaxAge = int(cfg.MaxAge.Seconds())
+	}
+	c.Secure = cfg.Secure
+
+	switch cfg.SameSite {
+	case "strict":
+		c.SameSite = http.SameSiteStrictMode
+	case "none":
+		c.SameSite = http.SameSiteNoneMode
+	}
+
+	return c
+}
+
 func generateRandomKey() string {
 	key := make([]byte, 32)
 	rand.Read(key)
@@ -259,7 +280,7 @@ func RequireAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		sess := GetSession(r)
 		if !sess.IsAuthenticated() {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			apperr.Write(w, r, apperr.Unauthorized(""))
 			return
 		}
 		next(w, r)
@@ -5,6 +5,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	"<<!.ProjectName!>>/internal/config"
 )
 
 func TestNewSession(t *testing.T) {
@@ -90,6 +93,27 @@ func TestDefaultConfig(t *testing.T) {
 	}
 }
 
+func TestNewConfig(t *testing.T) {
+	c := NewConfig(config.Session{
+		Key:      "configured-key",
+		Cookie:   "app_session",
+		MaxAge:   time.Hour,
+		Secure:   true,
+		SameSite: "strict",
+	})
+
+	if c.SecretKey != "configured-key" || c.CookieName != "app_session" {
+		t.Errorf("Expected key and cookie name from config, got %q and %q", c.SecretKey, c.CookieName)
+	}
+	if c.MaxAge != 3600 || !c.Secure || c.SameSite != http.SameSiteStrictMode {
+		t.Errorf("Unexpected cookie settings: %+v", c)
+	}
+
+	if NewConfig(config.Session{}).SecretKey == "" {
+		t.Error("Expected a random key when none is configured")
+	}
+}
+
 func TestEncryptDecrypt(t *testing.T) {
 	secretKey := "test-secret-key-for-encryption"
 	testData := []byte("Hello, World! This is test data.")
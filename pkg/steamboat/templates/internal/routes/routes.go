@@ -6,25 +6,96 @@ import (
 
 	"github.com/go-chi/chi/v5"
 
+	"<<!.ProjectName!>>/internal/apperr"
+	"<<!.ProjectName!>>/internal/assets"
+	"<<!.ProjectName!>>/internal/config"
 	"<<!.ProjectName!>>/internal/handlers"
+	"<<!.ProjectName!>>/internal/livereload"
+	"<<!.ProjectName!>>/internal/metrics"
 	"<<!.ProjectName!>>/internal/middleware"
+	"<<!.ProjectName!>>/internal/middleware/session"
+	"<<!.ProjectName!>>/internal/storage"
 )
 
-func Setup(h *handlers.Handlers) http.Handler {
+func Setup(h *handlers.Handlers, cfg *config.Config) http.Handler {
 	r := chi.NewRouter()
 
+	// Tracing and metrics wrap everything else so recovered panics are
+	// recorded as 500s
+	r.Use(middleware.Tracing())
+	if cfg.Metrics.Enabled {
+		r.Use(middleware.Metrics())
+	}
 	r.Use(middleware.Recoverer())
 	r.Use(middleware.RequestID())
 	r.Use(middleware.Logger())
-	r.Use(middleware.Session(nil))
-	r.Use(middleware.RateLimiter(100))
-	r.Use(middleware.Timeout(60 * time.Second))
-	r.Use(middleware.Compress())
-	r.Use(middleware.CORS())
 
-	//User routes
-	r.Get("/", h.HomeHandler)
+	// The app routes share these. The live reload stream stays open until the
+	// app restarts, so it is kept out of the rate limit, timeout and compression.
+	app := chi.Middlewares{
+		middleware.Session(session.NewConfig(cfg.Session)),
+		middleware.RateLimiter(100),
+		middleware.Timeout(60 * time.Second),
+		middleware.Compress(),
+		middleware.CORS(cfg.CORS),
+	}
+	if !cfg.IsProduction() {
+		app = append(app, middleware.LiveReload())
+		r.Get(livereload.Path, livereload.Handler)
+	}
+
+	// Unmatched requests get the same error responses as handler errors
+	r.NotFound(app.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apperr.Write(w, r, apperr.NotFound(""))
+	}).ServeHTTP)
+	r.MethodNotAllowed(app.HandlerFunc(methodNotAllowed(r)).ServeHTTP)
+
+	r.Group(func(r chi.Router) {
+		r.Use(app...)
+
+		// Liveness and readiness probes
+		r.Get("/healthz", h.Healthz)
+		r.Get("/readyz", h.Readyz)
+		if cfg.Metrics.Enabled && cfg.Metrics.Port == 0 {
+			r.Handle(cfg.Metrics.Path, metrics.Handler())
+		}
+
+		// Static files, referenced with assets.Path
+		r.Get(assets.Prefix+"*", assets.Handler().ServeHTTP)
+		r.Head(assets.Prefix+"*", assets.Handler().ServeHTTP)
+
+		// Stored files behind signed URLs
+		r.Get(storage.RoutePrefix+"*", h.StorageFile)
+
+		// Development tools, never mounted in production
+		if !cfg.IsProduction() {
+			r.Route("/_dev/mail", func(r chi.Router) {
+				r.Get("/", handlers.Wrap(h.DevMail))
+				r.Get("/{id}", handlers.Wrap(h.DevMailMessage))
+				r.Get("/{id}/html", handlers.Wrap(h.DevMailHTML))
+			})
+		}
+
+		//User routes
+		r.Get("/", handlers.Wrap(h.HomeHandler))
+	})
 
 	return r
 }
 
+// methodNotAllowed answers with 405 and the Allow header chi's default
+// handler would have set
+func methodNotAllowed(router chi.Routes) http.HandlerFunc {
+	methods := []string{
+		http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions,
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, method := range methods {
+			if router.Match(chi.NewRouteContext(), method, r.URL.Path) {
+				w.Header().Add("Allow", method)
+			}
+		}
+		apperr.Write(w, r, apperr.New(http.StatusMethodNotAllowed, ""))
+	}
+}
@@ -1,20 +1,36 @@
 package routes
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 
+	"<<!.ProjectName!>>/internal/config"
 	"<<!.ProjectName!>>/internal/database"
 	"<<!.ProjectName!>>/internal/handlers"
+	"<<!.ProjectName!>>/internal/livereload"
+	"<<!.ProjectName!>>/internal/mail"
+	"<<!.ProjectName!>>/internal/storage"
 )
 
+func testConfig(t *testing.T) *config.Config {
+	cfg, err := config.Parse(map[string]string{"DB_URL": ":memory:"})
+	if err != nil {
+		t.Fatalf("config.Parse failed: %v", err)
+	}
+	return cfg
+}
+
 func TestSetup(t *testing.T) {
-	db := database.New()
+	cfg := testConfig(t)
+	db := database.New(cfg.Database)
 	defer db.Close()
 	
-	h := handlers.New(db)
-	router := Setup(h)
+	h := handlers.New(db, mail.NewMemory(""), storage.New(config.Storage{Driver: "memory"}))
+	router := Setup(h, cfg)
 
 	testCases := []struct {
 		method string
@@ -22,6 +38,11 @@ func TestSetup(t *testing.T) {
 		status int
 	}{
 		{"GET", "/", http.StatusOK},
+		{"GET", "/healthz", http.StatusOK},
+		{"GET", "/readyz", http.StatusOK},
+		{"GET", "/metrics", http.StatusOK},
+		{"GET", "/_dev/mail", http.StatusOK},
+		{"GET", "/_storage/unsigned.txt", http.StatusForbidden},
 		{"POST", "/nonexistent", http.StatusNotFound},
 		{"GET", "/nonexistent", http.StatusNotFound},
 	}
@@ -40,12 +61,128 @@ func TestSetup(t *testing.T) {
 	}
 }
 
+func TestDevRoutesOffInProduction(t *testing.T) {
+	cfg, err := config.Parse(map[string]string{"DB_URL": ":memory:", "APP_ENV": "production", "SESSION_KEY": "0123456789abcdef0123456789abcdef"})
+	if err != nil {
+		t.Fatalf("config.Parse failed: %v", err)
+	}
+	db := database.New(cfg.Database)
+	defer db.Close()
+
+	router := Setup(handlers.New(db, mail.NewMemory(""), storage.New(config.Storage{Driver: "memory"})), cfg)
+
+	for _, path := range []string{"/_dev/mail", livereload.Path} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status %d, got %d", path, http.StatusNotFound, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if strings.Contains(w.Body.String(), livereload.Path) {
+		t.Error("expected no live reload script in production")
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	cfg := testConfig(t)
+	db := database.New(cfg.Database)
+	defer db.Close()
+
+	router := Setup(handlers.New(db, mail.NewMemory(""), storage.New(config.Storage{Driver: "memory"})), cfg)
+
+	req := httptest.NewRequest(http.MethodGet, "/nonexistent", nil)
+	req.Header.Set("Accept", "text/html")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "404 Not Found") {
+		t.Errorf("expected the 404 page, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/nonexistent", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Errorf("expected 404 problem details, got %d %v", w.Code, w.Header())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/healthz", nil)
+	req.Header.Set("Accept", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Errorf("expected 405 problem details, got %d %v", w.Code, w.Header())
+	}
+	if allow := w.Header().Values("Allow"); len(allow) != 1 || allow[0] != http.MethodGet {
+		t.Errorf("expected Allow: GET, got %v", allow)
+	}
+}
+
+func TestLiveReloadScript(t *testing.T) {
+	cfg := testConfig(t)
+	db := database.New(cfg.Database)
+	defer db.Close()
+
+	router := Setup(handlers.New(db, mail.NewMemory(""), storage.New(config.Storage{Driver: "memory"})), cfg)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	body := w.Body.String()
+	if !strings.Contains(body, `data-livereload="`+livereload.Path+`"`) || !strings.Contains(body, "EventSource") {
+		t.Errorf("expected the live reload script in the page, got %s", body)
+	}
+}
+
+// streamRecorder reports when an event stream has sent its greeting
+type streamRecorder struct {
+	*httptest.ResponseRecorder
+	once    sync.Once
+	flushed chan struct{}
+}
+
+func (r *streamRecorder) Flush() {
+	r.ResponseRecorder.Flush()
+	r.once.Do(func() { close(r.flushed) })
+}
+
+func TestLiveReloadStreamsSkipRequestLimits(t *testing.T) {
+	cfg := testConfig(t)
+	db := database.New(cfg.Database)
+	defer db.Close()
+
+	router := Setup(handlers.New(db, mail.NewMemory(""), storage.New(config.Storage{Driver: "memory"})), cfg)
+
+	// Every open tab holds a stream, which must not use up the rate limit
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+	for i := 0; i < 100; i++ {
+		w := &streamRecorder{ResponseRecorder: httptest.NewRecorder(), flushed: make(chan struct{})}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, livereload.Path, nil).WithContext(ctx))
+		}()
+		<-w.flushed
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected pages to be served next to open streams, got %d", w.Code)
+	}
+}
+
 func TestCORSHeaders(t *testing.T) {
-	db := database.New()
+	cfg := testConfig(t)
+	db := database.New(cfg.Database)
 	defer db.Close()
 	
-	h := handlers.New(db)
-	router := Setup(h)
+	h := handlers.New(db, mail.NewMemory(""), storage.New(config.Storage{Driver: "memory"}))
+	router := Setup(h, cfg)
 
 	req := httptest.NewRequest(http.MethodOptions, "/", nil)
 	req.Header.Set("Origin", "https://example.com")
@@ -0,0 +1,405 @@
+// Package schedule runs periodic tasks on cron expressions or fixed
+// intervals. The server starts the scheduler; every run takes a lease in the
+// scheduled_tasks table first, so when several instances share the database
+// each occurrence runs once. The table also keeps the last run and its outcome.
+//
+// Tasks register themselves from init() in this package, see tasks.go:
+//
+//	Register(Task{
+//		Name: "send_digest",
+//		Cron: "0 8 * * MON-FRI",
+//		Run:  sendDigest,
+//	})
+package schedule
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"<<!.ProjectName!>>/internal/database"
+	"<<!.ProjectName!>>/internal/tracing"
+	"<<!.ProjectName!>>/internal/utils"
+)
+
+// DefaultTimeout bounds a task run without a Timeout
+const DefaultTimeout = 10 * time.Minute
+
+// Task is a periodic piece of work. Set exactly one of Cron and Every.
+type Task struct {
+	Name string
+	// Cron is a five-field expression like "*/15 * * * *" or a macro like
+	// @daily, evaluated in the server's local time zone
+	Cron string
+	// Every runs the task at a fixed interval aligned to the clock, e.g.
+	// 15m runs at :00, :15, :30 and :45 on every instance
+	Every time.Duration
+	// Timeout bounds a run and is how long its lease lasts (default: DefaultTimeout)
+	Timeout time.Duration
+	Run     func(ctx context.Context, db database.Service) error
+}
+
+// schedule returns the human-readable schedule of t
+func (t Task) schedule() string {
+	if t.Cron != "" {
+		return t.Cron
+	}
+	return "every " + t.Every.String()
+}
+
+func (t Task) timeout() time.Duration {
+	if t.Timeout > 0 {
+		return t.Timeout
+	}
+	return DefaultTimeout
+}
+
+type entry struct {
+	task Task
+	// next returns the first run after a time
+	next func(time.Time) time.Time
+}
+
+var registry = make(map[string]entry)
+
+// Register adds a task. It panics on duplicate names and invalid schedules.
+func Register(t Task) {
+	if t.Name == "" || t.Run == nil {
+		panic("schedule: a task needs a name and a Run function")
+	}
+	if _, exists := registry[t.Name]; exists {
+		panic(fmt.Sprintf("schedule: duplicate task %q", t.Name))
+	}
+
+	var next func(time.Time) time.Time
+	switch {
+	case t.Cron != "" && t.Every != 0:
+		panic(fmt.Sprintf("schedule: task %q sets both Cron and Every", t.Name))
+	case t.Cron != "":
+		cron, err := ParseCron(t.Cron)
+		if err != nil {
+			panic(fmt.Sprintf("schedule: task %q: %v", t.Name, err))
+		}
+		next = cron.Next
+	case t.Every > 0:
+		next = func(after time.Time) time.Time {
+			return after.Truncate(t.Every).Add(t.Every)
+		}
+	default:
+		panic(fmt.Sprintf("schedule: task %q needs a Cron expression or a positive Every", t.Name))
+	}
+
+	registry[t.Name] = entry{task: t, next: next}
+}
+
+// Names returns the registered task names in alphabetical order
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// State is a registered task with its next run and the outcome of its last one
+type State struct {
+	Name     string
+	Schedule string
+	Next     time.Time
+	// LastRun is zero when the task never ran
+	LastRun      time.Time
+	LastDuration time.Duration
+	// LastStatus is "ok" or "failed"
+	LastStatus string
+	LastError  string
+	// RunningOn is the instance holding the lease of a running task
+	RunningOn string
+}
+
+// ErrLocked is returned by Run when another run of the task holds its lease
+var ErrLocked = errors.New("schedule: the task is already running")
+
+// cancelGrace bounds the wait for cancelled tasks in Stop
+const cancelGrace = 5 * time.Second
+
+// Scheduler runs the registered tasks
+type Scheduler struct {
+	db database.Service
+	// instance identifies this process in the leases it takes
+	instance string
+	now      func() time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	stop   chan struct{}
+	once   sync.Once
+	wg     sync.WaitGroup
+}
+
+// New returns a scheduler that keeps its leases in the primary database of db
+func New(db database.Service) *Scheduler {
+	host, _ := os.Hostname()
+	return &Scheduler{
+		db:       db,
+		instance: fmt.Sprintf("%s:%d", host, os.Getpid()),
+		now:      time.Now,
+	}
+}
+
+// Start starts running the tasks on their schedules. Without the
+// scheduled_tasks table, e.g. before the first `steamboat migrate`, it logs a
+// warning and runs nothing.
+func (s *Scheduler) Start(ctx context.Context) error {
+	var tables int
+	if err := s.db.DB().GetContext(ctx, &tables, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'scheduled_tasks'`); err != nil {
+		return fmt.Errorf("failed to look up scheduled_tasks table: %w", err)
+	}
+	if tables == 0 {
+		logger().Warn("Scheduled tasks table not found, run `steamboat migrate` to start the scheduler")
+		return nil
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.stop = make(chan struct{})
+
+	s.wg.Add(1)
+	go s.loop()
+
+	logger().Info("Scheduler started", "tasks", len(registry))
+	return nil
+}
+
+// Stop stops scheduling and waits for the running tasks. When ctx is done
+// first the running tasks are cancelled and Stop waits up to cancelGrace for
+// them to record their outcome.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	if s.stop == nil {
+		return nil
+	}
+	s.once.Do(func() { close(s.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		// Let the cancelled tasks record their outcome before the database closes
+		select {
+		case <-done:
+		case <-time.After(cancelGrace):
+			logger().Warn("Cancelled tasks did not return in time", "grace", cancelGrace)
+		}
+		return fmt.Errorf("cancelled running tasks: %w", ctx.Err())
+	}
+}
+
+// loop sleeps until the next task is due and starts every due task
+func (s *Scheduler) loop() {
+	defer s.wg.Done()
+
+	next := make(map[string]time.Time, len(registry))
+	for name, e := range registry {
+		next[name] = e.next(s.now())
+	}
+
+	for {
+		var wake time.Time
+		for _, due := range next {
+			if !due.IsZero() && (wake.IsZero() || due.Before(wake)) {
+				wake = due
+			}
+		}
+		if wake.IsZero() {
+			<-s.stop
+			return
+		}
+
+		timer := time.NewTimer(time.Until(wake))
+		select {
+		case <-timer.C:
+		case <-s.stop:
+			timer.Stop()
+			return
+		}
+
+		now := s.now()
+		for name, due := range next {
+			if due.IsZero() || due.After(now) {
+				continue
+			}
+
+			e := registry[name]
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				s.run(s.ctx, e.task, due)
+			}()
+			// Runs missed while the process was busy or asleep are skipped
+			next[name] = e.next(now)
+		}
+	}
+}
+
+// Run runs the named task now, unless another run holds its lease
+func (s *Scheduler) Run(ctx context.Context, name string) error {
+	e, ok := registry[name]
+	if !ok {
+		return fmt.Errorf("schedule: unknown task %q", name)
+	}
+
+	ran, err := s.run(ctx, e.task, time.Time{})
+	if !ran && err == nil {
+		return ErrLocked
+	}
+	return err
+}
+
+// run takes the lease of task, runs it and records the outcome. due is the
+// occurrence a scheduled run is for and zero for manual runs. It reports
+// false when another run holds the lease or the occurrence already ran, and
+// returns the error of the task or of the bookkeeping, which are also logged.
+func (s *Scheduler) run(ctx context.Context, task Task, due time.Time) (bool, error) {
+	acquired, err := s.acquire(ctx, task, due)
+	if err != nil {
+		logger().Error("Failed to lease scheduled task", "task", task.Name, "error", err)
+		return false, err
+	}
+	if !acquired {
+		return false, nil
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, task.timeout())
+	defer cancel()
+
+	runCtx, span := tracing.Start(runCtx, "schedule "+task.Name, trace.WithSpanKind(trace.SpanKindInternal))
+	defer span.End()
+
+	start := s.now()
+	runErr := call(runCtx, task, s.db)
+	duration := s.now().Sub(start)
+	tracing.RecordError(span, runErr)
+
+	status, lastError := "ok", sql.NullString{}
+	if runErr != nil {
+		status, lastError = "failed", sql.NullString{String: runErr.Error(), Valid: true}
+		logger().Error("Scheduled task failed", "task", task.Name, "duration", duration, "error", runErr)
+	} else {
+		logger().Info("Scheduled task finished", "task", task.Name, "duration", duration)
+	}
+
+	// The outcome is recorded even when Stop cancelled the run
+	_, err = s.db.DB().ExecContext(context.Background(), `UPDATE scheduled_tasks
+		SET locked_by = NULL, locked_until = NULL, last_run_at = ?, last_duration_ms = ?, last_status = ?, last_error = ?, updated_at = ?
+		WHERE name = ? AND locked_by = ?`,
+		start.UTC(), duration.Milliseconds(), status, lastError, s.now().UTC(), task.Name, s.instance)
+	if err != nil {
+		logger().Error("Failed to record scheduled task outcome", "task", task.Name, "error", err)
+		return true, errors.Join(runErr, fmt.Errorf("failed to record outcome of %s: %w", task.Name, err))
+	}
+	return true, runErr
+}
+
+// acquire takes the lease of task until its timeout passes. For a scheduled
+// run it also claims the occurrence, so an instance whose clock lags behind
+// does not run it a second time.
+func (s *Scheduler) acquire(ctx context.Context, task Task, due time.Time) (bool, error) {
+	now := s.now().UTC()
+	if _, err := s.db.DB().ExecContext(ctx, `INSERT INTO scheduled_tasks (name, updated_at) VALUES (?, ?) ON CONFLICT (name) DO NOTHING`,
+		task.Name, now); err != nil {
+		return false, fmt.Errorf("failed to register task %s: %w", task.Name, err)
+	}
+
+	query := `UPDATE scheduled_tasks SET locked_by = ?, locked_until = ?, updated_at = ?`
+	args := []interface{}{s.instance, now.Add(task.timeout()), now}
+	where := ` WHERE name = ? AND (locked_until IS NULL OR locked_until < ?)`
+	whereArgs := []interface{}{task.Name, now}
+	if !due.IsZero() {
+		query += `, last_due_at = ?`
+		args = append(args, due.UTC())
+		where += ` AND (last_due_at IS NULL OR last_due_at < ?)`
+		whereArgs = append(whereArgs, due.UTC())
+	}
+
+	result, err := s.db.DB().ExecContext(ctx, query+where, append(args, whereArgs...)...)
+	if err != nil {
+		return false, fmt.Errorf("failed to lease task %s: %w", task.Name, err)
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// call runs task, turning a panic into an error
+func call(ctx context.Context, task Task, db database.Service) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return task.Run(ctx, db)
+}
+
+// List returns the state of every registered task in alphabetical order
+func (s *Scheduler) List(ctx context.Context) ([]State, error) {
+	type row struct {
+		Name         string         `db:"name"`
+		LastRunAt    sql.NullTime   `db:"last_run_at"`
+		LastDuration sql.NullInt64  `db:"last_duration_ms"`
+		LastStatus   sql.NullString `db:"last_status"`
+		LastError    sql.NullString `db:"last_error"`
+		LockedBy     sql.NullString `db:"locked_by"`
+		LockedUntil  sql.NullTime   `db:"locked_until"`
+	}
+	var rows []row
+	if err := s.db.DB().SelectContext(ctx, &rows, `SELECT name, last_run_at, last_duration_ms, last_status, last_error, locked_by, locked_until
+		FROM scheduled_tasks`); err != nil {
+		return nil, fmt.Errorf("failed to read scheduled tasks: %w", err)
+	}
+	byName := make(map[string]row, len(rows))
+	for _, r := range rows {
+		byName[r.Name] = r
+	}
+
+	now := s.now()
+	states := make([]State, 0, len(registry))
+	for _, name := range Names() {
+		e := registry[name]
+		r := byName[name]
+		state := State{
+			Name:         name,
+			Schedule:     e.task.schedule(),
+			Next:         e.next(now),
+			LastRun:      r.LastRunAt.Time,
+			LastDuration: time.Duration(r.LastDuration.Int64) * time.Millisecond,
+			LastStatus:   r.LastStatus.String,
+			LastError:    r.LastError.String,
+		}
+		if r.LockedBy.Valid && r.LockedUntil.Time.After(now) {
+			state.RunningOn = r.LockedBy.String
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+func logger() *slog.Logger {
+	if utils.Logger != nil {
+		return utils.Logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,245 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+
+	"<<!.ProjectName!>>/internal/database"
+)
+
+// testService serves the test database as the primary connection
+type testService struct {
+	database.Service
+	db *sqlx.DB
+}
+
+func (s testService) DB() *sqlx.DB { return s.db }
+
+func setupScheduleTest(t *testing.T) database.Service {
+	// Start every test from an empty registry
+	saved := registry
+	registry = make(map[string]entry)
+	t.Cleanup(func() { registry = saved })
+
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	migration, err := os.ReadFile(filepath.Join("..", "database", "migrations", "000002_create_scheduled_tasks_table.up.sql"))
+	if err != nil {
+		t.Fatalf("Failed to read scheduled tasks migration: %v", err)
+	}
+	if _, err := db.Exec(string(migration)); err != nil {
+		t.Fatalf("Failed to create scheduled_tasks table: %v", err)
+	}
+
+	return testService{db: db}
+}
+
+func newScheduler(db database.Service, instance string) *Scheduler {
+	s := New(db)
+	s.instance = instance
+	return s
+}
+
+func findState(t *testing.T, s *Scheduler, name string) State {
+	t.Helper()
+	states, err := s.List(context.Background())
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	for _, state := range states {
+		if state.Name == name {
+			return state
+		}
+	}
+	t.Fatalf("Task %s not listed", name)
+	return State{}
+}
+
+func TestRunRecordsOutcome(t *testing.T) {
+	db := setupScheduleTest(t)
+	s := New(db)
+
+	Register(Task{Name: "fails", Cron: "@daily", Run: func(ctx context.Context, db database.Service) error {
+		return errors.New("smtp unavailable")
+	}})
+	Register(Task{Name: "panics", Every: time.Hour, Run: func(ctx context.Context, db database.Service) error {
+		panic("boom")
+	}})
+	Register(Task{Name: "works", Every: time.Hour, Run: func(ctx context.Context, db database.Service) error {
+		return nil
+	}})
+
+	if state := findState(t, s, "works"); !state.LastRun.IsZero() || state.Schedule != "every 1h0m0s" || state.Next.IsZero() {
+		t.Errorf("Unexpected state before the first run: %+v", state)
+	}
+
+	if err := s.Run(context.Background(), "fails"); err == nil || err.Error() != "smtp unavailable" {
+		t.Errorf("Expected the task error, got %v", err)
+	}
+	if err := s.Run(context.Background(), "panics"); err == nil || err.Error() != "panic: boom" {
+		t.Errorf("Expected the panic as an error, got %v", err)
+	}
+	if err := s.Run(context.Background(), "works"); err != nil {
+		t.Errorf("Run failed: %v", err)
+	}
+	if err := s.Run(context.Background(), "missing"); err == nil {
+		t.Error("Expected an unknown task to fail")
+	}
+
+	if state := findState(t, s, "fails"); state.LastStatus != "failed" || state.LastError != "smtp unavailable" || state.RunningOn != "" {
+		t.Errorf("Expected the failure to be recorded, got %+v", state)
+	}
+	if state := findState(t, s, "works"); state.LastStatus != "ok" || state.LastRun.IsZero() {
+		t.Errorf("Expected the success to be recorded, got %+v", state)
+	}
+}
+
+func TestLeaseRunsOccurrenceOnce(t *testing.T) {
+	db := setupScheduleTest(t)
+	first, second := newScheduler(db, "web-1"), newScheduler(db, "web-2")
+
+	started, release := make(chan struct{}), make(chan struct{})
+	var runs atomic.Int32
+	Register(Task{Name: "digest", Cron: "* * * * *", Run: func(ctx context.Context, db database.Service) error {
+		if runs.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+		return nil
+	}})
+	task := registry["digest"].task
+	due := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+
+	done := make(chan bool)
+	go func() {
+		ran, _ := first.run(context.Background(), task, due)
+		done <- ran
+	}()
+	<-started
+
+	if state := findState(t, second, "digest"); state.RunningOn != "web-1" {
+		t.Errorf("Expected the lease holder to be listed, got %q", state.RunningOn)
+	}
+	if ran, err := second.run(context.Background(), task, due); ran || err != nil {
+		t.Errorf("Expected the second instance to skip a leased task, got %v, %v", ran, err)
+	}
+	if err := second.Run(context.Background(), "digest"); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked for a manual run, got %v", err)
+	}
+
+	close(release)
+	if !<-done {
+		t.Fatal("Expected the first instance to run the task")
+	}
+
+	if ran, _ := second.run(context.Background(), task, due); ran {
+		t.Error("Expected an occurrence that already ran to be skipped")
+	}
+	if ran, err := second.run(context.Background(), task, due.Add(time.Minute)); !ran || err != nil {
+		t.Errorf("Expected the next occurrence to run, got %v, %v", ran, err)
+	}
+	if runs.Load() != 2 {
+		t.Errorf("Expected 2 runs, got %d", runs.Load())
+	}
+}
+
+func TestSchedulerLifecycle(t *testing.T) {
+	db := setupScheduleTest(t)
+	s := New(db)
+
+	var runs atomic.Int32
+	Register(Task{Name: "tick", Every: 20 * time.Millisecond, Run: func(ctx context.Context, db database.Service) error {
+		runs.Add(1)
+		return nil
+	}})
+
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runs.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if runs.Load() < 2 {
+		t.Fatalf("Expected the task to run on its interval, got %d runs", runs.Load())
+	}
+
+	stopped := runs.Load()
+	time.Sleep(50 * time.Millisecond)
+	if runs.Load() != stopped {
+		t.Error("Expected no runs after Stop")
+	}
+}
+
+func TestStopWaitsForCancelledTasks(t *testing.T) {
+	db := setupScheduleTest(t)
+	s := New(db)
+
+	started := make(chan struct{})
+	var once sync.Once
+	Register(Task{Name: "slow", Every: 20 * time.Millisecond, Run: func(ctx context.Context, db database.Service) error {
+		once.Do(func() { close(started) })
+		<-ctx.Done()
+		time.Sleep(50 * time.Millisecond)
+		return ctx.Err()
+	}})
+
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.Stop(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected Stop to report the cancellation, got %v", err)
+	}
+
+	states, err := s.List(context.Background())
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if states[0].LastStatus != "failed" || states[0].RunningOn != "" {
+		t.Errorf("Expected the cancelled run to be recorded before Stop returned, got %+v", states[0])
+	}
+}
+
+func TestRegisterRejectsInvalidTasks(t *testing.T) {
+	setupScheduleTest(t)
+	run := func(ctx context.Context, db database.Service) error { return nil }
+
+	for _, task := range []Task{
+		{Name: "no_schedule", Run: run},
+		{Name: "both", Cron: "@daily", Every: time.Hour, Run: run},
+		{Name: "bad_cron", Cron: "* * *", Run: run},
+		{Name: "no_run", Cron: "@daily"},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected Register to panic for %s", task.Name)
+				}
+			}()
+			Register(task)
+		}()
+	}
+}
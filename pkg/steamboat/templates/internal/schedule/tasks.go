@@ -0,0 +1,20 @@
+package schedule
+
+import (
+	"context"
+
+	"<<!.ProjectName!>>/internal/database"
+	"<<!.ProjectName!>>/internal/jobs"
+)
+
+// Register your periodic tasks here
+func init() {
+	Register(Task{
+		Name: "purge_jobs",
+		Cron: "@daily",
+		Run: func(ctx context.Context, db database.Service) error {
+			_, err := jobs.New(db.DB()).Purge(ctx, jobs.StatusDone)
+			return err
+		},
+	})
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"<<!.ProjectName!>>/internal/utils"
+)
+
+// Hook is a callback run when the server starts or shuts down, e.g. to start
+// and stop a background worker
+type Hook struct {
+	Name string
+	// Order sorts the hooks of a phase, lowest first; equal orders run in
+	// registration order. Shutdown hooks all run before the database closes.
+	Order int
+	// Timeout bounds the hook (default: SERVER_HOOK_TIMEOUT). A hook that
+	// overruns it is abandoned and the next one runs.
+	Timeout time.Duration
+	Fn      func(ctx context.Context) error
+}
+
+// OnStart registers a hook that runs before the server starts listening. An
+// error stops the startup and Start returns it.
+func (s *Server) OnStart(hook Hook) {
+	s.startHooks = append(s.startHooks, hook)
+}
+
+// OnShutdown registers a hook that runs once the HTTP server has stopped
+// accepting requests and before the database is closed. Errors are logged
+// and the remaining hooks still run.
+func (s *Server) OnShutdown(hook Hook) {
+	s.shutdownHooks = append(s.shutdownHooks, hook)
+}
+
+// runHooks runs hooks in order. With stopOnError it returns the first error,
+// otherwise it logs every error and continues.
+func (s *Server) runHooks(phase string, hooks []Hook, stopOnError bool) error {
+	sorted := make([]Hook, len(hooks))
+	copy(sorted, hooks)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Order < sorted[j].Order })
+
+	for _, hook := range sorted {
+		err := s.runHook(hook)
+		if err == nil {
+			continue
+		}
+		if stopOnError {
+			return fmt.Errorf("%s hook %s failed: %w", phase, hook.Name, err)
+		}
+		if utils.Logger != nil {
+			utils.Logger.Error("Hook failed", "phase", phase, "hook", hook.Name, "error", err)
+		}
+	}
+	return nil
+}
+
+// runHook runs hook with its timeout, returning when the timeout passes even
+// if the hook ignores its context
+func (s *Server) runHook(hook Hook) error {
+	timeout := hook.Timeout
+	if timeout == 0 {
+		timeout = s.config.Server.HookTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				result <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		result <- hook.Fn(ctx)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+}
@@ -6,93 +6,276 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
-	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
-	_ "github.com/joho/godotenv/autoload"
-
+	"<<!.ProjectName!>>/internal/assets"
+	"<<!.ProjectName!>>/internal/config"
 	"<<!.ProjectName!>>/internal/database"
 	"<<!.ProjectName!>>/internal/handlers"
+	"<<!.ProjectName!>>/internal/jobs"
+	"<<!.ProjectName!>>/internal/livereload"
+	"<<!.ProjectName!>>/internal/mail"
+	"<<!.ProjectName!>>/internal/metrics"
 	"<<!.ProjectName!>>/internal/routes"
+	"<<!.ProjectName!>>/internal/schedule"
+	"<<!.ProjectName!>>/internal/storage"
+	"<<!.ProjectName!>>/internal/tracing"
 	"<<!.ProjectName!>>/internal/utils"
 )
 
 type Server struct {
 	port     int
+	config   *config.Config
 	db       database.Service
 	handlers *handlers.Handlers
 	server   *http.Server
+	// metrics serves /metrics when METRICS_PORT is set
+	metrics *http.Server
+	// redirect sends plain HTTP to HTTPS when TLS_REDIRECT_PORT is set
+	redirect *http.Server
+	// shutdownTracing flushes the spans that have not been exported yet
+	shutdownTracing func(context.Context) error
+
+	startHooks    []Hook
+	shutdownHooks []Hook
+	// stopping is closed by Stop, done once the shutdown has completed
+	stopping chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
 }
 
-func New() *Server {
-	return NewWithOptions(true)
+func New(cfg *config.Config) *Server {
+	return NewWithOptions(cfg, true)
 }
 
-func NewWithOptions(enableLogging bool) *Server {
+func NewWithOptions(cfg *config.Config, enableLogging bool) *Server {
 	if enableLogging {
-		utils.InitLogger()
+		utils.InitLogger(cfg.Log)
 	}
 
-	port, err := strconv.Atoi(os.Getenv("PORT"))
-	if err != nil || port == 0 {
-		port = 8080
+	shutdownTracing, err := tracing.Setup(cfg.Tracing)
+	if err != nil {
+		if utils.Logger != nil {
+			utils.Logger.Error("Failed to set up tracing, spans will not be exported", "error", err)
+		}
+		shutdownTracing = func(context.Context) error { return nil }
 	}
 
-	db := database.New()
-	h := handlers.New(db)
+	// In development assets are read from disk, so edits show without a rebuild
+	liveAssets := false
+	if info, err := os.Stat(assets.SourceDir); err == nil && info.IsDir() && !cfg.IsProduction() {
+		assets.UseDir(assets.SourceDir)
+		liveAssets = true
+	}
+
+	db := database.New(cfg.Database)
+	files := storage.New(cfg.Storage)
+
+	// Mail is only queued when there are workers to deliver it
+	driver := mail.New(cfg.Mail)
+	mailer := driver
+	var queue *jobs.Queue
+	if cfg.Jobs.Workers > 0 {
+		queue = jobs.New(db.DB())
+		if cfg.Mail.Queue {
+			mailer = jobs.NewMailer(queue, driver)
+		}
+	}
+	h := handlers.New(db, mailer, files)
 
 	s := &Server{
-		port:     port,
-		db:       db,
-		handlers: h,
+		port:            cfg.Server.Port,
+		config:          cfg,
+		db:              db,
+		handlers:        h,
+		shutdownTracing: shutdownTracing,
+		stopping:        make(chan struct{}),
+		done:            make(chan struct{}),
 	}
 
 	s.server = &http.Server{
 		Addr:         fmt.Sprintf(":%d", s.port),
-		Handler:      routes.Setup(h),
-		IdleTimeout:  time.Minute,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		Handler:      routes.Setup(h, cfg),
+		IdleTimeout:  cfg.Server.IdleTimeout,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+	}
+
+	if cfg.TLS.Enabled() {
+		s.server.TLSConfig = newTLSConfig(cfg.TLS)
+
+		if cfg.TLS.RedirectPort != 0 {
+			s.redirect = &http.Server{
+				Addr:        fmt.Sprintf(":%d", cfg.TLS.RedirectPort),
+				Handler:     redirectHandler(cfg.Server.Port),
+				ReadTimeout: cfg.Server.ReadTimeout,
+			}
+		}
+	} else if cfg.TLS.H2C {
+		s.server.Protocols = cleartextHTTP2()
+	}
+
+	if cfg.Metrics.Enabled {
+		for name, conn := range db.Connections() {
+			if err := metrics.RegisterDatabase(name, conn.DB); err != nil && utils.Logger != nil {
+				utils.Logger.Error("Failed to register database metrics", "database", name, "error", err)
+			}
+		}
+
+		if cfg.Metrics.Port != 0 {
+			mux := http.NewServeMux()
+			mux.Handle(cfg.Metrics.Path, metrics.Handler())
+			s.metrics = &http.Server{
+				Addr:        fmt.Sprintf(":%d", cfg.Metrics.Port),
+				Handler:     mux,
+				ReadTimeout: cfg.Server.ReadTimeout,
+			}
+		}
+	}
+
+	if queue != nil {
+		pool := jobs.NewPool(queue, cfg.Jobs, jobs.Deps{DB: db, Mailer: driver, Storage: files})
+		s.OnStart(Hook{Name: "jobs", Fn: pool.Start})
+		s.OnShutdown(Hook{Name: "jobs", Fn: pool.Stop})
+	}
+
+	if !cfg.IsProduction() {
+		// Open reload streams would hold up the shutdown until it times out
+		s.server.RegisterOnShutdown(livereload.Close)
+		if liveAssets {
+			watcher := livereload.NewWatcher(assets.SourceDir)
+			s.OnStart(Hook{Name: "livereload", Fn: watcher.Start})
+			s.OnShutdown(Hook{Name: "livereload", Fn: watcher.Stop})
+		}
+	}
+
+	if cfg.Schedule.Enabled {
+		scheduler := schedule.New(db)
+		s.OnStart(Hook{Name: "schedule", Fn: scheduler.Start})
+		s.OnShutdown(Hook{Name: "schedule", Fn: scheduler.Stop})
 	}
 
 	return s
 }
 
 func (s *Server) Start() error {
-	done := make(chan bool, 1)
+	go s.gracefulShutdown()
 
-	go s.gracefulShutdown(done)
+	utils.Logger.Info("Server starting", "port", s.port, "env", s.config.Env, "tls", s.config.TLS.Enabled())
+	if s.config.Session.Key == "" {
+		utils.Logger.Warn("SESSION_KEY is not set, sessions will not survive a restart")
+	}
 
-	utils.Logger.Info("Server starting", "port", s.port)
+	if err := s.runHooks("start", s.startHooks, true); err != nil {
+		s.Stop(context.Background())
+		return err
+	}
 
-	err := s.server.ListenAndServe()
+	if s.metrics != nil {
+		go func() {
+			utils.Logger.Info("Metrics server starting", "port", s.config.Metrics.Port, "path", s.config.Metrics.Path)
+			if err := s.metrics.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				utils.Logger.Error("Metrics server error", "error", err)
+			}
+		}()
+	}
+
+	if s.redirect != nil {
+		go func() {
+			utils.Logger.Info("HTTPS redirect server starting", "port", s.config.TLS.RedirectPort)
+			if err := s.redirect.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				utils.Logger.Error("HTTPS redirect server error", "error", err)
+			}
+		}()
+	}
+
+	var err error
+	if s.config.TLS.Enabled() {
+		err = s.server.ListenAndServeTLS(s.config.TLS.CertFile, s.config.TLS.KeyFile)
+	} else {
+		err = s.server.ListenAndServe()
+	}
 	if err != nil && err != http.ErrServerClosed {
+		s.Stop(context.Background())
 		return fmt.Errorf("http server error: %w", err)
 	}
 
-	<-done
+	<-s.done
 	utils.Logger.Info("Server shutdown complete")
 
 	return nil
 }
 
-func (s *Server) gracefulShutdown(done chan bool) {
+// Stop shuts a started server down like SIGTERM does and waits until the
+// shutdown completes or ctx is done
+func (s *Server) Stop(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stopping) })
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Server) gracefulShutdown() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	<-ctx.Done()
-
-	utils.Logger.Info("Shutting down gracefully, press Ctrl+C again to force")
+	select {
+	case <-ctx.Done():
+		utils.Logger.Info("Shutting down gracefully, press Ctrl+C again to force")
+	case <-s.stopping:
+		utils.Logger.Info("Shutting down gracefully")
+	}
 	stop()
 
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	s.shutdown()
+	close(s.done)
+}
+
+// shutdown stops the server in order: fail readiness, wait out the drain
+// period, stop accepting requests and finish the in-flight ones, run the
+// shutdown hooks, flush traces and close the database
+func (s *Server) shutdown() {
+	// Fail readiness first so load balancers stop sending new requests
+	s.handlers.SetReady(false)
+	if s.config.Server.DrainPeriod > 0 {
+		utils.Logger.Info("Draining before shutdown", "period", s.config.Server.DrainPeriod)
+		time.Sleep(s.config.Server.DrainPeriod)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.config.Server.ShutdownTimeout)
 	defer cancel()
 
 	if err := s.server.Shutdown(shutdownCtx); err != nil {
 		utils.Logger.Error("Server forced to shutdown", "error", err)
 	}
 
+	if s.metrics != nil {
+		if err := s.metrics.Shutdown(shutdownCtx); err != nil {
+			utils.Logger.Error("Metrics server forced to shutdown", "error", err)
+		}
+	}
+
+	if s.redirect != nil {
+		if err := s.redirect.Shutdown(shutdownCtx); err != nil {
+			utils.Logger.Error("HTTPS redirect server forced to shutdown", "error", err)
+		}
+	}
+
+	s.runHooks("shutdown", s.shutdownHooks, false)
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), s.config.Server.ShutdownTimeout)
+	defer cancelFlush()
+
+	if err := s.shutdownTracing(flushCtx); err != nil {
+		utils.Logger.Error("Error flushing traces", "error", err)
+	}
+
 	if s.db != nil {
 		if err := s.db.Close(); err != nil {
 			utils.Logger.Error("Error closing database", "error", err)
@@ -102,7 +285,4 @@ func (s *Server) gracefulShutdown(done chan bool) {
 	}
 
 	utils.Logger.Info("Server exiting")
-
-	done <- true
 }
-
@@ -1,11 +1,28 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"<<!.ProjectName!>>/internal/config"
 )
 
 func TestNewWithOptions(t *testing.T) {
-	srv := NewWithOptions(false)
+	cfg, err := config.Parse(map[string]string{"DB_URL": ":memory:", "PORT": "9000"})
+	if err != nil {
+		t.Fatalf("config.Parse failed: %v", err)
+	}
+
+	srv := NewWithOptions(cfg, false)
 	if srv == nil {
 		t.Error("NewWithOptions() returned nil")
 	}
@@ -19,6 +36,228 @@ func TestNewWithOptions(t *testing.T) {
 	}
 
 	if srv.server == nil {
-		t.Error("http server not initialized")
+		t.Fatal("http server not initialized")
+	}
+
+	if srv.server.Addr != ":9000" || srv.server.ReadTimeout != cfg.Server.ReadTimeout {
+		t.Errorf("http server not configured from config, got addr %s", srv.server.Addr)
+	}
+
+	if srv.metrics != nil {
+		t.Error("metrics should be served on the application port by default")
+	}
+}
+
+func TestMetricsPort(t *testing.T) {
+	cfg, err := config.Parse(map[string]string{"DB_URL": ":memory:", "METRICS_PORT": "9100", "METRICS_PATH": "/internal/metrics"})
+	if err != nil {
+		t.Fatalf("config.Parse failed: %v", err)
+	}
+
+	srv := NewWithOptions(cfg, false)
+	if srv.metrics == nil || srv.metrics.Addr != ":9100" {
+		t.Fatal("expected a separate metrics server on port 9100")
+	}
+
+	w := httptest.NewRecorder()
+	srv.metrics.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/internal/metrics", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "go_sql_open_connections") {
+		t.Errorf("expected database pool metrics, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	srv.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/internal/metrics", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected metrics to be off the application port, got %d", w.Code)
+	}
+}
+
+func TestTLS(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := filepath.Join(dir, "dev.crt"), filepath.Join(dir, "dev.key")
+	for _, path := range []string{cert, key} {
+		if err := os.WriteFile(path, []byte("test"), 0600); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	cfg, err := config.Parse(map[string]string{
+		"DB_URL":            ":memory:",
+		"PORT":              "8443",
+		"TLS_CERT_FILE":     cert,
+		"TLS_KEY_FILE":      key,
+		"TLS_MIN_VERSION":   "1.3",
+		"TLS_REDIRECT_PORT": "8080",
+	})
+	if err != nil {
+		t.Fatalf("config.Parse failed: %v", err)
+	}
+
+	srv := NewWithOptions(cfg, false)
+	if srv.server.TLSConfig == nil || srv.server.TLSConfig.MinVersion != tls.VersionTLS13 {
+		t.Errorf("expected TLS 1.3 minimum, got %+v", srv.server.TLSConfig)
+	}
+	if srv.redirect == nil || srv.redirect.Addr != ":8080" {
+		t.Fatal("expected a redirect server on port 8080")
+	}
+
+	w := httptest.NewRecorder()
+	srv.redirect.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "http://example.com:8080/users?page=2", nil))
+	if w.Code != http.StatusPermanentRedirect || w.Header().Get("Location") != "https://example.com:8443/users?page=2" {
+		t.Errorf("unexpected redirect: %d %s", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestRedirectHandler(t *testing.T) {
+	testCases := []struct {
+		host     string
+		port     int
+		location string
+	}{
+		{"example.com", 443, "https://example.com/a?b=c"},
+		{"example.com:80", 443, "https://example.com/a?b=c"},
+		{"[::1]:8080", 8443, "https://[::1]:8443/a?b=c"},
+		{"[::1]", 443, "https://[::1]/a?b=c"},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodGet, "/a?b=c", nil)
+		req.Host = tc.host
+		w := httptest.NewRecorder()
+		redirectHandler(tc.port).ServeHTTP(w, req)
+
+		if location := w.Header().Get("Location"); location != tc.location {
+			t.Errorf("%s: expected %s, got %s", tc.host, tc.location, location)
+		}
+	}
+}
+
+func TestH2C(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	ts.Config.Protocols = cleartextHTTP2()
+	ts.Start()
+	defer ts.Close()
+
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("h2c request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ProtoMajor != 2 {
+		t.Errorf("expected HTTP/2 over cleartext, got %s", resp.Proto)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	cfg, err := config.Parse(map[string]string{"DB_URL": ":memory:", "SERVER_HOOK_TIMEOUT": "50ms"})
+	if err != nil {
+		t.Fatalf("config.Parse failed: %v", err)
+	}
+
+	srv := NewWithOptions(cfg, true)
+	srv.server.Addr = "127.0.0.1:0"
+
+	var mu sync.Mutex
+	var calls []string
+	record := func(call string) func(context.Context) error {
+		return func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, call)
+			return nil
+		}
+	}
+
+	srv.OnStart(Hook{Name: "second", Order: 2, Fn: record("start second")})
+	srv.OnStart(Hook{Name: "first", Order: 1, Fn: record("start first")})
+
+	srv.OnShutdown(Hook{Name: "last", Order: 2, Fn: record("shutdown last")})
+	srv.OnShutdown(Hook{Name: "failing", Order: 1, Fn: func(ctx context.Context) error {
+		return errors.New("boom")
+	}})
+	srv.OnShutdown(Hook{Name: "slow", Fn: func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}})
+	srv.OnShutdown(Hook{Name: "worker", Order: -1, Fn: func(ctx context.Context) error {
+		if srv.handlers.Ready() {
+			t.Error("expected readiness to fail before shutdown hooks run")
+		}
+		if err := srv.db.DB().PingContext(ctx); err != nil {
+			t.Errorf("expected the database to be open during shutdown hooks: %v", err)
+		}
+		return record("shutdown worker")(ctx)
+	}})
+
+	started := make(chan error, 1)
+	go func() { started <- srv.Start() }()
+
+	// Wait for the start hooks before stopping
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		mu.Lock()
+		n := len(calls)
+		mu.Unlock()
+		if n == 2 || time.Now().After(deadline) {
+			break
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	begin := time.Now()
+	if err := srv.Stop(ctx); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if elapsed := time.Since(begin); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the slow hook to be abandoned after its timeout, shutdown took %s", elapsed)
+	}
+	if err := <-started; err != nil {
+		t.Errorf("Start returned an error: %v", err)
+	}
+
+	want := "start first, start second, shutdown worker, shutdown last"
+	if got := strings.Join(calls, ", "); got != want {
+		t.Errorf("expected hooks in order %q, got %q", want, got)
+	}
+	if err := srv.db.DB().Ping(); err == nil {
+		t.Error("expected the database to be closed after shutdown")
+	}
+}
+
+func TestStartHookError(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	cfg, err := config.Parse(map[string]string{"DB_URL": ":memory:"})
+	if err != nil {
+		t.Fatalf("config.Parse failed: %v", err)
+	}
+
+	srv := NewWithOptions(cfg, true)
+	srv.server.Addr = "127.0.0.1:0"
+
+	shutdown := false
+	srv.OnStart(Hook{Name: "migrate", Fn: func(ctx context.Context) error {
+		return errors.New("pending migrations")
+	}})
+	srv.OnShutdown(Hook{Name: "cleanup", Fn: func(ctx context.Context) error {
+		shutdown = true
+		return nil
+	}})
+
+	if err := srv.Start(); err == nil || !strings.Contains(err.Error(), "start hook migrate failed") {
+		t.Errorf("expected the start hook error, got %v", err)
+	}
+	if !shutdown {
+		t.Error("expected shutdown hooks to run after a failed start")
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"<<!.ProjectName!>>/internal/config"
+)
+
+// newTLSConfig returns the TLS settings of cfg. The certificate itself is
+// loaded by ListenAndServeTLS. HTTP/2 is negotiated automatically.
+func newTLSConfig(cfg config.TLS) *tls.Config {
+	// Validated by config.Parse
+	suites, _ := cfg.CipherSuiteIDs()
+
+	return &tls.Config{
+		MinVersion:   cfg.Version(),
+		CipherSuites: suites,
+	}
+}
+
+// redirectHandler sends every request to the same URL over HTTPS on httpsPort
+func redirectHandler(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = strings.Trim(r.Host, "[]")
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
+
+// cleartextHTTP2 serves HTTP/1 and unencrypted HTTP/2 (h2c) on one listener
+func cleartextHTTP2() *http.Protocols {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return protocols
+}
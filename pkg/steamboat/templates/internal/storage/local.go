@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// tempPrefix marks files still being written by Put
+const tempPrefix = ".put-"
+
+// Local stores files in a directory. The content type of a file is derived
+// from its extension, which Upload picks from the sniffed type.
+type Local struct {
+	root string
+}
+
+// NewLocal returns a driver storing files under root
+func NewLocal(root string) *Local {
+	return &Local{root: root}
+}
+
+func (l *Local) Put(ctx context.Context, name string, r io.Reader, contentType string) error {
+	if err := checkPath(name); err != nil {
+		return err
+	}
+	target := filepath.Join(l.root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Write next to the target and rename, so readers never see half a file
+	tmp, err := os.CreateTemp(filepath.Dir(target), tempPrefix+"*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+func (l *Local) Open(ctx context.Context, name string) (io.ReadCloser, Object, error) {
+	if err := checkPath(name); err != nil {
+		return nil, Object{}, err
+	}
+	f, err := os.Open(filepath.Join(l.root, filepath.FromSlash(name)))
+	if err != nil {
+		return nil, Object{}, l.wrap(name, err)
+	}
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		f.Close()
+		return nil, Object{}, ErrNotFound
+	}
+	return f, l.object(name, info), nil
+}
+
+func (l *Local) Stat(ctx context.Context, name string) (Object, error) {
+	if err := checkPath(name); err != nil {
+		return Object{}, err
+	}
+	info, err := os.Stat(filepath.Join(l.root, filepath.FromSlash(name)))
+	if err != nil {
+		return Object{}, l.wrap(name, err)
+	}
+	if info.IsDir() {
+		return Object{}, ErrNotFound
+	}
+	return l.object(name, info), nil
+}
+
+func (l *Local) Delete(ctx context.Context, name string) error {
+	if err := checkPath(name); err != nil {
+		return err
+	}
+	err := os.Remove(filepath.Join(l.root, filepath.FromSlash(name)))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete %s: %w", name, err)
+	}
+	return nil
+}
+
+func (l *Local) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	err := filepath.WalkDir(l.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), tempPrefix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(l.root, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if !strings.HasPrefix(name, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, l.object(name, info))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Path < objects[j].Path })
+	return objects, nil
+}
+
+func (l *Local) object(name string, info fs.FileInfo) Object {
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return Object{Path: name, Size: info.Size(), ContentType: contentType, ModTime: info.ModTime()}
+}
+
+func (l *Local) wrap(name string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return fmt.Errorf("failed to read %s: %w", name, err)
+}
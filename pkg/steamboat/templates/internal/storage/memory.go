@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory keeps files in memory, for tests
+type Memory struct {
+	mu    sync.RWMutex
+	files map[string]memoryFile
+}
+
+type memoryFile struct {
+	data   []byte
+	object Object
+}
+
+// NewMemory returns an empty in-memory driver
+func NewMemory() *Memory {
+	return &Memory{files: make(map[string]memoryFile)}
+}
+
+func (m *Memory) Put(ctx context.Context, name string, r io.Reader, contentType string) error {
+	if err := checkPath(name); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[name] = memoryFile{
+		data:   data,
+		object: Object{Path: name, Size: int64(len(data)), ContentType: contentType, ModTime: time.Now()},
+	}
+	return nil
+}
+
+func (m *Memory) Open(ctx context.Context, name string) (io.ReadCloser, Object, error) {
+	if err := checkPath(name); err != nil {
+		return nil, Object{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	file, ok := m.files[name]
+	if !ok {
+		return nil, Object{}, ErrNotFound
+	}
+	return memoryReader{bytes.NewReader(file.data)}, file.object, nil
+}
+
+func (m *Memory) Stat(ctx context.Context, name string) (Object, error) {
+	if err := checkPath(name); err != nil {
+		return Object{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	file, ok := m.files[name]
+	if !ok {
+		return Object{}, ErrNotFound
+	}
+	return file.object, nil
+}
+
+func (m *Memory) Delete(ctx context.Context, name string) error {
+	if err := checkPath(name); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.files, name)
+	return nil
+}
+
+func (m *Memory) List(ctx context.Context, prefix string) ([]Object, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var objects []Object
+	for name, file := range m.files {
+		if strings.HasPrefix(name, prefix) {
+			objects = append(objects, file.object)
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Path < objects[j].Path })
+	return objects, nil
+}
+
+// memoryReader lets downloads seek within a file held in memory
+type memoryReader struct {
+	*bytes.Reader
+}
+
+func (memoryReader) Close() error { return nil }
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RoutePrefix is where the router mounts the download route
+const RoutePrefix = "/_storage/"
+
+// signingKey derives the URL signing key, so the session key can be shared
+// without using it for two purposes
+func signingKey(key string) []byte {
+	if key == "" {
+		random := make([]byte, 32)
+		rand.Read(random)
+		return random
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte("storage urls"))
+	return mac.Sum(nil)
+}
+
+// URL returns a download URL for path that works for ttl
+func (s *Storage) URL(path string, ttl time.Duration) string {
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	query := url.Values{"expires": {expires}, "signature": {s.sign(path, expires)}}
+	return RoutePrefix + strings.Join(segments, "/") + "?" + query.Encode()
+}
+
+// ServeHTTP serves the file of a URL made by URL, if it is signed and has
+// not expired
+func (s *Storage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, RoutePrefix)
+	expires := r.URL.Query().Get("expires")
+	signature := r.URL.Query().Get("signature")
+
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !hmac.Equal([]byte(signature), []byte(s.sign(path, expires))) {
+		http.Error(w, "Invalid signature", http.StatusForbidden)
+		return
+	}
+	remaining := time.Until(time.Unix(unix, 0))
+	if remaining <= 0 {
+		http.Error(w, "Link expired", http.StatusForbidden)
+		return
+	}
+
+	content, object, err := s.Open(r.Context(), path)
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalidPath) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", object.ContentType)
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(int(remaining.Seconds())))
+	// Uploaded HTML or SVG must not run scripts on the app's origin
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if seeker, ok := content.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", object.ModTime, seeker)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(object.Size, 10))
+	io.Copy(w, content)
+}
+
+func (s *Storage) sign(path, expires string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(path + "\n" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,82 @@
+// Package storage keeps uploaded files on a Disk and serves them through
+// signed, expiring URLs:
+//
+//	file, err := storage.Upload(r, h.storage, storage.UploadOptions{
+//		Field: "avatar",
+//		Types: []string{"image/png", "image/jpeg"},
+//		Dir:   "avatars",
+//	})
+//	...
+//	url := h.storage.URL(file.Path, time.Hour)
+//
+// Paths are slash-separated keys such as avatars/2024/05/3f2a.png, never
+// file system paths, so a driver for an object store like S3 fits the same
+// interface. The storagetest package checks a driver against the contract;
+// an S3 driver runs it against a local stand-in such as MinIO.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"time"
+
+	"<<!.ProjectName!>>/internal/config"
+)
+
+var (
+	// ErrNotFound is returned for paths with nothing stored
+	ErrNotFound = errors.New("storage: file not found")
+	// ErrInvalidPath is returned for paths that are not clean, relative and
+	// slash-separated, e.g. ../secrets or /etc/passwd
+	ErrInvalidPath = errors.New("storage: invalid path")
+)
+
+// Disk stores files by path
+type Disk interface {
+	// Put stores the content of r at path, replacing what was there. When
+	// reading r fails nothing is stored and the error is returned wrapped.
+	Put(ctx context.Context, path string, r io.Reader, contentType string) error
+	// Open returns the content at path, which the caller closes. Drivers
+	// return an io.ReadSeeker when they can, so downloads support ranges.
+	Open(ctx context.Context, path string) (io.ReadCloser, Object, error)
+	Stat(ctx context.Context, path string) (Object, error)
+	// Delete removes path. Deleting a missing path is not an error.
+	Delete(ctx context.Context, path string) error
+	// List returns the files whose path starts with prefix, sorted by path
+	List(ctx context.Context, prefix string) ([]Object, error)
+}
+
+// Object describes a stored file
+type Object struct {
+	Path        string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Storage is the configured disk with the key that signs its download URLs
+type Storage struct {
+	Disk
+	key []byte
+}
+
+// New returns the storage configured by cfg
+func New(cfg config.Storage) *Storage {
+	var disk Disk
+	switch cfg.Driver {
+	case "memory":
+		disk = NewMemory()
+	default:
+		disk = NewLocal(cfg.Dir)
+	}
+	return &Storage{Disk: disk, key: signingKey(cfg.Key)}
+}
+
+func checkPath(path string) error {
+	if path == "." || !fs.ValidPath(path) {
+		return ErrInvalidPath
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"<<!.ProjectName!>>/internal/config"
+	"<<!.ProjectName!>>/internal/storage"
+	"<<!.ProjectName!>>/internal/storage/storagetest"
+)
+
+// pngHeader is enough of a PNG file for content sniffing
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestLocal(t *testing.T) {
+	storagetest.Run(t, storage.NewLocal(t.TempDir()))
+}
+
+func TestMemory(t *testing.T) {
+	storagetest.Run(t, storage.NewMemory())
+}
+
+func uploadRequest(t *testing.T, field, filename string, content []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	w.WriteField("title", "My avatar")
+	part, err := w.CreateFormFile(field, filename)
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+	part.Write(content)
+	w.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func TestUpload(t *testing.T) {
+	disk := storage.NewMemory()
+	content := append(pngHeader, bytes.Repeat([]byte{0}, 1000)...)
+
+	file, err := storage.Upload(uploadRequest(t, "avatar", `C:\photos\me.PNG`, content), disk, storage.UploadOptions{
+		Field: "avatar",
+		Types: []string{"image/*"},
+		Dir:   "avatars",
+	})
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	if file.Filename != "me.PNG" || file.ContentType != "image/png" || file.Size != int64(len(content)) {
+		t.Errorf("Unexpected file: %+v", file)
+	}
+	if !strings.HasPrefix(file.Path, "avatars/"+time.Now().UTC().Format("2006/01")+"/") || !strings.HasSuffix(file.Path, ".png") {
+		t.Errorf("Unexpected path %q", file.Path)
+	}
+
+	stored, err := disk.Stat(context.Background(), file.Path)
+	if err != nil || stored.Size != file.Size {
+		t.Errorf("Expected the file to be stored, got %+v, %v", stored, err)
+	}
+
+	again, err := storage.Upload(uploadRequest(t, "avatar", "me.png", content), disk, storage.UploadOptions{Field: "avatar"})
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if again.Path == file.Path {
+		t.Error("Expected every upload to get its own path")
+	}
+}
+
+func TestUploadLimits(t *testing.T) {
+	testCases := []struct {
+		name    string
+		req     func(t *testing.T) *http.Request
+		opts    storage.UploadOptions
+		wantErr error
+	}{
+		{
+			name: "too large",
+			req: func(t *testing.T) *http.Request {
+				return uploadRequest(t, "file", "big.txt", bytes.Repeat([]byte("a"), 2048))
+			},
+			opts:    storage.UploadOptions{MaxSize: 1024},
+			wantErr: storage.ErrTooLarge,
+		},
+		{
+			name: "type sniffed from content",
+			req: func(t *testing.T) *http.Request {
+				return uploadRequest(t, "file", "fake.png", []byte("<html><script>alert(1)</script>"))
+			},
+			opts:    storage.UploadOptions{Types: []string{"image/png"}},
+			wantErr: storage.ErrType,
+		},
+		{
+			name:    "other field",
+			req:     func(t *testing.T) *http.Request { return uploadRequest(t, "document", "a.txt", []byte("text")) },
+			wantErr: storage.ErrNoFile,
+		},
+		{
+			name:    "empty file",
+			req:     func(t *testing.T) *http.Request { return uploadRequest(t, "file", "empty.txt", nil) },
+			wantErr: storage.ErrNoFile,
+		},
+		{
+			name: "not multipart",
+			req: func(t *testing.T) *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("name=x"))
+			},
+			wantErr: storage.ErrNoFile,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			disk := storage.NewMemory()
+			if _, err := storage.Upload(tc.req(t), disk, tc.opts); !errors.Is(err, tc.wantErr) {
+				t.Errorf("Expected %v, got %v", tc.wantErr, err)
+			}
+			if objects, _ := disk.List(context.Background(), ""); len(objects) != 0 {
+				t.Errorf("Expected nothing stored, got %+v", objects)
+			}
+		})
+	}
+}
+
+func TestUploadExactLimit(t *testing.T) {
+	content := bytes.Repeat([]byte("a"), 1024)
+	file, err := storage.Upload(uploadRequest(t, "file", "notes.txt", content), storage.NewMemory(), storage.UploadOptions{MaxSize: 1024})
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if file.Size != 1024 || !strings.HasSuffix(file.Path, ".txt") {
+		t.Errorf("Unexpected file: %+v", file)
+	}
+}
+
+func TestSignedURL(t *testing.T) {
+	s := storage.New(config.Storage{Driver: "memory", Key: "secret"})
+	if err := s.Put(context.Background(), "docs/my report.txt", strings.NewReader("0123456789"), "text/plain; charset=utf-8"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	get := func(url string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w
+	}
+
+	url := s.URL("docs/my report.txt", time.Hour)
+	if !strings.HasPrefix(url, storage.RoutePrefix+"docs/my%20report.txt?") {
+		t.Errorf("Unexpected URL %q", url)
+	}
+
+	w := get(url, nil)
+	if w.Code != http.StatusOK || w.Body.String() != "0123456789" {
+		t.Fatalf("Expected the file, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != "text/plain; charset=utf-8" || w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("Unexpected headers: %v", w.Header())
+	}
+
+	w = get(url, http.Header{"Range": {"bytes=2-4"}})
+	if w.Code != http.StatusPartialContent || w.Body.String() != "234" {
+		t.Errorf("Expected a partial response, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// A signature only covers its own path
+	if w := get(strings.Replace(url, "report", "other", 1), nil); w.Code != http.StatusForbidden {
+		t.Errorf("Expected a tampered URL to be refused, got %d", w.Code)
+	}
+	// Nor does another key sign the same URL
+	other := storage.New(config.Storage{Driver: "memory", Key: "other"})
+	if w := get(other.URL("docs/my report.txt", time.Hour), nil); w.Code != http.StatusForbidden {
+		t.Errorf("Expected a URL signed with another key to be refused, got %d", w.Code)
+	}
+	if w := get(s.URL("docs/my report.txt", -time.Minute), nil); w.Code != http.StatusForbidden {
+		t.Errorf("Expected an expired URL to be refused, got %d", w.Code)
+	}
+	if w := get(s.URL("docs/missing.txt", time.Hour), nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected a missing file to be 404, got %d", w.Code)
+	}
+}
+
+func TestLocalFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	s := storage.New(config.Storage{Driver: "local", Dir: dir})
+	if err := s.Put(context.Background(), "a/b.txt", strings.NewReader("hi"), ""); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	content, object, err := storage.NewLocal(dir).Open(context.Background(), "a/b.txt")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer content.Close()
+	data, _ := io.ReadAll(content)
+	if string(data) != "hi" || !strings.HasPrefix(object.ContentType, "text/plain") {
+		t.Errorf("Unexpected file %q, %+v", data, object)
+	}
+}
@@ -0,0 +1,135 @@
+// Package storagetest checks that a storage.Disk keeps the contract the rest
+// of the app relies on. Every driver runs it, e.g. an S3 driver against a
+// local stand-in:
+//
+//	func TestS3(t *testing.T) {
+//		endpoint := os.Getenv("S3_TEST_ENDPOINT") // e.g. a MinIO container
+//		if endpoint == "" {
+//			t.Skip("S3_TEST_ENDPOINT is not set")
+//		}
+//		storagetest.Run(t, NewS3(...))
+//	}
+package storagetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"<<!.ProjectName!>>/internal/storage"
+)
+
+// Run tests disk. It only touches paths under storagetest/, which it
+// deletes when done.
+func Run(t *testing.T, disk storage.Disk) {
+	ctx := context.Background()
+	t.Cleanup(func() {
+		objects, _ := disk.List(ctx, "storagetest/")
+		for _, object := range objects {
+			disk.Delete(ctx, object.Path)
+		}
+	})
+
+	put := func(t *testing.T, path, content string) {
+		t.Helper()
+		if err := disk.Put(ctx, path, strings.NewReader(content), "text/plain; charset=utf-8"); err != nil {
+			t.Fatalf("Put %s failed: %v", path, err)
+		}
+	}
+
+	t.Run("PutOpen", func(t *testing.T) {
+		put(t, "storagetest/put/hello.txt", "hello")
+
+		content, object, err := disk.Open(ctx, "storagetest/put/hello.txt")
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		defer content.Close()
+		data, err := io.ReadAll(content)
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		if string(data) != "hello" {
+			t.Errorf("Expected hello, got %q", data)
+		}
+		if object.Path != "storagetest/put/hello.txt" || object.Size != 5 || !strings.HasPrefix(object.ContentType, "text/plain") {
+			t.Errorf("Unexpected object: %+v", object)
+		}
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		put(t, "storagetest/replace.txt", "first")
+		put(t, "storagetest/replace.txt", "second")
+
+		object, err := disk.Stat(ctx, "storagetest/replace.txt")
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if object.Size != 6 || object.ModTime.IsZero() {
+			t.Errorf("Expected the second content, got %+v", object)
+		}
+	})
+
+	t.Run("FailedPut", func(t *testing.T) {
+		cause := errors.New("client went away")
+		reader := io.MultiReader(strings.NewReader("partial"), iotest.ErrReader(cause))
+		if err := disk.Put(ctx, "storagetest/failed.txt", reader, "text/plain"); !errors.Is(err, cause) {
+			t.Errorf("Expected the reader's error, got %v", err)
+		}
+		if _, err := disk.Stat(ctx, "storagetest/failed.txt"); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("Expected nothing stored, got %v", err)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		if _, _, err := disk.Open(ctx, "storagetest/missing.txt"); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("Open: expected ErrNotFound, got %v", err)
+		}
+		if _, err := disk.Stat(ctx, "storagetest/missing.txt"); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("Stat: expected ErrNotFound, got %v", err)
+		}
+		if err := disk.Delete(ctx, "storagetest/missing.txt"); err != nil {
+			t.Errorf("Delete: expected no error, got %v", err)
+		}
+	})
+
+	t.Run("InvalidPath", func(t *testing.T) {
+		for _, path := range []string{"", "/storagetest/abs.txt", "storagetest/../escape.txt", "storagetest//double.txt"} {
+			if err := disk.Put(ctx, path, bytes.NewReader(nil), ""); !errors.Is(err, storage.ErrInvalidPath) {
+				t.Errorf("Put %q: expected ErrInvalidPath, got %v", path, err)
+			}
+			if _, _, err := disk.Open(ctx, path); !errors.Is(err, storage.ErrInvalidPath) {
+				t.Errorf("Open %q: expected ErrInvalidPath, got %v", path, err)
+			}
+		}
+	})
+
+	t.Run("ListDelete", func(t *testing.T) {
+		put(t, "storagetest/list/b.txt", "b")
+		put(t, "storagetest/list/a/c.txt", "c")
+		put(t, "storagetest/list.txt", "other")
+
+		objects, err := disk.List(ctx, "storagetest/list/")
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		var paths []string
+		for _, object := range objects {
+			paths = append(paths, object.Path)
+		}
+		if strings.Join(paths, " ") != "storagetest/list/a/c.txt storagetest/list/b.txt" {
+			t.Errorf("Unexpected listing: %v", paths)
+		}
+
+		if err := disk.Delete(ctx, "storagetest/list/b.txt"); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if _, err := disk.Stat(ctx, "storagetest/list/b.txt"); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("Expected the file to be deleted, got %v", err)
+		}
+	})
+}
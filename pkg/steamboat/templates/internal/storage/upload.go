@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// DefaultMaxUploadSize is the upload limit when UploadOptions.MaxSize is 0
+const DefaultMaxUploadSize = 10 << 20
+
+var (
+	// ErrNoFile is returned when the request has no file in the field
+	ErrNoFile = errors.New("storage: no file uploaded")
+	// ErrTooLarge is returned when the file exceeds the size limit
+	ErrTooLarge = errors.New("storage: file too large")
+	// ErrType is returned when the sniffed content type is not allowed
+	ErrType = errors.New("storage: file type not allowed")
+)
+
+// UploadOptions limits what Upload accepts and says where it goes
+type UploadOptions struct {
+	// Field is the form field of the file (default: file)
+	Field string
+	// MaxSize is the largest accepted file in bytes (default: DefaultMaxUploadSize)
+	MaxSize int64
+	// Types lists the accepted content types, e.g. image/png or image/*.
+	// Empty accepts any.
+	Types []string
+	// Dir is the path the file is stored under, e.g. avatars
+	Dir string
+}
+
+// File is an uploaded file
+type File struct {
+	Object
+	// Filename is the name the client sent. Only show it; the stored path
+	// is generated.
+	Filename string
+}
+
+// extensions picks the extension of common sniffed types, where
+// mime.ExtensionsByType returns several
+var extensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+	"text/plain":      ".txt",
+}
+
+// Upload streams the file in the multipart field opts.Field of r to disk
+// without buffering it. The content type is sniffed from the first bytes,
+// not taken from the client, and the file is stored at a generated path
+// under opts.Dir with the extension of that type.
+//
+// Handlers map ErrNoFile to 400, ErrTooLarge to 413 and ErrType to 415.
+func Upload(r *http.Request, disk Disk, opts UploadOptions) (*File, error) {
+	if opts.Field == "" {
+		opts.Field = "file"
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultMaxUploadSize
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNoFile, err)
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, ErrNoFile
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read upload: %w", err)
+		}
+		if part.FormName() != opts.Field || part.FileName() == "" {
+			continue
+		}
+
+		file, err := store(r, disk, part, part.FileName(), opts)
+		part.Close()
+		return file, err
+	}
+}
+
+func store(r *http.Request, disk Disk, part io.Reader, filename string, opts UploadOptions) (*File, error) {
+	buffered := bufio.NewReaderSize(part, 512)
+	head, err := buffered.Peek(512)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	if len(head) == 0 {
+		return nil, ErrNoFile
+	}
+
+	contentType := http.DetectContentType(head)
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if !allowed(mediaType, opts.Types) {
+		return nil, fmt.Errorf("%w: %s", ErrType, mediaType)
+	}
+
+	name, err := uniquePath(opts.Dir, extension(mediaType, filename))
+	if err != nil {
+		return nil, err
+	}
+
+	limited := &limitReader{r: buffered, remaining: opts.MaxSize}
+	if err := disk.Put(r.Context(), name, limited, contentType); err != nil {
+		return nil, err
+	}
+
+	return &File{
+		Object:   Object{Path: name, Size: opts.MaxSize - limited.remaining, ContentType: contentType, ModTime: time.Now()},
+		Filename: path.Base(strings.ReplaceAll(filename, "\\", "/")),
+	}, nil
+}
+
+func allowed(mediaType string, types []string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, t := range types {
+		if t == mediaType || strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(t, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// extension keeps the client's extension when it matches the sniffed type
+func extension(mediaType, filename string) string {
+	ext := strings.ToLower(path.Ext(filename))
+	if byExt, _, _ := mime.ParseMediaType(mime.TypeByExtension(ext)); ext != "" && byExt == mediaType {
+		return ext
+	}
+	if ext, ok := extensions[mediaType]; ok {
+		return ext
+	}
+	if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+// uniquePath returns dir/yyyy/mm/<random><ext>
+func uniquePath(dir, ext string) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate path: %w", err)
+	}
+	name := path.Join(dir, time.Now().UTC().Format("2006/01"), hex.EncodeToString(random)+ext)
+	if err := checkPath(name); err != nil {
+		return "", fmt.Errorf("%w: upload directory %q", err, dir)
+	}
+	return name, nil
+}
+
+// limitReader fails with ErrTooLarge once more than remaining bytes are read
+type limitReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.remaining {
+		l.remaining = 0
+		return 0, ErrTooLarge
+	}
+	l.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Querier is the query interface of *sqlx.DB and *sqlx.Tx used by the
+// generated *Queries (models.DBTX)
+type Querier interface {
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
+}
+
+// DB records a client span for every query run through it
+type DB struct {
+	db   Querier
+	name string
+}
+
+// WrapDB traces the queries run on db, a connection or transaction of the
+// database called name. The generated queries of database.Service use it,
+// and a transaction can be traced the same way:
+//
+//	models.NewUserQueries(tracing.WrapDB(tx, database.Primary))
+func WrapDB(db Querier, name string) *DB {
+	return &DB{db: db, name: name}
+}
+
+func (d *DB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	ctx, span := d.start(ctx, query)
+	defer span.End()
+
+	err := d.db.GetContext(ctx, dest, query, args...)
+	if !errors.Is(err, sql.ErrNoRows) {
+		RecordError(span, err)
+	}
+	return err
+}
+
+func (d *DB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	ctx, span := d.start(ctx, query)
+	defer span.End()
+
+	err := d.db.SelectContext(ctx, dest, query, args...)
+	RecordError(span, err)
+	return err
+}
+
+func (d *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := d.start(ctx, query)
+	defer span.End()
+
+	result, err := d.db.ExecContext(ctx, query, args...)
+	RecordError(span, err)
+	return result, err
+}
+
+func (d *DB) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	ctx, span := d.start(ctx, query)
+	defer span.End()
+
+	result, err := d.db.NamedExecContext(ctx, query, arg)
+	RecordError(span, err)
+	return result, err
+}
+
+// start opens a span named after the SQL operation, e.g. "SELECT primary"
+func (d *DB) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	query = strings.TrimSpace(query)
+	operation := "QUERY"
+	if fields := strings.Fields(query); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+
+	return Start(ctx, operation+" "+d.name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "sqlite"),
+			attribute.String("db.name", d.name),
+			attribute.String("db.operation", operation),
+			attribute.String("db.statement", query),
+		),
+	)
+}
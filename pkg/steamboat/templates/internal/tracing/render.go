@@ -0,0 +1,21 @@
+package tracing
+
+import (
+	"context"
+	"io"
+
+	"github.com/a-h/templ"
+)
+
+// Component wraps a templ component so each render gets a child span named
+// "render <name>", e.g. Component("pages.Home", pages.Home()).Render(ctx, w)
+func Component(name string, component templ.Component) templ.Component {
+	return templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		ctx, span := Start(ctx, "render "+name)
+		defer span.End()
+
+		err := component.Render(ctx, w)
+		RecordError(span, err)
+		return err
+	})
+}
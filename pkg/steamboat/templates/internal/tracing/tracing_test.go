@@ -0,0 +1,154 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/a-h/templ"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"<<!.ProjectName!>>/internal/config"
+)
+
+// setupTest installs a tracer provider that records spans in memory
+func setupTest(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := NewProvider(config.Tracing{ServiceName: "test", SampleRatio: 1}, sdktrace.WithSyncer(exporter))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previous)
+	})
+
+	return exporter
+}
+
+func TestComponent(t *testing.T) {
+	exporter := setupTest(t)
+
+	ctx, parent := Start(context.Background(), "request")
+	hello := templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		_, err := io.WriteString(w, "hello")
+		return err
+	})
+
+	var buf bytes.Buffer
+	if err := Component("pages.Hello", hello).Render(ctx, &buf); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	parent.End()
+
+	if buf.String() != "hello" {
+		t.Errorf("expected the component output, got %q", buf.String())
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 || spans[0].Name != "render pages.Hello" {
+		t.Fatalf("expected a render span, got %v", spans)
+	}
+	if spans[0].Parent.SpanID() != spans[1].SpanContext.SpanID() {
+		t.Error("expected the render span to be a child of the request span")
+	}
+
+	failing := templ.ComponentFunc(func(ctx context.Context, w io.Writer) error {
+		return errors.New("boom")
+	})
+	if err := Component("pages.Broken", failing).Render(context.Background(), io.Discard); err == nil {
+		t.Error("expected the render error to be returned")
+	}
+	if span := exporter.GetSpans()[2]; span.Status.Code != codes.Error {
+		t.Errorf("expected the failed render span to have error status, got %v", span.Status)
+	}
+}
+
+func TestWrapDB(t *testing.T) {
+	exporter := setupTest(t)
+
+	conn, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer conn.Close()
+	conn.SetMaxOpenConns(1)
+
+	db := WrapDB(conn, "primary")
+	ctx := context.Background()
+
+	if _, err := db.ExecContext(ctx, "CREATE TABLE posts (id INTEGER PRIMARY KEY, title TEXT)"); err != nil {
+		t.Fatalf("ExecContext failed: %v", err)
+	}
+	if _, err := db.NamedExecContext(ctx, "INSERT INTO posts (title) VALUES (:title)", map[string]interface{}{"title": "Hello"}); err != nil {
+		t.Fatalf("NamedExecContext failed: %v", err)
+	}
+
+	var title string
+	if err := db.GetContext(ctx, &title, "\n\tselect title FROM posts WHERE id = ?", 1); err != nil || title != "Hello" {
+		t.Fatalf("GetContext failed: %q, %v", title, err)
+	}
+	if err := db.GetContext(ctx, &title, "SELECT title FROM posts WHERE id = ?", 2); err == nil {
+		t.Fatal("expected no rows")
+	}
+
+	spans := exporter.GetSpans()
+	names := []string{"CREATE primary", "INSERT primary", "SELECT primary", "SELECT primary"}
+	if len(spans) != len(names) {
+		t.Fatalf("expected %d spans, got %d", len(names), len(spans))
+	}
+	for i, name := range names {
+		if spans[i].Name != name {
+			t.Errorf("expected span %q, got %q", name, spans[i].Name)
+		}
+	}
+	if spans[3].Status.Code == codes.Error {
+		t.Error("sql.ErrNoRows should not mark the span as failed")
+	}
+}
+
+func TestSetupFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces", "traces.json")
+
+	previous := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(previous)
+
+	shutdown, err := Setup(config.Tracing{Exporter: "file", File: path, ServiceName: "test", SampleRatio: 1})
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	_, span := Start(context.Background(), "exported")
+	span.End()
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read traces: %v", err)
+	}
+	if !bytes.Contains(content, []byte(`"Name":"exported"`)) {
+		t.Errorf("expected the span in the traces file, got %s", content)
+	}
+}
+
+func TestSetupNone(t *testing.T) {
+	shutdown, err := Setup(config.Tracing{Exporter: "none"})
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown failed: %v", err)
+	}
+}
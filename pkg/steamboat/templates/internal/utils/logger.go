@@ -1,13 +1,17 @@
 package utils
 
 import (
+	"context"
 	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"sync"
 
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/natefinch/lumberjack.v2"
+
+	"<<!.ProjectName!>>/internal/config"
 )
 
 var (
@@ -15,19 +19,19 @@ var (
 	once   sync.Once
 )
 
-func InitLogger() {
+func InitLogger(cfg config.Log) {
 	once.Do(func() {
-		Logger = newLogger()
+		Logger = newLogger(cfg)
 	})
 }
 
-func newLogger() *slog.Logger {
-	if err := os.MkdirAll("logs", 0755); err != nil {
+func newLogger(cfg config.Log) *slog.Logger {
+	if err := os.MkdirAll(filepath.Dir(cfg.File), 0755); err != nil {
 		panic("failed to create logs directory: " + err.Error())
 	}
 
 	logFile := &lumberjack.Logger{
-		Filename:   filepath.Join("logs", "app.log"),
+		Filename:   cfg.File,
 		MaxSize:    10,
 		MaxBackups: 2,
 		MaxAge:     0,
@@ -37,8 +41,43 @@ func newLogger() *slog.Logger {
 	multiWriter := io.MultiWriter(os.Stdout, logFile)
 	
 	handler := slog.NewJSONHandler(multiWriter, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
+		Level: level(cfg.Level),
 	})
 
-	return slog.New(handler)
-}
\ No newline at end of file
+	return slog.New(traceHandler{handler})
+}
+
+// traceHandler adds the trace and span IDs to records logged with the context
+// of a traced request, e.g. Logger.InfoContext(r.Context(), "user created")
+type traceHandler struct {
+	slog.Handler
+}
+
+func (h traceHandler) Handle(ctx context.Context, r slog.Record) error {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h traceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return traceHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h traceHandler) WithGroup(name string) slog.Handler {
+	return traceHandler{h.Handler.WithGroup(name)}
+}
+
+// level maps the LOG_LEVEL names to slog levels
+func level(name string) slog.Level {
+	switch name {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
@@ -1,11 +1,20 @@
 package utils
 
 import (
+	"context"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"<<!.ProjectName!>>/internal/config"
 )
 
+var testLogConfig = config.Log{Level: "info", File: filepath.Join("logs", "app.log")}
+
 func TestInitLogger(t *testing.T) {
 	tempDir := t.TempDir()
 	originalWd, _ := os.Getwd()
@@ -13,7 +22,7 @@ func TestInitLogger(t *testing.T) {
 	os.Chdir(tempDir)
 	defer os.Chdir(originalWd)
 	
-	InitLogger()
+	InitLogger(testLogConfig)
 	
 	if Logger == nil {
 		t.Fatal("InitLogger did not set global Logger")
@@ -36,10 +45,10 @@ func TestMultipleInitCalls(t *testing.T) {
 	os.Chdir(tempDir)
 	defer os.Chdir(originalWd)
 	
-	InitLogger()
+	InitLogger(testLogConfig)
 	firstLogger := Logger
 	
-	InitLogger()
+	InitLogger(testLogConfig)
 	secondLogger := Logger
 	
 	if firstLogger != secondLogger {
@@ -55,7 +64,7 @@ func TestLoggerLevels(t *testing.T) {
 	defer os.Chdir(originalWd)
 	
 	// Create a fresh logger for this test
-	testLogger := newLogger()
+	testLogger := newLogger(config.Log{Level: "warn", File: filepath.Join("logs", "app.log")})
 	
 	testLogger.Info("info message")
 	testLogger.Error("error message")
@@ -64,4 +73,40 @@ func TestLoggerLevels(t *testing.T) {
 	if _, err := os.Stat("logs"); os.IsNotExist(err) {
 		t.Error("logs directory was not created")
 	}
-}
\ No newline at end of file
+	
+	if testLogger.Enabled(context.Background(), slog.LevelInfo) || !testLogger.Enabled(context.Background(), slog.LevelWarn) {
+		t.Error("logger should honour the configured level")
+	}
+}
+
+func TestLoggerTraceID(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	path := filepath.Join("logs", "trace.log")
+	logger := newLogger(config.Log{Level: "info", File: path})
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	logger.InfoContext(ctx, "traced")
+	logger.With("component", "test").InfoContext(context.Background(), "untraced")
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log lines, got %d", len(lines))
+	}
+	if !strings.Contains(lines[0], `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`) || !strings.Contains(lines[0], `"span_id":"00f067aa0ba902b7"`) {
+		t.Errorf("expected trace and span IDs, got %s", lines[0])
+	}
+	if strings.Contains(lines[1], "trace_id") {
+		t.Errorf("expected no trace ID without a span, got %s", lines[1])
+	}
+}